@@ -0,0 +1,11 @@
+package audit
+
+import "github.com/Benyam-S/onemembership/entity"
+
+// IAuditRepository is an interface that defines all the repository methods of an audit record struct,
+// the audit trail is append only so records can't be updated or deleted
+type IAuditRepository interface {
+	Create(newAuditRecord *entity.AuditRecord) error
+	Find(id int64) (*entity.AuditRecord, error)
+	Search(filter *Filter, pageNum int64) ([]*entity.AuditRecord, int64)
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"math"
+	"strings"
+
+	"github.com/Benyam-S/onemembership/audit"
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/jinzhu/gorm"
+)
+
+// AuditRepository is a type that defines an audit record repository type
+type AuditRepository struct {
+	conn *gorm.DB
+}
+
+// NewAuditRepository is a function that creates a new audit record repository type
+func NewAuditRepository(connection *gorm.DB) audit.IAuditRepository {
+	return &AuditRepository{conn: connection}
+}
+
+// Create is a method that appends a new audit record to the database
+func (repo *AuditRepository) Create(newAuditRecord *entity.AuditRecord) error {
+
+	err := repo.conn.Create(newAuditRecord).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Find is a method that finds a certain audit record from the database using an id
+func (repo *AuditRepository) Find(id int64) (*entity.AuditRecord, error) {
+
+	auditRecord := new(entity.AuditRecord)
+	err := repo.conn.Model(auditRecord).Where("id = ?", id).First(auditRecord).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return auditRecord, nil
+}
+
+// Search is a method that searchs and returns set of audit records that match the filter limited to the page number,
+// the latest records are returned first
+func (repo *AuditRepository) Search(filter *audit.Filter, pageNum int64) ([]*entity.AuditRecord, int64) {
+
+	var auditRecords []*entity.AuditRecord
+	var count float64
+
+	// Starting with an always true statement so an empty filter matches every record
+	whereStmt := []string{" 1 = 1 "}
+	var sqlValues []interface{}
+
+	columns := []string{"actor", "action", "entity_type", "entity_id"}
+	values := []string{filter.Actor, filter.Action, filter.EntityType, filter.EntityID}
+	for index, value := range values {
+		if value != "" {
+			whereStmt = append(whereStmt, " "+columns[index]+" = ? ")
+			sqlValues = append(sqlValues, value)
+		}
+	}
+
+	if !filter.From.IsZero() {
+		whereStmt = append(whereStmt, " created_at >= ? ")
+		sqlValues = append(sqlValues, filter.From)
+	}
+
+	if !filter.To.IsZero() {
+		whereStmt = append(whereStmt, " created_at < ? ")
+		sqlValues = append(sqlValues, filter.To)
+	}
+
+	where := strings.Join(whereStmt, "&&")
+	repo.conn.Raw("SELECT COUNT(*) FROM audit_records WHERE ("+where+") ", sqlValues...).Count(&count)
+
+	sqlValues = append(sqlValues, pageNum*20)
+	repo.conn.Raw("SELECT * FROM audit_records WHERE ("+where+") ORDER BY id DESC LIMIT ?, 20",
+		sqlValues...).Scan(&auditRecords)
+
+	var pageCount int64 = int64(math.Ceil(count / 20.0))
+	return auditRecords, pageCount
+}
@@ -0,0 +1,34 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+)
+
+// SystemActor is the actor of the actions that haven't been performed on behalf of anyone, such as background workers
+const SystemActor = "System"
+
+// Filter is a type that defines the criteria audit records are searched with, empty criteria are ignored
+type Filter struct {
+	Actor      string
+	Action     string
+	EntityType string
+	EntityID   string
+	From       time.Time // Inclusive lower bound of the record time
+	To         time.Time // Exclusive upper bound of the record time
+}
+
+// Change is a type that defines the value of a field before and after an action,
+// sensitive values are redacted but their change is still recorded
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// IService is an interface that defines all the service methods of an audit record struct
+type IService interface {
+	Record(actor, action, entityID string, before, after interface{}) error
+	FindAuditRecord(id int64) (*entity.AuditRecord, error)
+	SearchAuditRecords(filter *Filter, pageNum int64) ([]*entity.AuditRecord, int64)
+}
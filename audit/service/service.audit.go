@@ -0,0 +1,189 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/Benyam-S/onemembership/audit"
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/log"
+	"github.com/Benyam-S/onemembership/redact"
+)
+
+// Service is a type that defines an audit service
+type Service struct {
+	auditRepo audit.IAuditRepository
+	logger    *log.Logger
+}
+
+// NewAuditService is a function that returns a new audit service
+func NewAuditService(auditRepository audit.IAuditRepository, serverLogger *log.Logger) audit.IService {
+	return &Service{auditRepo: auditRepository, logger: serverLogger}
+}
+
+// Record is a method that appends an action performed on an entity to the audit trail. The entity type is read from
+// the before or the after value, which should be structs or struct pointers of the same type, a nil before records
+// a creation and a nil after records a removal. Updates that haven't changed any field aren't recorded.
+func (service *Service) Record(actor, action, entityID string, before, after interface{}) error {
+
+	empty, _ := regexp.MatchString(`^\s*$`, actor)
+	if empty {
+		actor = audit.SystemActor
+	}
+
+	entityType, changes, err := diff(before, after)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For recording audit { Actor : %s, Action : %s, "+
+			"Entity ID : %s }, %s", actor, action, entityID, err.Error()))
+
+		return errors.New("unable to record audit")
+	}
+
+	if len(changes) == 0 && action == entity.AuditActionUpdate {
+		return nil
+	}
+
+	output, err := json.Marshal(changes)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For recording audit { Actor : %s, Action : %s, "+
+			"Entity ID : %s }, %s", actor, action, entityID, err.Error()))
+
+		return errors.New("unable to record audit")
+	}
+
+	auditRecord := &entity.AuditRecord{Actor: actor, Action: action, EntityType: entityType, EntityID: entityID,
+		Changes: string(output)}
+
+	err = service.auditRepo.Create(auditRecord)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For recording Audit Record => %s, %s",
+			auditRecord.ToString(), err.Error()))
+
+		return errors.New("unable to record audit")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Recorded audit, Audit Record => %s", auditRecord.ToString()),
+		service.logger.Logs.ServerLogFile)
+
+	return nil
+}
+
+// FindAuditRecord is a method that find and return an audit record that matches the id value
+func (service *Service) FindAuditRecord(id int64) (*entity.AuditRecord, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Single audit record finding process { Audit Record ID : %d }", id),
+		service.logger.Logs.ServerLogFile)
+
+	auditRecord, err := service.auditRepo.Find(id)
+	if err != nil {
+		return nil, errors.New("no audit record found")
+	}
+	return auditRecord, nil
+}
+
+// SearchAuditRecords is a method that searchs and returns a set of audit records that match the filter,
+// such as the history of an entity or the actions of an actor, along with the page count
+func (service *Service) SearchAuditRecords(filter *audit.Filter, pageNum int64) ([]*entity.AuditRecord, int64) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Searching audit records process { Actor : %s, Action : %s, Entity Type : %s, "+
+		"Entity ID : %s, Page Number : %d }", filter.Actor, filter.Action, filter.EntityType, filter.EntityID, pageNum),
+		service.logger.Logs.ServerLogFile)
+
+	return service.auditRepo.Search(filter, pageNum)
+}
+
+// diff is a function that returns the type name of the audited entity and the fields that differ between
+// the before and the after values. The values of redacted fields are masked but their change is kept.
+func diff(before, after interface{}) (string, map[string]*audit.Change, error) {
+
+	beforeValue, beforeOK := structValue(before)
+	afterValue, afterOK := structValue(after)
+
+	if !beforeOK && !afterOK {
+		return "", nil, errors.New("nothing to audit")
+	}
+
+	if beforeOK && afterOK && beforeValue.Type() != afterValue.Type() {
+		return "", nil, errors.New("before and after values are of different types")
+	}
+
+	entityType := afterValue
+	if beforeOK {
+		entityType = beforeValue
+	}
+
+	changes := make(map[string]*audit.Change)
+	reflectType := entityType.Type()
+	for index := 0; index < reflectType.NumField(); index++ {
+
+		field := reflectType.Field(index)
+
+		// The time of the record already holds when the entity has been updated
+		if field.PkgPath != "" || field.Name == "UpdatedAt" {
+			continue
+		}
+
+		var beforeField, afterField interface{}
+		if beforeOK {
+			beforeField = beforeValue.Field(index).Interface()
+		}
+		if afterOK {
+			afterField = afterValue.Field(index).Interface()
+		}
+
+		if beforeOK && afterOK && reflect.DeepEqual(beforeField, afterField) {
+			continue
+		}
+
+		policy, ok := field.Tag.Lookup(redact.Tag)
+		if !ok {
+			policy = redact.Policy(field.Name)
+		}
+
+		if policy != "" {
+			beforeField = redactField(policy, beforeField)
+			afterField = redactField(policy, afterField)
+		}
+
+		changes[field.Name] = &audit.Change{Before: beforeField, After: afterField}
+	}
+
+	return reflectType.Name(), changes, nil
+}
+
+// structValue is a function that returns the struct a value holds, false is returned for nil or non struct values
+func structValue(value interface{}) (reflect.Value, bool) {
+
+	reflectValue := reflect.ValueOf(value)
+	if reflectValue.Kind() == reflect.Ptr {
+		if reflectValue.IsNil() {
+			return reflect.Value{}, false
+		}
+		reflectValue = reflectValue.Elem()
+	}
+
+	return reflectValue, reflectValue.Kind() == reflect.Struct
+}
+
+// redactField is a function that redacts the value of a sensitive field according to the policy
+func redactField(policy string, value interface{}) interface{} {
+
+	if stringValue, ok := value.(string); ok {
+		return redact.Value(policy, stringValue)
+	}
+
+	if value == nil {
+		return nil
+	}
+
+	return redact.Value(redact.Secret, fmt.Sprint(value))
+}
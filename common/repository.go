@@ -1,10 +1,17 @@
 package common
 
-import "github.com/Benyam-S/onemembership/entity"
+import (
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+)
 
 // ICommonRepository is an interface that defines all the common repository methods
 type ICommonRepository interface {
 	IsUnique(columnName string, columnValue interface{}, tableName string) bool
+	HasActiveSPSubscription(providerID string, moment time.Time) bool
+	ProjectHasActiveSPSubscription(projectID string, moment time.Time) bool
+	AllCurrencyCodes() []string
 }
 
 // ILanguageRepository is an interface that defines all the repository methods of a language struct
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"regexp"
+	"time"
 
 	"github.com/Benyam-S/onemembership/common"
 	"github.com/Benyam-S/onemembership/tools"
@@ -32,3 +33,37 @@ func (repo *CommonRepository) IsUnique(columnName string, columnValue interface{
 
 	return tools.IsUnique(columnName, columnValue, tableName, repo.conn)
 }
+
+// HasActiveSPSubscription is a method that checks if a service provider has a subscription that hasn't expired at the given moment
+func (repo *CommonRepository) HasActiveSPSubscription(providerID string, moment time.Time) bool {
+
+	var count int64
+	err := repo.conn.Table("sp_subscriptions").Where("provider_id = ? && expires_at > ?", providerID, moment).
+		Count(&count).Error
+
+	return err == nil && count > 0
+}
+
+// ProjectHasActiveSPSubscription is a method that checks if the service provider of a project
+// has a subscription that hasn't expired at the given moment
+func (repo *CommonRepository) ProjectHasActiveSPSubscription(projectID string, moment time.Time) bool {
+
+	var count int64
+	err := repo.conn.Table("sp_subscriptions").Joins("INNER JOIN projects ON projects.provider_id = "+
+		"sp_subscriptions.provider_id").Where("projects.id = ? && sp_subscriptions.expires_at > ?", projectID, moment).
+		Count(&count).Error
+
+	return err == nil && count > 0
+}
+
+// AllCurrencyCodes is a method that returns the codes of all the currencies registered in the currency table
+func (repo *CommonRepository) AllCurrencyCodes() []string {
+
+	var codes []string
+	err := repo.conn.Table("currencies").Order("code ASC").Pluck("code", &codes).Error
+	if err != nil {
+		return []string{}
+	}
+
+	return codes
+}
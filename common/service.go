@@ -5,6 +5,8 @@ import "github.com/Benyam-S/onemembership/entity"
 // IService is an interface that defines all the common service methods
 type IService interface {
 	IsUnique(columnName string, columnValue interface{}, tableName string) bool
+	HasActiveSPSubscription(providerID string) bool
+	ProjectHasActiveSPSubscription(projectID string) bool
 	FindLanguage(identifier string) (*entity.Language, error)
 	FindLanguageEntry(identifier, code string) string
 	AllLanguages() []*entity.Language
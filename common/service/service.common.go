@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"time"
 
 	"github.com/Benyam-S/onemembership/common"
 	"github.com/Benyam-S/onemembership/entity"
@@ -31,6 +32,17 @@ func (service *Service) IsUnique(columnName string, columnValue interface{}, tab
 	return service.commonRepo.IsUnique(columnName, columnValue, tableName)
 }
 
+// HasActiveSPSubscription is a method that checks if a service provider currently has an active platform subscription
+func (service *Service) HasActiveSPSubscription(providerID string) bool {
+	return service.commonRepo.HasActiveSPSubscription(providerID, time.Now())
+}
+
+// ProjectHasActiveSPSubscription is a method that checks if the service provider of a project
+// currently has an active platform subscription
+func (service *Service) ProjectHasActiveSPSubscription(projectID string) bool {
+	return service.commonRepo.ProjectHasActiveSPSubscription(projectID, time.Now())
+}
+
 // FindLanguage is a method that find and return a certain language that matches the given identifier
 func (service *Service) FindLanguage(identifier string) (*entity.Language, error) {
 	/* ---------------------------- Logging ---------------------------- */
@@ -80,9 +92,10 @@ func (service *Service) GetAllValidChatTypes() []string {
 	return []string{"CHANNEL", "GROUP"}
 }
 
-// GetAllValidCurrencyTypes is a method that returns all the valid currency types that are supported by the system
+// GetAllValidCurrencyTypes is a method that returns all the valid currency types that are supported by the system,
+// which are the currencies registered in the currency table
 func (service *Service) GetAllValidCurrencyTypes() []string {
-	return []string{"ETB"}
+	return service.commonRepo.AllCurrencyCodes()
 }
 
 // GetAllValidLinkedAccountProviders is a method that returns all the valid linked account providers that are supported by the system
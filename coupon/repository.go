@@ -0,0 +1,21 @@
+package coupon
+
+import "github.com/Benyam-S/onemembership/entity"
+
+// ICouponRepository is an interface that defines all the repository methods of a coupon struct
+type ICouponRepository interface {
+	Create(newCoupon *entity.Coupon) error
+	Find(identifier string) (*entity.Coupon, error)
+	FindMultiple(providerID string) []*entity.Coupon
+	Update(coupon *entity.Coupon) error
+	Delete(id string) (*entity.Coupon, error)
+}
+
+// ICouponRedemptionRepository is an interface that defines all the repository methods of a coupon redemption struct
+type ICouponRedemptionRepository interface {
+	Reserve(newCouponRedemption *entity.CouponRedemption) error
+	Find(id int64) (*entity.CouponRedemption, error)
+	FindMultiple(couponID string) []*entity.CouponRedemption
+	Attach(id int64, subscriptionTransactionID string) error
+	Delete(id int64) (*entity.CouponRedemption, error)
+}
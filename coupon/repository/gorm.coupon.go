@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Benyam-S/onemembership/coupon"
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/tools"
+	"github.com/jinzhu/gorm"
+)
+
+// CouponRepository is a type that defines a coupon repository type
+type CouponRepository struct {
+	conn *gorm.DB
+}
+
+// NewCouponRepository is a function that creates a new coupon repository type
+func NewCouponRepository(connection *gorm.DB) coupon.ICouponRepository {
+	return &CouponRepository{conn: connection}
+}
+
+// Create is a method that adds a new coupon to the database
+func (repo *CouponRepository) Create(newCoupon *entity.Coupon) error {
+
+	totalNumOfCoupons := tools.CountMembers("coupons", repo.conn)
+	newCoupon.ID = fmt.Sprintf("CP-%s%d", tools.RandomStringGN(7), totalNumOfCoupons+1)
+
+	for !tools.IsUnique("id", newCoupon.ID, "coupons", repo.conn) {
+		totalNumOfCoupons++
+		newCoupon.ID = fmt.Sprintf("CP-%s%d", tools.RandomStringGN(7), totalNumOfCoupons+1)
+	}
+
+	err := repo.conn.Create(newCoupon).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Find is a method that finds a certain coupon from the database using an identifier,
+// also Find() uses id and code as a key for selection, codes are stored in upper case
+func (repo *CouponRepository) Find(identifier string) (*entity.Coupon, error) {
+
+	coupon := new(entity.Coupon)
+	err := repo.conn.Model(coupon).Where("id = ? || code = ?", identifier, strings.ToUpper(identifier)).
+		First(coupon).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return coupon, nil
+}
+
+// FindMultiple is a method that finds all the coupons of a service provider from the database
+func (repo *CouponRepository) FindMultiple(providerID string) []*entity.Coupon {
+
+	var coupons []*entity.Coupon
+	err := repo.conn.Model(entity.Coupon{}).Where("provider_id = ?", providerID).
+		Order("created_at DESC").Find(&coupons).Error
+
+	if err != nil {
+		return []*entity.Coupon{}
+	}
+	return coupons
+}
+
+// Update is a method that updates a certain coupon entries in the database
+func (repo *CouponRepository) Update(coupon *entity.Coupon) error {
+
+	prevCoupon := new(entity.Coupon)
+	err := repo.conn.Model(prevCoupon).Where("id = ?", coupon.ID).First(prevCoupon).Error
+
+	if err != nil {
+		return err
+	}
+
+	/* --------------------------- can change layer if needed --------------------------- */
+	coupon.ProviderID = prevCoupon.ProviderID
+	coupon.CreatedAt = prevCoupon.CreatedAt
+	/* -------------------------------------- end --------------------------------------- */
+
+	err = repo.conn.Save(coupon).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Delete is a method that deletes a certain coupon from the database using a coupon id.
+// In Delete() id is only used as an key
+func (repo *CouponRepository) Delete(id string) (*entity.Coupon, error) {
+	coupon := new(entity.Coupon)
+	err := repo.conn.Model(coupon).Where("id = ?", id).First(coupon).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	repo.conn.Delete(coupon)
+	return coupon, nil
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Benyam-S/onemembership/coupon"
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/jinzhu/gorm"
+)
+
+// CouponRedemptionRepository is a type that defines a coupon redemption repository type
+type CouponRedemptionRepository struct {
+	conn *gorm.DB
+}
+
+// NewCouponRedemptionRepository is a function that creates a new coupon redemption repository type
+func NewCouponRedemptionRepository(connection *gorm.DB) coupon.ICouponRedemptionRepository {
+	return &CouponRedemptionRepository{conn: connection}
+}
+
+// Reserve is a method that adds a new coupon redemption to the database if the coupon's redemption limits allow it.
+// The coupon is locked while the redemptions are counted so concurrent checkouts can't exceed the limits,
+// redemptions whose transaction has failed or expired aren't counted
+func (repo *CouponRedemptionRepository) Reserve(newCouponRedemption *entity.CouponRedemption) error {
+
+	return repo.conn.Transaction(func(tx *gorm.DB) error {
+
+		lockedCoupon := new(entity.Coupon)
+		err := tx.Set("gorm:query_option", "FOR UPDATE").
+			Where("id = ?", newCouponRedemption.CouponID).First(lockedCoupon).Error
+		if err != nil {
+			return err
+		}
+
+		if !newCouponRedemption.IsRenewal {
+			if lockedCoupon.MaxRedemptions > 0 &&
+				countRedemptions(tx, lockedCoupon.ID, "") >= lockedCoupon.MaxRedemptions {
+				return errors.New("coupon has reached its redemption limit")
+			}
+
+			if lockedCoupon.PerUserLimit > 0 &&
+				countRedemptions(tx, lockedCoupon.ID, newCouponRedemption.UserID) >= lockedCoupon.PerUserLimit {
+				return errors.New("coupon has reached its redemption limit for the user")
+			}
+		}
+
+		return tx.Create(newCouponRedemption).Error
+	})
+}
+
+// countRedemptions is a function that counts the redemptions of a coupon that hold or may still hold,
+// the count is limited to a single user's redemptions when a user id is given
+func countRedemptions(tx *gorm.DB, couponID, userID string) int64 {
+
+	query := "SELECT COUNT(*) AS total FROM coupon_redemptions R " +
+		"LEFT JOIN subscription_transactions T ON T.id = R.subscription_transaction_id " +
+		"WHERE R.coupon_id = ? && R.is_renewal = false && (T.status IS NULL || T.status NOT IN (?, ?))"
+	values := []interface{}{couponID, entity.TransactionStatusFailed, entity.TransactionStatusExpired}
+
+	if userID != "" {
+		query += " && R.user_id = ?"
+		values = append(values, userID)
+	}
+
+	var count struct{ Total int64 }
+	tx.Raw(query, values...).Scan(&count)
+
+	return count.Total
+}
+
+// Find is a method that finds a certain coupon redemption from the database using a coupon redemption id,
+// also Find() uses only id as a key for selection
+func (repo *CouponRedemptionRepository) Find(id int64) (*entity.CouponRedemption, error) {
+
+	couponRedemption := new(entity.CouponRedemption)
+	err := repo.conn.Model(couponRedemption).Where("id = ?", id).First(couponRedemption).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return couponRedemption, nil
+}
+
+// FindMultiple is a method that finds all the redemptions of a coupon from the database
+func (repo *CouponRedemptionRepository) FindMultiple(couponID string) []*entity.CouponRedemption {
+
+	var couponRedemptions []*entity.CouponRedemption
+	err := repo.conn.Model(entity.CouponRedemption{}).Where("coupon_id = ?", couponID).
+		Order("created_at DESC").Find(&couponRedemptions).Error
+
+	if err != nil {
+		return []*entity.CouponRedemption{}
+	}
+	return couponRedemptions
+}
+
+// Attach is a method that records the subscription transaction a reserved coupon redemption has been made for
+func (repo *CouponRedemptionRepository) Attach(id int64, subscriptionTransactionID string) error {
+
+	result := repo.conn.Model(entity.CouponRedemption{}).Where("id = ? && subscription_transaction_id = ?", id, "").
+		Update(map[string]interface{}{"subscription_transaction_id": subscriptionTransactionID})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("no reserved coupon redemption found")
+	}
+
+	return nil
+}
+
+// Delete is a method that deletes a certain coupon redemption from the database using a coupon redemption id.
+// In Delete() id is only used as an key
+func (repo *CouponRedemptionRepository) Delete(id int64) (*entity.CouponRedemption, error) {
+	couponRedemption := new(entity.CouponRedemption)
+	err := repo.conn.Model(couponRedemption).Where("id = ?", id).First(couponRedemption).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	repo.conn.Delete(couponRedemption)
+	return couponRedemption, nil
+}
@@ -0,0 +1,38 @@
+package coupon
+
+import (
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/money"
+)
+
+// BasisPoints is a constant that holds the rate of a whole, so a percentage coupon with a rate of BasisPoints is 100%
+const BasisPoints = 10000
+
+// Request is a type that defines the details needed to apply a coupon to a subscription checkout
+type Request struct {
+	Identifier         string // The code the user has entered, or the coupon id carried over by a renewal
+	UserID             string
+	SubscriptionPlanID string
+	CurrencyType       string
+	Amount             money.Amount // The price the coupon is applied to
+	IsRenewal          bool
+	Moment             time.Time
+}
+
+// IService is an interface that defines all the service methods of a coupon struct
+type IService interface {
+	AddCoupon(newCoupon *entity.Coupon) error
+	ValidateCoupon(coupon *entity.Coupon) entity.ErrMap
+	FindCoupon(identifier string) (*entity.Coupon, error)
+	FindMultipleCoupons(providerID string) []*entity.Coupon
+	UpdateCoupon(coupon *entity.Coupon) error
+	DeleteCoupon(id string) (*entity.Coupon, error)
+
+	ApplyCoupon(request *Request) (*entity.CouponRedemption, error)
+	RedeemCoupon(couponRedemption *entity.CouponRedemption) error
+	AttachCouponRedemption(id int64, subscriptionTransactionID string) error
+	ReleaseCouponRedemption(id int64) error
+	FindMultipleCouponRedemptions(couponID string) []*entity.CouponRedemption
+}
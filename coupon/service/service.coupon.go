@@ -0,0 +1,243 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Benyam-S/onemembership/common"
+	"github.com/Benyam-S/onemembership/coupon"
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/log"
+	"github.com/Benyam-S/onemembership/project"
+	"github.com/Benyam-S/onemembership/subscriptionplan"
+)
+
+// basisPoints is a constant that holds the rate of a whole, since the coupon package is shadowed by the coupon variables
+const basisPoints = coupon.BasisPoints
+
+// Service is a type that defines a coupon service
+type Service struct {
+	couponRepo              coupon.ICouponRepository
+	couponRedemptionRepo    coupon.ICouponRedemptionRepository
+	subscriptionPlanService subscriptionplan.IService
+	projectService          project.IService
+	cmService               common.IService
+	logger                  *log.Logger
+}
+
+// NewCouponService is a function that returns a new coupon service
+func NewCouponService(couponRepository coupon.ICouponRepository,
+	couponRedemptionRepository coupon.ICouponRedemptionRepository, subscriptionPlanService subscriptionplan.IService,
+	projectService project.IService, commonService common.IService, subscriptionPlanLogger *log.Logger) coupon.IService {
+	return &Service{couponRepo: couponRepository, couponRedemptionRepo: couponRedemptionRepository,
+		subscriptionPlanService: subscriptionPlanService, projectService: projectService, cmService: commonService,
+		logger: subscriptionPlanLogger}
+}
+
+// AddCoupon is a method that adds a new coupon to the system
+func (service *Service) AddCoupon(newCoupon *entity.Coupon) error {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started coupon adding process, Coupon => %s",
+		newCoupon.ToString()), service.logger.Logs.SubscriptionPlanLogFile)
+
+	err := service.couponRepo.Create(newCoupon)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For adding Coupon => %s, %s",
+			newCoupon.ToString(), err.Error()))
+
+		return errors.New("unable to add new coupon")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished coupon adding process, Coupon => %s",
+		newCoupon.ToString()), service.logger.Logs.SubscriptionPlanLogFile)
+
+	return nil
+}
+
+// ValidateCoupon is a method that validates a coupon entries.
+// It checks if the coupon has a valid entries or not and return map of errors if any.
+func (service *Service) ValidateCoupon(coupon *entity.Coupon) entity.ErrMap {
+
+	errMap := make(map[string]error)
+
+	// Formatting the code so users can enter it in any case
+	coupon.Code = strings.ToUpper(strings.TrimSpace(coupon.Code))
+	isValidCode, _ := regexp.MatchString(`^[A-Z0-9_-]{3,32}$`, coupon.Code)
+	if !isValidCode {
+		errMap["code"] = errors.New(`coupon code should be 3 to 32 letters, digits, dashes or underscores`)
+	} else if prevCoupon, err := service.couponRepo.Find(coupon.Code); err == nil && prevCoupon.ID != coupon.ID {
+		errMap["code"] = errors.New(`coupon code is taken, please try another code`)
+	}
+
+	// A coupon can't discount the whole price since payment gateways can't take a zero payment
+	switch coupon.Type {
+	case entity.CouponTypePercentage:
+		if coupon.Rate <= 0 || coupon.Rate >= basisPoints {
+			errMap["rate"] = errors.New(`percentage coupon should have a rate between 0 and 10000 basis points`)
+		}
+		if coupon.FixedAmount != 0 {
+			errMap["fixed_amount"] = errors.New(`percentage coupon can not have a fixed amount`)
+		}
+
+	case entity.CouponTypeFixed:
+		if coupon.FixedAmount <= 0 {
+			errMap["fixed_amount"] = errors.New(`fixed coupon should have a fixed amount`)
+		}
+		if coupon.Rate != 0 {
+			errMap["rate"] = errors.New(`fixed coupon can not have a rate`)
+		}
+
+		var isValidCurrency bool
+		for _, currencyType := range service.cmService.GetAllValidCurrencyTypes() {
+			if strings.EqualFold(currencyType, coupon.Currency) {
+				coupon.Currency = currencyType
+				isValidCurrency = true
+				break
+			}
+		}
+
+		if !isValidCurrency {
+			errMap["currency"] = errors.New(`invalid currency type selected`)
+		}
+
+	default:
+		errMap["type"] = errors.New(`invalid coupon type selected`)
+	}
+
+	if coupon.MaxRedemptions < 0 {
+		errMap["max_redemptions"] = errors.New(`maximum redemptions can not be negative`)
+	}
+
+	if coupon.PerUserLimit < 0 {
+		errMap["per_user_limit"] = errors.New(`per user limit can not be negative`)
+	}
+
+	if !coupon.ValidUntil.IsZero() && !coupon.ValidUntil.After(coupon.ValidFrom) {
+		errMap["valid_until"] = errors.New(`coupon should be valid until a date after its start date`)
+	}
+
+	// Restricting the coupon to the service provider's own subscription plans and projects
+	for _, planID := range splitIDs(coupon.PlanIDs) {
+		if providerID, err := service.planProviderID(planID); err != nil || providerID != coupon.ProviderID {
+			errMap["plan_ids"] = errors.New(`invalid subscription plan selected`)
+			break
+		}
+	}
+
+	for _, projectID := range splitIDs(coupon.ProjectIDs) {
+		project, err := service.projectService.FindProject(projectID)
+		if err != nil || project.ProviderID != coupon.ProviderID {
+			errMap["project_ids"] = errors.New(`invalid project selected`)
+			break
+		}
+	}
+
+	if len(errMap) > 0 {
+		return errMap
+	}
+
+	return nil
+}
+
+// FindCoupon is a method that find and return a coupon that matches the identifier value
+func (service *Service) FindCoupon(identifier string) (*entity.Coupon, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Single coupon finding process { Identifier : %s }", identifier),
+		service.logger.Logs.SubscriptionPlanLogFile)
+
+	empty, _ := regexp.MatchString(`^\s*$`, identifier)
+	if empty {
+		return nil, errors.New("no coupon found")
+	}
+
+	coupon, err := service.couponRepo.Find(strings.TrimSpace(identifier))
+	if err != nil {
+		return nil, errors.New("no coupon found")
+	}
+	return coupon, nil
+}
+
+// FindMultipleCoupons is a method that find and return multiple coupons that belong to a service provider
+func (service *Service) FindMultipleCoupons(providerID string) []*entity.Coupon {
+	return service.couponRepo.FindMultiple(providerID)
+}
+
+// UpdateCoupon is a method that updates a coupon in the system
+func (service *Service) UpdateCoupon(coupon *entity.Coupon) error {
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started coupon updating process, Coupon => %s",
+		coupon.ToString()), service.logger.Logs.SubscriptionPlanLogFile)
+
+	err := service.couponRepo.Update(coupon)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For updating Coupon => %s, %s",
+			coupon.ToString(), err.Error()))
+
+		return errors.New("unable to update coupon")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished coupon updating process, Coupon => %s",
+		coupon.ToString()), service.logger.Logs.SubscriptionPlanLogFile)
+
+	return nil
+}
+
+// DeleteCoupon is a method that deletes a coupon from the system using an id,
+// the redemptions of the coupon are kept so the transactions it has been redeemed for can still be traced
+func (service *Service) DeleteCoupon(id string) (*entity.Coupon, error) {
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started coupon deleting process { Coupon ID : %s }", id),
+		service.logger.Logs.SubscriptionPlanLogFile)
+
+	coupon, err := service.couponRepo.Delete(id)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For deleting coupon { Coupon ID : %s }, %s",
+			id, err.Error()))
+
+		return nil, errors.New("unable to delete coupon")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished coupon deleting process, Deleted Coupon => %s",
+		coupon.ToString()), service.logger.Logs.SubscriptionPlanLogFile)
+
+	return coupon, nil
+}
+
+// planProviderID is a method that returns the id of the service provider that owns a subscription plan
+func (service *Service) planProviderID(planID string) (string, error) {
+
+	subscriptionPlan, err := service.subscriptionPlanService.FindSubscriptionPlan(planID)
+	if err != nil {
+		return "", err
+	}
+
+	project, err := service.projectService.FindProject(subscriptionPlan.ProjectID)
+	if err != nil {
+		return "", err
+	}
+
+	return project.ProviderID, nil
+}
+
+// splitIDs is a function that splits a comma separated list of ids, ignoring the empty entries
+func splitIDs(ids string) []string {
+
+	var splitIDs []string
+	for _, id := range strings.Split(ids, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			splitIDs = append(splitIDs, id)
+		}
+	}
+
+	return splitIDs
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Benyam-S/onemembership/coupon"
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/money"
+)
+
+// ApplyCoupon is a method that checks whether a coupon can be used for a subscription checkout
+// and returns the redemption holding the discount it gives, the redemption has to be reserved using RedeemCoupon.
+// Renewals carry over the discount of the coupon the subscription has been redeemed with regardless of its validity window,
+// unless the coupon only discounts the first period.
+func (service *Service) ApplyCoupon(request *coupon.Request) (*entity.CouponRedemption, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Coupon applying process { Identifier : %s, User ID : %s, Plan ID : %s, "+
+		"Amount : %s %s }", request.Identifier, request.UserID, request.SubscriptionPlanID, request.Amount,
+		request.CurrencyType), service.logger.Logs.SubscriptionPlanLogFile)
+
+	coupon, err := service.FindCoupon(request.Identifier)
+	if err != nil {
+		return nil, errors.New("invalid coupon code used")
+	}
+
+	if request.IsRenewal && coupon.FirstPeriodOnly {
+		return nil, errors.New("coupon only discounts the first period")
+	}
+
+	if !request.IsRenewal {
+		if request.Moment.Before(coupon.ValidFrom) {
+			return nil, errors.New("coupon is not valid yet")
+		}
+
+		if !coupon.ValidUntil.IsZero() && request.Moment.After(coupon.ValidUntil) {
+			return nil, errors.New("coupon has expired")
+		}
+	}
+
+	subscriptionPlan, err := service.subscriptionPlanService.FindSubscriptionPlan(request.SubscriptionPlanID)
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := service.projectService.FindProject(subscriptionPlan.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if project.ProviderID != coupon.ProviderID || !isRestrictedTo(coupon, subscriptionPlan.ID, project.ID) {
+		return nil, errors.New("coupon doesn't apply to the subscription plan")
+	}
+
+	var discountAmount money.Amount
+	switch coupon.Type {
+	case entity.CouponTypePercentage:
+		discountAmount = request.Amount.Prorate(coupon.Rate, basisPoints)
+
+	case entity.CouponTypeFixed:
+		if !strings.EqualFold(coupon.Currency, request.CurrencyType) {
+			return nil, errors.New("coupon can not be used with the subscription plan's currency")
+		}
+		discountAmount = coupon.FixedAmount
+
+	default:
+		return nil, errors.New("invalid coupon type")
+	}
+
+	if discountAmount >= request.Amount {
+		return nil, errors.New("coupon can not discount the whole price")
+	}
+
+	couponRedemption := &entity.CouponRedemption{
+		CouponID:       coupon.ID,
+		UserID:         request.UserID,
+		DiscountAmount: discountAmount,
+		CurrencyType:   strings.ToUpper(request.CurrencyType),
+		IsRenewal:      request.IsRenewal,
+	}
+
+	return couponRedemption, nil
+}
+
+// isRestrictedTo is a function that checks whether a coupon can be used for the given subscription plan and project,
+// a coupon without restrictions can be used for all the plans of its service provider
+func isRestrictedTo(coupon *entity.Coupon, planID, projectID string) bool {
+
+	planIDs := splitIDs(coupon.PlanIDs)
+	projectIDs := splitIDs(coupon.ProjectIDs)
+	if len(planIDs) == 0 && len(projectIDs) == 0 {
+		return true
+	}
+
+	for _, id := range planIDs {
+		if id == planID {
+			return true
+		}
+	}
+
+	for _, id := range projectIDs {
+		if id == projectID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RedeemCoupon is a method that reserves a coupon redemption for a checkout,
+// the redemption fails if the coupon has reached its redemption limits
+func (service *Service) RedeemCoupon(couponRedemption *entity.CouponRedemption) error {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started coupon redeeming process, Coupon Redemption => %s",
+		couponRedemption.ToString()), service.logger.Logs.SubscriptionPlanLogFile)
+
+	err := service.couponRedemptionRepo.Reserve(couponRedemption)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For redeeming Coupon Redemption => %s, %s",
+			couponRedemption.ToString(), err.Error()))
+
+		return errors.New("unable to redeem coupon")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished coupon redeeming process, Coupon Redemption => %s",
+		couponRedemption.ToString()), service.logger.Logs.SubscriptionPlanLogFile)
+
+	return nil
+}
+
+// AttachCouponRedemption is a method that records the subscription transaction a reserved coupon redemption has been made for
+func (service *Service) AttachCouponRedemption(id int64, subscriptionTransactionID string) error {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Coupon redemption attaching process { Coupon Redemption ID : %d, "+
+		"Subscription Transaction ID : %s }", id, subscriptionTransactionID), service.logger.Logs.SubscriptionPlanLogFile)
+
+	err := service.couponRedemptionRepo.Attach(id, subscriptionTransactionID)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For attaching coupon redemption { Coupon Redemption ID : %d, "+
+			"Subscription Transaction ID : %s }, %s", id, subscriptionTransactionID, err.Error()))
+
+		return errors.New("unable to attach coupon redemption")
+	}
+
+	return nil
+}
+
+// ReleaseCouponRedemption is a method that removes a reserved coupon redemption whose checkout has failed,
+// so it no longer counts towards the coupon's redemption limits
+func (service *Service) ReleaseCouponRedemption(id int64) error {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Coupon redemption releasing process { Coupon Redemption ID : %d }", id),
+		service.logger.Logs.SubscriptionPlanLogFile)
+
+	couponRedemption, err := service.couponRedemptionRepo.Find(id)
+	if err != nil {
+		return errors.New("no coupon redemption found")
+	}
+
+	if couponRedemption.SubscriptionTransactionID != "" {
+		return errors.New("coupon redemption has already been made for a subscription transaction")
+	}
+
+	_, err = service.couponRedemptionRepo.Delete(id)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For releasing coupon redemption "+
+			"{ Coupon Redemption ID : %d }, %s", id, err.Error()))
+
+		return errors.New("unable to release coupon redemption")
+	}
+
+	return nil
+}
+
+// FindMultipleCouponRedemptions is a method that find and return multiple redemptions of a coupon
+func (service *Service) FindMultipleCouponRedemptions(couponID string) []*entity.CouponRedemption {
+	return service.couponRedemptionRepo.FindMultiple(couponID)
+}
@@ -0,0 +1,25 @@
+package currency
+
+import (
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+)
+
+// ICurrencyRepository is an interface that defines all the repository methods of a currency struct
+type ICurrencyRepository interface {
+	Create(newCurrency *entity.Currency) error
+	Find(code string) (*entity.Currency, error)
+	All() []*entity.Currency
+	Update(currency *entity.Currency) error
+	Delete(code string) (*entity.Currency, error)
+}
+
+// IExchangeRateRepository is an interface that defines all the repository methods of an exchange rate struct
+type IExchangeRateRepository interface {
+	Create(newExchangeRate *entity.ExchangeRate) error
+	Find(id int64) (*entity.ExchangeRate, error)
+	FindEffective(baseCurrency, quoteCurrency string, moment time.Time) (*entity.ExchangeRate, error)
+	FindMultiple(baseCurrency, quoteCurrency string) []*entity.ExchangeRate
+	Delete(id int64) (*entity.ExchangeRate, error)
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"github.com/Benyam-S/onemembership/currency"
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/jinzhu/gorm"
+)
+
+// CurrencyRepository is a type that defines a currency repository type
+type CurrencyRepository struct {
+	conn *gorm.DB
+}
+
+// NewCurrencyRepository is a function that creates a new currency repository type
+func NewCurrencyRepository(connection *gorm.DB) currency.ICurrencyRepository {
+	return &CurrencyRepository{conn: connection}
+}
+
+// Create is a method that adds a new currency to the database
+func (repo *CurrencyRepository) Create(newCurrency *entity.Currency) error {
+	err := repo.conn.Create(newCurrency).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Find is a method that finds a certain currency from the database using a currency code,
+// also Find() uses only code as a key for selection
+func (repo *CurrencyRepository) Find(code string) (*entity.Currency, error) {
+
+	currency := new(entity.Currency)
+	err := repo.conn.Model(currency).Where("code = ?", code).First(currency).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return currency, nil
+}
+
+// All is a method that returns all the currencies found in the database
+func (repo *CurrencyRepository) All() []*entity.Currency {
+
+	var currencies []*entity.Currency
+	repo.conn.Model(entity.Currency{}).Order("code ASC").Find(&currencies)
+
+	return currencies
+}
+
+// Update is a method that updates a certain currency entries in the database
+func (repo *CurrencyRepository) Update(currency *entity.Currency) error {
+
+	prevCurrency := new(entity.Currency)
+	err := repo.conn.Model(prevCurrency).Where("code = ?", currency.Code).First(prevCurrency).Error
+
+	if err != nil {
+		return err
+	}
+
+	/* --------------------------- can change layer if needed --------------------------- */
+	currency.CreatedAt = prevCurrency.CreatedAt
+	/* -------------------------------------- end --------------------------------------- */
+
+	err = repo.conn.Save(currency).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Delete is a method that deletes a certain currency from the database using a currency code.
+// In Delete() code is only used as an key
+func (repo *CurrencyRepository) Delete(code string) (*entity.Currency, error) {
+	currency := new(entity.Currency)
+	err := repo.conn.Model(currency).Where("code = ?", code).First(currency).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	repo.conn.Delete(currency)
+	return currency, nil
+}
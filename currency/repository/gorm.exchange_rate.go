@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/Benyam-S/onemembership/currency"
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/jinzhu/gorm"
+)
+
+// ExchangeRateRepository is a type that defines an exchange rate repository type
+type ExchangeRateRepository struct {
+	conn *gorm.DB
+}
+
+// NewExchangeRateRepository is a function that creates a new exchange rate repository type
+func NewExchangeRateRepository(connection *gorm.DB) currency.IExchangeRateRepository {
+	return &ExchangeRateRepository{conn: connection}
+}
+
+// Create is a method that adds a new exchange rate to the database
+func (repo *ExchangeRateRepository) Create(newExchangeRate *entity.ExchangeRate) error {
+	err := repo.conn.Create(newExchangeRate).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Find is a method that finds a certain exchange rate from the database using an exchange rate id,
+// also Find() uses only id as a key for selection
+func (repo *ExchangeRateRepository) Find(id int64) (*entity.ExchangeRate, error) {
+
+	exchangeRate := new(entity.ExchangeRate)
+	err := repo.conn.Model(exchangeRate).Where("id = ?", id).First(exchangeRate).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return exchangeRate, nil
+}
+
+// FindEffective is a method that finds the exchange rate of a currency pair that is in effect at the given moment,
+// which is the latest rate that has become effective before the moment
+func (repo *ExchangeRateRepository) FindEffective(baseCurrency, quoteCurrency string,
+	moment time.Time) (*entity.ExchangeRate, error) {
+
+	exchangeRate := new(entity.ExchangeRate)
+	err := repo.conn.Model(exchangeRate).Where("base_currency = ? && quote_currency = ? && effective_at <= ?",
+		baseCurrency, quoteCurrency, moment).Order("effective_at DESC, id DESC").First(exchangeRate).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return exchangeRate, nil
+}
+
+// FindMultiple is a method that finds the exchange rates of a currency pair from the database,
+// the latest effective rate comes first
+func (repo *ExchangeRateRepository) FindMultiple(baseCurrency, quoteCurrency string) []*entity.ExchangeRate {
+
+	var exchangeRates []*entity.ExchangeRate
+	err := repo.conn.Model(entity.ExchangeRate{}).Where("base_currency = ? && quote_currency = ?",
+		baseCurrency, quoteCurrency).Order("effective_at DESC, id DESC").Find(&exchangeRates).Error
+
+	if err != nil {
+		return []*entity.ExchangeRate{}
+	}
+	return exchangeRates
+}
+
+// Delete is a method that deletes a certain exchange rate from the database using an exchange rate id.
+// In Delete() id is only used as an key
+func (repo *ExchangeRateRepository) Delete(id int64) (*entity.ExchangeRate, error) {
+	exchangeRate := new(entity.ExchangeRate)
+	err := repo.conn.Model(exchangeRate).Where("id = ?", id).First(exchangeRate).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	repo.conn.Delete(exchangeRate)
+	return exchangeRate, nil
+}
@@ -0,0 +1,25 @@
+package currency
+
+import (
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/money"
+)
+
+// IService is an interface that defines all the service methods of a currency struct
+type IService interface {
+	AddCurrency(newCurrency *entity.Currency) error
+	ValidateCurrency(currency *entity.Currency) entity.ErrMap
+	FindCurrency(code string) (*entity.Currency, error)
+	AllCurrencies() []*entity.Currency
+	UpdateCurrency(currency *entity.Currency) error
+	DeleteCurrency(code string) (*entity.Currency, error)
+
+	AddExchangeRate(newExchangeRate *entity.ExchangeRate) error
+	ValidateExchangeRate(exchangeRate *entity.ExchangeRate) entity.ErrMap
+	FindExchangeRate(baseCurrency, quoteCurrency string, moment time.Time) (money.Rate, error)
+	FindMultipleExchangeRates(baseCurrency, quoteCurrency string) []*entity.ExchangeRate
+	DeleteExchangeRate(id int64) (*entity.ExchangeRate, error)
+	Convert(amount money.Amount, fromCurrency, toCurrency string, moment time.Time) (money.Amount, money.Rate, error)
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Benyam-S/onemembership/currency"
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/log"
+)
+
+// Service is a type that defines a currency service
+type Service struct {
+	currencyRepo     currency.ICurrencyRepository
+	exchangeRateRepo currency.IExchangeRateRepository
+	logger           *log.Logger
+}
+
+// NewCurrencyService is a function that returns a new currency service
+func NewCurrencyService(currencyRepository currency.ICurrencyRepository,
+	exchangeRateRepository currency.IExchangeRateRepository, transactionLogger *log.Logger) currency.IService {
+	return &Service{currencyRepo: currencyRepository, exchangeRateRepo: exchangeRateRepository,
+		logger: transactionLogger}
+}
+
+// AddCurrency is a method that adds a new currency to the system
+func (service *Service) AddCurrency(newCurrency *entity.Currency) error {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started currency adding process, Currency => %s",
+		newCurrency.ToString()), service.logger.Logs.TransactionLogFile)
+
+	err := service.currencyRepo.Create(newCurrency)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For adding Currency => %s, %s",
+			newCurrency.ToString(), err.Error()))
+
+		return errors.New("unable to add new currency")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished currency adding process, Currency => %s",
+		newCurrency.ToString()), service.logger.Logs.TransactionLogFile)
+
+	return nil
+}
+
+// ValidateCurrency is a method that validates a currency entries.
+// It checks if the currency has a valid entries or not and return map of errors if any.
+func (service *Service) ValidateCurrency(currency *entity.Currency) entity.ErrMap {
+
+	errMap := make(map[string]error)
+
+	// Formatting the code
+	currency.Code = strings.ToUpper(strings.TrimSpace(currency.Code))
+	isValidCode, _ := regexp.MatchString(`^[A-Z]{3}$`, currency.Code)
+	if !isValidCode {
+		errMap["code"] = errors.New(`currency code should be a three letter code`)
+	}
+
+	empty, _ := regexp.MatchString(`^\s*$`, currency.Name)
+	if empty {
+		errMap["name"] = errors.New(`currency name can not be empty`)
+	} else if len(currency.Name) > 255 {
+		errMap["name"] = errors.New(`currency name should not be longer than 255 characters`)
+	}
+
+	if len(currency.Symbol) > 10 {
+		errMap["symbol"] = errors.New(`currency symbol should not be longer than 10 characters`)
+	}
+
+	// Amounts are stored with two fraction digits so a currency can't be more precise than that
+	if currency.Precision < 0 || currency.Precision > 2 {
+		errMap["precision"] = errors.New(`currency precision should be between 0 and 2`)
+	}
+
+	if len(errMap) > 0 {
+		return errMap
+	}
+
+	return nil
+}
+
+// FindCurrency is a method that find and return a currency that matches the code value
+func (service *Service) FindCurrency(code string) (*entity.Currency, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Single currency finding process { Currency Code : %s }", code),
+		service.logger.Logs.TransactionLogFile)
+
+	empty, _ := regexp.MatchString(`^\s*$`, code)
+	if empty {
+		return nil, errors.New("no currency found")
+	}
+
+	currency, err := service.currencyRepo.Find(strings.ToUpper(code))
+	if err != nil {
+		return nil, errors.New("no currency found")
+	}
+	return currency, nil
+}
+
+// AllCurrencies is a method that returns all the currencies in the system
+func (service *Service) AllCurrencies() []*entity.Currency {
+	return service.currencyRepo.All()
+}
+
+// UpdateCurrency is a method that updates a currency in the system
+func (service *Service) UpdateCurrency(currency *entity.Currency) error {
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started currency updating process, Currency => %s",
+		currency.ToString()), service.logger.Logs.TransactionLogFile)
+
+	err := service.currencyRepo.Update(currency)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For updating Currency => %s, %s",
+			currency.ToString(), err.Error()))
+
+		return errors.New("unable to update currency")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished currency updating process, Currency => %s",
+		currency.ToString()), service.logger.Logs.TransactionLogFile)
+
+	return nil
+}
+
+// DeleteCurrency is a method that deletes a currency from the system using a code
+func (service *Service) DeleteCurrency(code string) (*entity.Currency, error) {
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started currency deleting process { Currency Code : %s }", code),
+		service.logger.Logs.TransactionLogFile)
+
+	currency, err := service.currencyRepo.Delete(strings.ToUpper(code))
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For deleting currency { Currency Code : %s }, %s",
+			code, err.Error()))
+
+		return nil, errors.New("unable to delete currency")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished currency deleting process, Deleted Currency => %s",
+		currency.ToString()), service.logger.Logs.TransactionLogFile)
+
+	return currency, nil
+}
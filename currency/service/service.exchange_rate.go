@@ -0,0 +1,147 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/money"
+)
+
+// AddExchangeRate is a method that adds a new exchange rate to the system,
+// rates are never changed so a new rate is added whenever the rate of a currency pair changes
+func (service *Service) AddExchangeRate(newExchangeRate *entity.ExchangeRate) error {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started exchange rate adding process, Exchange Rate => %s",
+		newExchangeRate.ToString()), service.logger.Logs.TransactionLogFile)
+
+	err := service.exchangeRateRepo.Create(newExchangeRate)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For adding Exchange Rate => %s, %s",
+			newExchangeRate.ToString(), err.Error()))
+
+		return errors.New("unable to add new exchange rate")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished exchange rate adding process, Exchange Rate => %s",
+		newExchangeRate.ToString()), service.logger.Logs.TransactionLogFile)
+
+	return nil
+}
+
+// ValidateExchangeRate is a method that validates an exchange rate entries.
+// It checks if the exchange rate has a valid entries or not and return map of errors if any.
+func (service *Service) ValidateExchangeRate(exchangeRate *entity.ExchangeRate) entity.ErrMap {
+
+	errMap := make(map[string]error)
+
+	// Formatting the currency codes
+	exchangeRate.BaseCurrency = strings.ToUpper(strings.TrimSpace(exchangeRate.BaseCurrency))
+	exchangeRate.QuoteCurrency = strings.ToUpper(strings.TrimSpace(exchangeRate.QuoteCurrency))
+
+	if _, err := service.currencyRepo.Find(exchangeRate.BaseCurrency); err != nil {
+		errMap["base_currency"] = errors.New(`invalid base currency selected`)
+	}
+
+	if _, err := service.currencyRepo.Find(exchangeRate.QuoteCurrency); err != nil {
+		errMap["quote_currency"] = errors.New(`invalid quote currency selected`)
+	} else if exchangeRate.QuoteCurrency == exchangeRate.BaseCurrency {
+		errMap["quote_currency"] = errors.New(`quote currency should be different from the base currency`)
+	}
+
+	if exchangeRate.Rate <= 0 {
+		errMap["rate"] = errors.New(`invalid exchange rate used`)
+	}
+
+	if exchangeRate.EffectiveAt.IsZero() {
+		exchangeRate.EffectiveAt = time.Now()
+	}
+
+	if len(errMap) > 0 {
+		return errMap
+	}
+
+	return nil
+}
+
+// FindExchangeRate is a method that returns the rate of a currency pair that is in effect at the given moment,
+// the inverse of the opposite pair's rate is used when the pair itself has no rate
+func (service *Service) FindExchangeRate(baseCurrency, quoteCurrency string, moment time.Time) (money.Rate, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Exchange rate finding process { Base Currency : %s, Quote Currency : %s, "+
+		"Moment : %s }", baseCurrency, quoteCurrency, moment), service.logger.Logs.TransactionLogFile)
+
+	baseCurrency = strings.ToUpper(baseCurrency)
+	quoteCurrency = strings.ToUpper(quoteCurrency)
+
+	if baseCurrency == quoteCurrency {
+		return money.RateScale, nil
+	}
+
+	exchangeRate, err := service.exchangeRateRepo.FindEffective(baseCurrency, quoteCurrency, moment)
+	if err == nil {
+		return exchangeRate.Rate, nil
+	}
+
+	inverseExchangeRate, err := service.exchangeRateRepo.FindEffective(quoteCurrency, baseCurrency, moment)
+	if err == nil {
+		return inverseExchangeRate.Rate.Invert(), nil
+	}
+
+	return 0, errors.New("no exchange rate found")
+}
+
+// FindMultipleExchangeRates is a method that returns the rate history of a currency pair, the latest rate comes first
+func (service *Service) FindMultipleExchangeRates(baseCurrency, quoteCurrency string) []*entity.ExchangeRate {
+	return service.exchangeRateRepo.FindMultiple(strings.ToUpper(baseCurrency), strings.ToUpper(quoteCurrency))
+}
+
+// DeleteExchangeRate is a method that deletes an exchange rate from the system using an id
+func (service *Service) DeleteExchangeRate(id int64) (*entity.ExchangeRate, error) {
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started exchange rate deleting process { Exchange Rate ID : %d }", id),
+		service.logger.Logs.TransactionLogFile)
+
+	exchangeRate, err := service.exchangeRateRepo.Delete(id)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For deleting exchange rate { Exchange Rate ID : %d }, %s",
+			id, err.Error()))
+
+		return nil, errors.New("unable to delete exchange rate")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished exchange rate deleting process, Deleted Exchange Rate => %s",
+		exchangeRate.ToString()), service.logger.Logs.TransactionLogFile)
+
+	return exchangeRate, nil
+}
+
+// Convert is a method that converts an amount to another currency using the rate in effect at the given moment,
+// the converted amount is rounded to the precision of the currency it has been converted to
+func (service *Service) Convert(amount money.Amount, fromCurrency, toCurrency string,
+	moment time.Time) (money.Amount, money.Rate, error) {
+
+	rate, err := service.FindExchangeRate(fromCurrency, toCurrency, moment)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if strings.EqualFold(fromCurrency, toCurrency) {
+		return amount, rate, nil
+	}
+
+	currency, err := service.currencyRepo.Find(strings.ToUpper(toCurrency))
+	if err != nil {
+		return 0, 0, errors.New("no currency found")
+	}
+
+	return amount.Convert(rate).Round(currency.Precision), rate, nil
+}
@@ -0,0 +1,8 @@
+-- Plan changes used to be credited with the remaining value of the changed subscription at activation,
+-- the plan changes awaiting payment are credited with the value the subscription had left at checkout instead
+ALTER TABLE subscription_transactions ADD COLUMN credited_amount DECIMAL(19, 2) NOT NULL DEFAULT 0;
+
+UPDATE subscription_transactions T INNER JOIN subscriptions S ON S.plan_change_transaction_id = T.id
+    SET T.credited_amount = ROUND(S.subscription_plan_price * TIMESTAMPDIFF(SECOND, T.created_at, S.expires_at) /
+        (S.subscription_plan_duration * 24 * 60 * 60), 2)
+    WHERE T.status = 'Pending' && S.is_trial = FALSE && S.subscription_plan_duration > 0 && S.expires_at > T.created_at;
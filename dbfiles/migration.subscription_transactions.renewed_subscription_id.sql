@@ -0,0 +1,7 @@
+-- Renewal transactions used to hold the renewed subscription only in their idempotency key,
+-- as in renewal:<subscription id>:<attempt>
+ALTER TABLE subscription_transactions ADD COLUMN renewed_subscription_id VARCHAR(255);
+
+UPDATE subscription_transactions
+    SET renewed_subscription_id = SUBSTRING_INDEX(SUBSTRING_INDEX(idempotency_key, ':', 2), ':', -1)
+    WHERE initiated_from = 'renewal_engine' && idempotency_key LIKE 'renewal:%';
@@ -0,0 +1,12 @@
+-- Subscriptions created before the status was introduced hold no status, the ones that haven't expired yet
+-- are the active subscriptions and the rest are kept as expired history
+UPDATE subscriptions SET status = 'Active' WHERE (status IS NULL || status = '') && expires_at > NOW();
+UPDATE subscriptions SET status = 'Expired' WHERE status IS NULL || status = '';
+
+ALTER TABLE subscriptions MODIFY status VARCHAR(255) NOT NULL DEFAULT 'Active';
+
+-- Only the latest subscription of a subscriber to a plan stays active, the earlier ones have been superseded by it
+UPDATE subscriptions S INNER JOIN (SELECT subscriber_id, subscription_plan_id, MAX(created_at) AS created_at
+    FROM subscriptions WHERE status = 'Active' GROUP BY subscriber_id, subscription_plan_id HAVING COUNT(*) > 1) L
+    ON L.subscriber_id = S.subscriber_id && L.subscription_plan_id = S.subscription_plan_id
+    SET S.status = 'Superseded' WHERE S.status = 'Active' && S.created_at < L.created_at;
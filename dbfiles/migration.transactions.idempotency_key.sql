@@ -0,0 +1,21 @@
+-- Transactions created without an idempotency key hold a NULL key, so the unique keys only apply to repeatable requests
+UPDATE subscription_transactions SET idempotency_key = NULL WHERE idempotency_key = '';
+UPDATE sp_subscription_transactions SET idempotency_key = NULL WHERE idempotency_key = '';
+
+-- Keeping the latest transaction of the keys that have been used more than once before the keys were unique
+UPDATE subscription_transactions T INNER JOIN (SELECT user_id, idempotency_key, MAX(created_at) AS created_at
+    FROM subscription_transactions WHERE idempotency_key IS NOT NULL GROUP BY user_id, idempotency_key
+    HAVING COUNT(*) > 1) D ON D.user_id = T.user_id && D.idempotency_key = T.idempotency_key
+    SET T.idempotency_key = NULL WHERE T.created_at < D.created_at;
+UPDATE sp_subscription_transactions T INNER JOIN (SELECT provider_id, idempotency_key, MAX(created_at) AS created_at
+    FROM sp_subscription_transactions WHERE idempotency_key IS NOT NULL GROUP BY provider_id, idempotency_key
+    HAVING COUNT(*) > 1) D ON D.provider_id = T.provider_id && D.idempotency_key = T.idempotency_key
+    SET T.idempotency_key = NULL WHERE T.created_at < D.created_at;
+
+DROP INDEX idx_subscription_transactions_idempotency_key ON subscription_transactions;
+DROP INDEX idx_sp_subscription_transactions_idempotency_key ON sp_subscription_transactions;
+
+CREATE UNIQUE INDEX unique_subscription_transaction_idempotency_key
+    ON subscription_transactions (user_id, idempotency_key);
+CREATE UNIQUE INDEX unique_sp_subscription_transaction_idempotency_key
+    ON sp_subscription_transactions (provider_id, idempotency_key);
@@ -0,0 +1,6 @@
+-- Transactions that haven't been settled hold a NULL trade number, so the unique index only applies to settled transactions
+UPDATE subscription_transactions SET trade_no = NULL WHERE trade_no = '';
+UPDATE sp_subscription_transactions SET trade_no = NULL WHERE trade_no = '';
+
+CREATE UNIQUE INDEX uix_subscription_transactions_trade_no ON subscription_transactions (trade_no);
+CREATE UNIQUE INDEX uix_sp_subscription_transactions_trade_no ON sp_subscription_transactions (trade_no);
@@ -1,11 +1,43 @@
 CREATE TABLE subscriptions (
     id VARCHAR(255) PRIMARY KEY UNIQUE NOT NULL,
-    user_id VARCHAR(255) NOT NULL,
+    subscriber_id VARCHAR(255) NOT NULL,
+    subscriber_first_name VARCHAR(255),
+    subscriber_last_name VARCHAR(255),
+    subscriber_user_name VARCHAR(255),
+    subscriber_phone_number VARCHAR(255),
+    subscriber_email VARCHAR(255),
     provider_id VARCHAR(255) NOT NULL,
-    subscription_plan VARCHAR(255),
-    status VARCHAR(255),
+    provider_first_name VARCHAR(255),
+    provider_last_name VARCHAR(255),
+    provider_user_name VARCHAR(255),
+    provider_phone_number VARCHAR(255),
+    provider_email VARCHAR(255),
+    project_id VARCHAR(255) NOT NULL,
+    project_name BLOB,
+    project_description BLOB,
+    project_link VARCHAR(255),
+    subscription_plan_id VARCHAR(255) NOT NULL,
+    subscription_plan_name VARCHAR(255),
+    subscription_plan_benfits BLOB,
+    subscription_plan_duration BIGINT,
+    subscription_plan_price DECIMAL(19, 2),
+    subscription_plan_is_recurring BOOLEAN,
+    subscription_plan_currency VARCHAR(255),
+    transaction_id VARCHAR(255),
+    is_trial BOOLEAN,
+    coupon_id VARCHAR(255),
+    status VARCHAR(255) NOT NULL DEFAULT 'Active',
+    expiry_stage VARCHAR(255),
+    renewal_status VARCHAR(255),
+    renewal_transaction_id VARCHAR(255),
+    renewal_attempts BIGINT,
+    renewal_attempted_at DATETIME,
+    plan_change_transaction_id VARCHAR(255),
+    cancellation_mode VARCHAR(255),
+    cancelled_at DATETIME,
     created_at DATETIME,
     updated_at DATETIME,
-    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    expires_at DATETIME,
+    FOREIGN KEY (subscriber_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
     FOREIGN KEY (provider_id) REFERENCES service_providers(id) ON DELETE CASCADE ON UPDATE CASCADE
-);
\ No newline at end of file
+);
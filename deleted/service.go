@@ -4,6 +4,8 @@ import "github.com/Benyam-S/onemembership/entity"
 
 // IService is a method that defines all the service methods for managing deleted struct
 type IService interface {
+	WithActor(actor string) IService
+
 	AddUserToTrash(user *entity.User) (*entity.DeletedUser, error)
 	AddServiceProviderToTrash(serviceProvider *entity.ServiceProvider) (*entity.DeletedServiceProvider, error)
 	AddSubscriptionTranstactionsToTrash(userID, prefixedUserID string)
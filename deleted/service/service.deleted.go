@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"strconv"
 
+	"github.com/Benyam-S/onemembership/audit"
 	"github.com/Benyam-S/onemembership/deleted"
 	"github.com/Benyam-S/onemembership/entity"
 	"github.com/Benyam-S/onemembership/log"
@@ -18,6 +19,8 @@ type Service struct {
 	deletedSubscriptionTransaction   deleted.IDeletedSubscriptionTransactionRepository
 	deletedSPSubscriptionTransaction deleted.IDeletedSPSubscriptionTransactionRepository
 	deletedSPPayrollTransaction      deleted.IDeletedSPPayrollTransactionRepository
+	auditService                     audit.IService
+	actor                            string // The actor the changes are recorded on behalf of in the audit trail
 	logger                           *log.Logger
 }
 
@@ -27,19 +30,39 @@ func NewDeletedService(deletedUserRepository deleted.IDeletedUserRepository,
 	deletedSubscriptionTransactionRepository deleted.IDeletedSubscriptionTransactionRepository,
 	deletedSPSubscriptionTransactionRepository deleted.IDeletedSPSubscriptionTransactionRepository,
 	deletedSPPayrollTransactionRepository deleted.IDeletedSPPayrollTransactionRepository,
-	deletedLogger *log.Logger) deleted.IService {
+	auditService audit.IService, deletedLogger *log.Logger) deleted.IService {
 
 	return &Service{deletedUserRepo: deletedUserRepository, deletedServiceProvider: deletedServiceProviderRepository,
 		deletedSubscriptionTransaction:   deletedSubscriptionTransactionRepository,
 		deletedSPSubscriptionTransaction: deletedSPSubscriptionTransactionRepository,
-		deletedSPPayrollTransaction:      deletedSPPayrollTransactionRepository, logger: deletedLogger}
+		deletedSPPayrollTransaction:      deletedSPPayrollTransactionRepository, auditService: auditService,
+		actor: audit.SystemActor, logger: deletedLogger}
+}
+
+// WithActor is a method that returns a copy of the deleted service that records its changes in the audit trail
+// on behalf of the given actor
+func (service *Service) WithActor(actor string) deleted.IService {
+	serviceCopy := *service
+	serviceCopy.actor = actor
+	return &serviceCopy
+}
+
+// recordAudit is a method that appends an action performed on an entity to the audit trail on behalf of the actor,
+// the action has already taken place so a failure is only logged
+func (service *Service) recordAudit(action, entityID string, before, after interface{}) {
+	err := service.auditService.Record(service.actor, action, entityID, before, after)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For recording audit { Actor : %s, Action : %s, "+
+			"Entity ID : %s }, %s", service.actor, action, entityID, err.Error()))
+	}
 }
 
 // AddUserToTrash is a method that adds a user to deleted table
 func (service *Service) AddUserToTrash(user *entity.User) (*entity.DeletedUser, error) {
 
 	deletedUser := new(entity.DeletedUser)
-	deletedUser.ID = user.ID
+	deletedUser.UserID = user.ID
 	deletedUser.FirstName = user.FirstName
 	deletedUser.LastName = user.LastName
 	deletedUser.Username = user.UserName
@@ -59,6 +82,9 @@ func (service *Service) AddUserToTrash(user *entity.User) (*entity.DeletedUser,
 		return nil, errors.New("unable to add user to trash")
 	}
 
+	// Recording the trashing in the audit trail
+	service.recordAudit(entity.AuditActionTrash, user.ID, nil, deletedUser)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished trashed user adding process, Deleted User => %s",
 		deletedUser.ToString()), service.logger.Logs.DeletedLogFile)
@@ -69,6 +95,7 @@ func (service *Service) AddUserToTrash(user *entity.User) (*entity.DeletedUser,
 func (service *Service) AddServiceProviderToTrash(serviceProvider *entity.ServiceProvider) (*entity.DeletedServiceProvider, error) {
 
 	deletedServiceProvider := new(entity.DeletedServiceProvider)
+	deletedServiceProvider.ProviderID = serviceProvider.ID
 	deletedServiceProvider.FirstName = serviceProvider.FirstName
 	deletedServiceProvider.LastName = serviceProvider.LastName
 	deletedServiceProvider.UserName = serviceProvider.UserName
@@ -88,6 +115,10 @@ func (service *Service) AddServiceProviderToTrash(serviceProvider *entity.Servic
 		return nil, errors.New("unable to add service provider to trash")
 	}
 
+	// Recording the trashing in the audit trail
+	service.recordAudit(entity.AuditActionTrash, serviceProvider.ID, nil,
+		deletedServiceProvider)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished trashed service provider adding process, Deleted Service Provider => %s",
 		deletedServiceProvider.ToString()), service.logger.Logs.DeletedLogFile)
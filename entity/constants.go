@@ -36,5 +36,167 @@ const TransactionStatusPending = "Pending"
 // TransactionStatusComplete is a constant that states subscription transaction has been completed
 const TransactionStatusComplete = "Complete"
 
+// TransactionStatusFailed is a constant that states subscription transaction has failed at the payment gateway
+const TransactionStatusFailed = "Failed"
+
+// TransactionStatusExpired is a constant that states subscription transaction hasn't been paid before its timeout
+const TransactionStatusExpired = "Expired"
+
+// TransactionStatusRefundPending is a constant that states a refund transaction is waiting to be paid back
+const TransactionStatusRefundPending = "Refund_Pending"
+
+// TransactionStatusRefunded is a constant that states a refund transaction has been paid back
+const TransactionStatusRefunded = "Refunded"
+
+// TransactionStatusRefundFailed is a constant that states a refund transaction couldn't be paid back
+const TransactionStatusRefundFailed = "Refund_Failed"
+
+// PayrollStatusPending is a constant that states a payroll transaction has been requested and is waiting for approval
+const PayrollStatusPending = "Pending"
+
+// PayrollStatusApproved is a constant that states a payroll transaction has been approved and is waiting to be paid
+const PayrollStatusApproved = "Approved"
+
+// PayrollStatusRejected is a constant that states a payroll transaction has been rejected
+const PayrollStatusRejected = "Rejected"
+
+// PayrollStatusComplete is a constant that states a payroll transaction has been paid to the provider
+const PayrollStatusComplete = "Complete"
+
+// AccountGateway is a constant that identifies the ledger account holding the money kept at the payment gateways
+const AccountGateway = "Gateway"
+
+// AccountPlatformFee is a constant that identifies the ledger account holding the collected transaction fees
+const AccountPlatformFee = "Platform_Fee"
+
+// AccountPlatformRevenue is a constant that identifies the ledger account holding the service provider subscription payments
+const AccountPlatformRevenue = "Platform_Revenue"
+
+// AccountProviderPayable is a constant that identifies the ledger account holding the amount owed to a service provider
+const AccountProviderPayable = "Provider_Payable"
+
+// AccountProviderPendingPayout is a constant that identifies the ledger account holding a service provider's requested withdrawals
+const AccountProviderPendingPayout = "Provider_Pending_Payout"
+
+// AccountOpeningBalance is a constant that identifies the ledger account holding the wallet balances
+// that have been accumulated before the ledger was introduced
+const AccountOpeningBalance = "Opening_Balance"
+
+// JournalSubscriptionPayment is a constant that identifies a journal entry recording a completed subscription payment
+const JournalSubscriptionPayment = "Subscription_Payment"
+
+// JournalSPSubscriptionPayment is a constant that identifies a journal entry recording a completed service provider subscription payment
+const JournalSPSubscriptionPayment = "SP_Subscription_Payment"
+
+// JournalRefund is a constant that identifies a journal entry recording a requested refund
+const JournalRefund = "Refund"
+
+// JournalRefundFailure is a constant that identifies a journal entry reversing a failed refund
+const JournalRefundFailure = "Refund_Failure"
+
+// JournalWithdrawal is a constant that identifies a journal entry recording a requested withdrawal
+const JournalWithdrawal = "Withdrawal"
+
+// JournalWithdrawalRejection is a constant that identifies a journal entry reversing a rejected withdrawal
+const JournalWithdrawalRejection = "Withdrawal_Rejection"
+
+// JournalPayout is a constant that identifies a journal entry recording a completed withdrawal
+const JournalPayout = "Payout"
+
+// JournalOpeningBalance is a constant that identifies a journal entry recording the balance a wallet already held
+// when the ledger was introduced
+const JournalOpeningBalance = "Opening_Balance"
+
+// TransactionTypeSubscription is a constant that identifies a subscription transaction
+const TransactionTypeSubscription = "Subscription_Transaction"
+
+// TransactionTypeSPSubscription is a constant that identifies a service provider subscription transaction
+const TransactionTypeSPSubscription = "SP_Subscription_Transaction"
+
+// TransactionTypeSPPayroll is a constant that identifies a service provider payroll transaction
+const TransactionTypeSPPayroll = "SP_Payroll_Transaction"
+
+// TransactionTypeSPWallet is a constant that identifies a service provider wallet balance in a single currency
+const TransactionTypeSPWallet = "SP_Wallet"
+
+// FeeTypeFixed is a constant that states a fee rule charges a fixed amount
+const FeeTypeFixed = "Fixed"
+
+// FeeTypePercentage is a constant that states a fee rule charges a percentage of the amount
+const FeeTypePercentage = "Percentage"
+
+// FeeTypeFixedPlusPercentage is a constant that states a fee rule charges a fixed amount plus a percentage of the amount
+const FeeTypeFixedPlusPercentage = "Fixed_Plus_Percentage"
+
+// FeeTypeDiscount is a constant that states a fee rule reduces the calculated fee by a percentage
+const FeeTypeDiscount = "Discount"
+
+// FeeAbsorbedByPayer is a constant that states the transaction fee is added on top of the amount the payer pays
+const FeeAbsorbedByPayer = "Payer"
+
+// FeeAbsorbedByProvider is a constant that states the transaction fee is deducted from the amount the receiver gets
+const FeeAbsorbedByProvider = "Provider"
+
+// CouponTypePercentage is a constant that states a coupon discounts a percentage of the price
+const CouponTypePercentage = "Percentage"
+
+// CouponTypeFixed is a constant that states a coupon discounts a fixed amount from the price
+const CouponTypeFixed = "Fixed"
+
+// ExpiryStageExpiringSoon is a constant that states a subscription is about to expire
+const ExpiryStageExpiringSoon = "Expiring_Soon"
+
+// ExpiryStageExpired is a constant that states a subscription has expired and is in its grace period
+const ExpiryStageExpired = "Expired"
+
+// ExpiryStageRemoved is a constant that states an expired subscription's subscriber has been removed from the chats
+const ExpiryStageRemoved = "Removed"
+
+// SubscriptionStatusActive is a constant that states a subscription is the one currently granting access
+const SubscriptionStatusActive = "Active"
+
+// SubscriptionStatusExpired is a constant that states a subscription has ended and its access has been revoked
+const SubscriptionStatusExpired = "Expired"
+
+// SubscriptionStatusCancelled is a constant that states a subscription has been cancelled before its term ended
+const SubscriptionStatusCancelled = "Cancelled"
+
+// SubscriptionStatusSuperseded is a constant that states a subscription has been replaced by a renewal or a plan change
+const SubscriptionStatusSuperseded = "Superseded"
+
+// CancellationImmediate is a constant that states a subscription is cancelled and its access is revoked right away
+const CancellationImmediate = "Immediate"
+
+// CancellationEndOfTerm is a constant that states a subscription is cancelled once its current term ends
+const CancellationEndOfTerm = "End_Of_Term"
+
+// RenewalStatusPending is a constant that states a renewal transaction has been requested for a subscription
+const RenewalStatusPending = "Pending"
+
+// RenewalStatusLapsed is a constant that states a subscription has run out of renewal attempts
+const RenewalStatusLapsed = "Lapsed"
+
 // InitiatedFromBot is a constant that indicate the location where the request was initiated
 const InitiatedFromBot = "telegram_bot"
+
+// InitiatedFromRenewal is a constant that indicate the request was initiated by the renewal engine
+const InitiatedFromRenewal = "renewal_engine"
+
+// InitiatedFromPlanChange is a constant that indicate the request was initiated by a subscription plan change
+const InitiatedFromPlanChange = "plan_change"
+
+// InitiatedFromPlanChangeCredit is a constant that indicate the transaction records the credit of a subscription plan change
+// that didn't require a payment
+const InitiatedFromPlanChangeCredit = "plan_change_credit"
+
+// CreditAppID is a constant that holds the app id of transactions that have been recorded without a payment gateway
+const CreditAppID = "internal_credit"
+
+// AuditActionUpdate is a constant that states an entity has been updated
+const AuditActionUpdate = "Update"
+
+// AuditActionDelete is a constant that states an entity has been deleted
+const AuditActionDelete = "Delete"
+
+// AuditActionTrash is a constant that states a deleted entity has been moved to the trash
+const AuditActionTrash = "Trash"
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// AuditRecord is a type that defines an append only record of an administrative or financial action,
+// records are never updated or deleted once they have been written
+type AuditRecord struct {
+	ID         int64  `gorm:"primary_key; unique; auto_increment;"`
+	Actor      string `gorm:"index;"` // The id of the admin, user or service provider that performed the action
+	Action     string // Holds one of the audit action constants
+	EntityType string `gorm:"index:idx_audit_entity;"` // Defining composite index
+	EntityID   string `gorm:"index:idx_audit_entity;"` // Defining composite index
+	Changes    string `gorm:"type:text;"`              // JSON object of the changed fields with their before and after values
+	CreatedAt  time.Time
+}
@@ -3,6 +3,8 @@ package entity
 import (
 	"net/http"
 	"time"
+
+	"github.com/Benyam-S/onemembership/money"
 )
 
 // User is a type that defines a user group the will use or subscribe to subscription plans
@@ -11,8 +13,8 @@ type User struct {
 	FirstName   string
 	LastName    string
 	UserName    string
-	PhoneNumber string
-	Email       string
+	PhoneNumber string `redact:"mask"`
+	Email       string `redact:"mask"`
 	Preference  *ClientPreference
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
@@ -21,8 +23,8 @@ type User struct {
 // UserPassword is a type that defines a user password
 type UserPassword struct {
 	UserID    string `gorm:"primary_key; unique;"`
-	Password  string
-	Salt      string
+	Password  string `redact:"secret"`
+	Salt      string `redact:"secret"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
@@ -33,8 +35,8 @@ type ServiceProvider struct {
 	FirstName   string
 	LastName    string
 	UserName    string
-	PhoneNumber string
-	Email       string
+	PhoneNumber string `redact:"mask"`
+	Email       string `redact:"mask"`
 	Preference  *ClientPreference
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
@@ -43,25 +45,35 @@ type ServiceProvider struct {
 // SPPassword (ServiceProviderPassword) is a type that defines a user password
 type SPPassword struct {
 	ProviderID string `gorm:"primary_key; unique;"`
-	Password   string
-	Salt       string
+	Password   string `redact:"secret"`
+	Salt       string `redact:"secret"`
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 }
 
-// SPWallet (ServiceProviderWallet) is a type that defines the service provider wallet account
+// SPWallet (ServiceProviderWallet) is a type that defines the service provider wallet account,
+// the amounts the wallet holds are kept per currency as wallet balances
 type SPWallet struct {
-	ProviderID    string  `gorm:"primary_key; unique;"`
-	RunningAmount float64 // Amount that is actively counting the received value,
-	//it is estimated value it mightn't be incorrect so we should recalculate it from the transactions on withdraw
-
-	PendingAmount         float64 // Amount requested to be withdrawn
-	LinkedAccount         string  // Third party banking account saved by service provider
-	LinkedAccountProvider string  // Third party banking account provider
+	ProviderID            string `gorm:"primary_key; unique;"`
+	LinkedAccount         string `redact:"mask"` // Third party banking account saved by service provider
+	LinkedAccountProvider string // Third party banking account provider
 	CreatedAt             time.Time
 	UpdatedAt             time.Time
 }
 
+// SPWalletBalance (ServiceProviderWalletBalance) is a type that defines the amounts a service provider wallet holds
+// in a single currency
+type SPWalletBalance struct {
+	ProviderID    string       `gorm:"primary_key;"`
+	CurrencyType  string       `gorm:"primary_key;"`
+	RunningAmount money.Amount `gorm:"type:decimal(19,2);"` // Amount that is actively counting the received value,
+	//it is estimated value it mightn't be incorrect so we should recalculate it from the transactions on withdraw
+
+	PendingAmount money.Amount `gorm:"type:decimal(19,2);"` // Amount requested to be withdrawn
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
 // SPSubscription (ServiceProviderSubscription) is a type that defines service provider subscription
 type SPSubscription struct {
 	ProviderID string `gorm:"primary_key; unique;"`
@@ -70,9 +82,11 @@ type SPSubscription struct {
 	SubscriptionPlanID       string
 	SubscriptionPlanName     string
 	SubscriptionPlanDuration int64
-	SubscriptionPlanPrice    float64
+	SubscriptionPlanPrice    money.Amount `gorm:"type:decimal(19,2);"`
 	SubscriptionPlanCurrency string
 
+	TransactionID string // The last transaction the subscription has been paid with
+
 	// TimeStamp for the created subscription
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -84,8 +98,8 @@ type SPSubscription struct {
 type SPSubscriptionPlan struct {
 	ID        string `gorm:"primary_key; unique;"`
 	Name      string
-	Duration  int64 // Represents the number of days the subscription plan lengthen
-	Price     float64
+	Duration  int64        // Represents the number of days the subscription plan lengthen
+	Price     money.Amount `gorm:"type:decimal(19,2);"`
 	Currency  string
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -104,6 +118,8 @@ type Feedback struct {
 type ClientPreference struct {
 	ClientID string `gorm:"primary_key; unique;"`
 	Language string `gorm:"default: 'en'"` // The same as the DefaultLanguage constant
+
+	RenewalOptOut bool // Stops recurring subscriptions of the client from being renewed automatically
 }
 
 // Language is a type that defines the langauges available in the system
@@ -128,7 +144,7 @@ type SystemConfig struct {
 	MysqlClient          map[string]string `json:"mysql_client"`
 	CookieName           string            `json:"cookie_name"`
 	SecretKey            string            `json:"secret_key"`
-	SuperAdminEmail      string            `json:"super_admin_email"`
+	SuperAdminEmail      string            `json:"super_admin_email" redact:"mask"`
 	HTTPDomainAddress    string            `json:"http_domain_address"`
 	BotDomainAddress     string            `json:"bot_domain_address"`
 	BotClientServerPort  string            `json:"bot_client_server_port"`
@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/Benyam-S/onemembership/money"
+)
+
+// Currency is a type that defines a currency supported by the system
+type Currency struct {
+	Code      string `gorm:"primary_key; unique;"` // The ISO 4217 code of the currency such as ETB
+	Name      string
+	Symbol    string
+	Precision int64 // The number of fraction digits amounts held in the currency are rounded to
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ExchangeRate is a type that defines the rate of a currency pair starting from the moment it becomes effective,
+// the rate holds how much of the quote currency a single unit of the base currency is worth
+type ExchangeRate struct {
+	ID            int64 `gorm:"primary_key; unique; auto_increment;"`
+	BaseCurrency  string
+	QuoteCurrency string
+	Rate          money.Rate `gorm:"type:decimal(19,8);"`
+	EffectiveAt   time.Time
+	CreatedAt     time.Time
+}
@@ -2,6 +2,8 @@ package entity
 
 import (
 	"time"
+
+	"github.com/Benyam-S/onemembership/money"
 )
 
 // DeletedUser is a type that defines a user that has been deleted
@@ -12,8 +14,8 @@ type DeletedUser struct {
 	FirstName   string
 	LastName    string
 	Username    string
-	PhoneNumber string
-	Email       string
+	PhoneNumber string `redact:"mask"`
+	Email       string `redact:"mask"`
 	CreatedAt   time.Time
 }
 
@@ -25,8 +27,8 @@ type DeletedServiceProvider struct {
 	FirstName   string
 	LastName    string
 	UserName    string
-	PhoneNumber string
-	Email       string
+	PhoneNumber string `redact:"mask"`
+	Email       string `redact:"mask"`
 	CreatedAt   time.Time
 }
 
@@ -39,8 +41,8 @@ type DeletedSPSubscriptionTransaction struct {
 	AppID          string
 	ReceiverName   string
 	Subject        string
-	ReceivedAmount float64
-	TransactionFee float64
+	ReceivedAmount money.Amount `gorm:"type:decimal(19,2);"`
+	TransactionFee money.Amount `gorm:"type:decimal(19,2);"`
 	CurrencyType   string
 	TimeoutExpress int64
 	Nonce          string
@@ -55,8 +57,8 @@ type DeletedSPSubscriptionTransaction struct {
 type DeletedSPPayrollTransaction struct {
 	ID                    string
 	ProviderID            string
-	PayedAmount           float64
-	LinkedAccount         string
+	PayedAmount           money.Amount `gorm:"type:decimal(19,2);"`
+	LinkedAccount         string       `redact:"mask"`
 	LinkedAccountProvider string
 	Status                string
 	CreatedAt             time.Time
@@ -70,8 +72,8 @@ type DeletedSubscriptionTransaction struct {
 	AppID          string
 	ReceiverName   string
 	Subject        string
-	ReceivedAmount float64
-	TransactionFee float64
+	ReceivedAmount money.Amount `gorm:"type:decimal(19,2);"`
+	TransactionFee money.Amount `gorm:"type:decimal(19,2);"`
 	CurrencyType   string
 	TimeoutExpress int64
 	Nonce          string
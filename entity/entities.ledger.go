@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/Benyam-S/onemembership/money"
+)
+
+// JournalEntry is a type that defines an immutable double-entry record of a money movement,
+// the debits and credits of its lines always balance
+type JournalEntry struct {
+	ID              string `gorm:"primary_key; unique;"`
+	TransactionID   string `gorm:"unique_index:unique_journal_entry_source;"` // The transaction that caused the entry
+	TransactionType string // Identifies whether the transaction is a subscription, sp subscription or payroll transaction
+	Kind            string `gorm:"unique_index:unique_journal_entry_source;"` // Defining composite unique key
+	Currency        string
+	Lines           []*JournalLine `gorm:"foreignkey:EntryID;"`
+	CreatedAt       time.Time
+}
+
+// JournalLine is a type that defines a single debit or credit of a journal entry against an account
+type JournalLine struct {
+	ID        int64 `gorm:"primary_key; unique; auto_increment;"`
+	EntryID   string
+	Account   string
+	OwnerID   string       // Identifies the service provider for service provider accounts
+	Debit     money.Amount `gorm:"type:decimal(19,2);"`
+	Credit    money.Amount `gorm:"type:decimal(19,2);"`
+	CreatedAt time.Time
+}
@@ -0,0 +1,19 @@
+package entity
+
+import (
+	"github.com/Benyam-S/onemembership/money"
+)
+
+// Total is a method that returns the amount the payer has been charged for a subscription transaction,
+// the received amount and the transaction fee are both held in the transaction's currency
+func (subscriptionTransaction *SubscriptionTransaction) Total() money.Money {
+	return money.New(subscriptionTransaction.ReceivedAmount.Add(subscriptionTransaction.TransactionFee),
+		subscriptionTransaction.CurrencyType)
+}
+
+// Total is a method that returns the amount the service provider has been charged for a service provider
+// subscription transaction, the received amount and the transaction fee are both held in the transaction's currency
+func (spSubscriptionTransaction *SPSubscriptionTransaction) Total() money.Money {
+	return money.New(spSubscriptionTransaction.ReceivedAmount.Add(spSubscriptionTransaction.TransactionFee),
+		spSubscriptionTransaction.CurrencyType)
+}
@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// NullableString is a type that defines a string stored as NULL when it is empty,
+// so a unique index on its column only applies to the rows that hold a value
+type NullableString string
+
+// Value is a method that converts the string to a database value, an empty string is stored as NULL
+func (value NullableString) Value() (driver.Value, error) {
+	if value == "" {
+		return nil, nil
+	}
+	return string(value), nil
+}
+
+// Scan is a method that reads the string from a database value, NULL values are read as an empty string
+func (value *NullableString) Scan(src interface{}) error {
+
+	switch srcValue := src.(type) {
+	case nil:
+		*value = ""
+	case []byte:
+		*value = NullableString(srcValue)
+	case string:
+		*value = NullableString(srcValue)
+	default:
+		return fmt.Errorf("unable to scan %T into a nullable string", src)
+	}
+
+	return nil
+}
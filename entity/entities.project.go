@@ -2,6 +2,8 @@ package entity
 
 import (
 	"time"
+
+	"github.com/Benyam-S/onemembership/money"
 )
 
 // Project is a type that defines a project that a service provider can own
@@ -28,16 +30,55 @@ type SubscriptionPlan struct {
 	ID          string `gorm:"primary_key; unique;"`
 	ProjectID   string
 	Name        string
-	Benfits     string `gorm:"type:blob;"` // The benfits may contain special characters or emojis
-	Duration    int64  // Represents the number of days the subscription plan lengthen
-	Price       float64
+	Benfits     string       `gorm:"type:blob;"` // The benfits may contain special characters or emojis
+	Duration    int64        // Represents the number of days the subscription plan lengthen
+	Price       money.Amount `gorm:"type:decimal(19,2);"`
 	Currency    string
 	IsRecurring bool
+	TrialDays   int64  // Represents the number of days of the free trial, zero means the plan doesn't offer a trial
 	Status      string // Can be used to identify the status of the plan in order to take action
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 }
 
+// Coupon is a type that defines a discount code a service provider offers for its subscription plans
+type Coupon struct {
+	ID             string `gorm:"primary_key; unique;"`
+	ProviderID     string
+	Code           string       `gorm:"unique;"`
+	Type           string       // Can be used to identify whether the coupon discounts a percentage or a fixed amount
+	Rate           int64        // The percentage discount in basis points, where 10000 is 100%
+	FixedAmount    money.Amount `gorm:"type:decimal(19,2);"`
+	Currency       string       // The currency of the fixed amount
+	MaxRedemptions int64        // Zero means the coupon can be redeemed any number of times
+	PerUserLimit   int64        // Zero means a user can redeem the coupon any number of times
+
+	// For restricting the coupon to certain subscription plans or projects, the ids are separated by commas
+	// and the coupon applies to all the service provider's plans when both are empty
+	PlanIDs    string `gorm:"type:text;"`
+	ProjectIDs string `gorm:"type:text;"`
+
+	// For only discounting the first period of recurring plans, otherwise the discount carries over to the renewals
+	FirstPeriodOnly bool
+
+	ValidFrom  time.Time
+	ValidUntil time.Time // Zero means the coupon doesn't expire
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// CouponRedemption is a type that defines a coupon that has been redeemed for a subscription transaction
+type CouponRedemption struct {
+	ID                        int64  `gorm:"primary_key; unique; auto_increment;"`
+	CouponID                  string `gorm:"index;"`
+	UserID                    string
+	SubscriptionTransactionID string       `gorm:"index;"` // Empty while the redemption is reserved for a checkout
+	DiscountAmount            money.Amount `gorm:"type:decimal(19,2);"`
+	CurrencyType              string
+	IsRenewal                 bool // Renewals carry over the discount without counting towards the redemption limits
+	CreatedAt                 time.Time
+}
+
 // PlanChatLink is a type that defines a link between subscription plan and telegram chat
 type PlanChatLink struct {
 	PlanID string `gorm:"unique_index:unique_plan_to_chat_link_relation;"` // Defining composite unique key
@@ -63,16 +104,16 @@ type Subscription struct {
 	SubscriberFirstName   string
 	SubscriberLastName    string
 	SubscriberUserName    string
-	SubscriberPhoneNumber string
-	SubscriberEmail       string
+	SubscriberPhoneNumber string `redact:"mask"`
+	SubscriberEmail       string `redact:"mask"`
 
 	// For storing the current transaction subscription provider detail
 	ProviderID          string
 	ProviderFirstName   string
 	ProviderLastName    string
 	ProviderUserName    string
-	ProviderPhoneNumber string
-	ProviderEmail       string
+	ProviderPhoneNumber string `redact:"mask"`
+	ProviderEmail       string `redact:"mask"`
 
 	// For storing the current project detail
 	ProjectID          string
@@ -85,10 +126,36 @@ type Subscription struct {
 	SubscriptionPlanName        string
 	SubscriptionPlanBenfits     string `gorm:"type:blob;"`
 	SubscriptionPlanDuration    int64
-	SubscriptionPlanPrice       float64
+	SubscriptionPlanPrice       money.Amount `gorm:"type:decimal(19,2);"`
 	SubscriptionPlanIsRecurring bool
 	SubscriptionPlanCurrency    string
 
+	// For storing the subscription transaction that has activated the subscription, free trials don't have one
+	TransactionID string
+	IsTrial       bool
+
+	// For storing the coupon that has been redeemed for the subscription, so renewals can carry over its discount
+	CouponID string
+
+	// For separating the active subscription from the subscription history
+	Status string `gorm:"default: 'Active'"`
+
+	// For storing the last expiry stage reached, so expiry events aren't repeated
+	ExpiryStage string
+
+	// For tracking the automatic renewal of recurring subscriptions
+	RenewalStatus        string
+	RenewalTransactionID string // The subscription transaction created for the latest renewal attempt
+	RenewalAttempts      int64
+	RenewalAttemptedAt   time.Time
+
+	// For storing the subscription transaction created for a requested plan change
+	PlanChangeTransactionID string
+
+	// For storing how and when the subscription has been cancelled
+	CancellationMode string
+	CancelledAt      time.Time
+
 	// TimeStamp for the created history or subscription
 	CreatedAt time.Time
 	UpdatedAt time.Time
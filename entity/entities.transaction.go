@@ -2,6 +2,8 @@ package entity
 
 import (
 	"time"
+
+	"github.com/Benyam-S/onemembership/money"
 )
 
 // PaymentGateway is a type that defines all the available payment gateways or gateways
@@ -11,46 +13,106 @@ type PaymentGateway struct {
 	CreatedAt time.Time
 }
 
+// FeeRule is a type that defines a rule the fee engine uses for calculating the transaction fee of a payment.
+// Empty gateway, service provider subscription plan and currency fields make the rule apply to any value.
+type FeeRule struct {
+	ID                   string `gorm:"primary_key; unique;"`
+	Name                 string
+	Type                 string       // Holds one of the fee type constants
+	GatewayID            int64        // The payment gateway the rule is limited to
+	SPSubscriptionPlanID string       // Limits the rule to service providers subscribed to the platform subscription plan
+	CurrencyType         string       // Should be set for rules with a fixed amount
+	FixedAmount          money.Amount `gorm:"type:decimal(19,2);"`
+	Rate                 int64        // In basis points of the amount, or of the fee for discounts, where 150 is 1.5%
+	AbsorbedBy           string       // Holds one of the fee absorbed by constants, not used by discounts
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+}
+
 // SubscriptionTransaction is a type that defines a transaction performed during subscription
 type SubscriptionTransaction struct {
 	ID             string `gorm:"primary_key; unique;"`
-	UserID         string
+	UserID         string `gorm:"unique_index:unique_subscription_transaction_idempotency_key;"` // Defining composite unique key
 	PlanID         string
 	AppID          string // Used to identify which gateway was used
 	ReceiverName   string
 	Subject        string
-	ReceivedAmount float64 // Indicates the amount that will be provided to service provider
-	TransactionFee float64 // The amount that will be deducted from the total amount where TotalAmount = ReceivedAmount + Fee
+	ReceivedAmount money.Amount `gorm:"type:decimal(19,2);"` // Indicates the amount that will be provided to service provider
+	TransactionFee money.Amount `gorm:"type:decimal(19,2);"` // The amount that will be deducted from the total amount where TotalAmount = ReceivedAmount + Fee
 	CurrencyType   string
 	TimeoutExpress int64
 	Nonce          string
 	OutTradeNo     string
-	TradeNo        string
-	Status         string // Can be used to identify the status of the transaction
-	InitiatedFrom  string // Indicates from which interface the request was initiated such as from bot or web
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
+	TradeNo        NullableString `gorm:"unique_index;"` // A trade number can only settle a single transaction
+	Status         string         // Can be used to identify the status of the transaction
+	InitiatedFrom  string         // Indicates from which interface the request was initiated such as from bot or web
+
+	// For returning the same order when the caller repeats a request, such as the bot update id or web request id,
+	// a user can only create a single transaction with the same key
+	IdempotencyKey NullableString `gorm:"unique_index:unique_subscription_transaction_idempotency_key;"`
+	PayURL         string         `gorm:"type:text;"`
+
+	// For auditing how the transaction fee has been calculated, the ids are separated by commas
+	FeeRuleIDs    string
+	FeeAbsorbedBy string
+
+	// For storing the amount that has been requested before the fee and the conversion to the paid currency
+	OriginalAmount   money.Amount `gorm:"type:decimal(19,2);"`
+	OriginalCurrency string
+	ExchangeRate     money.Rate `gorm:"type:decimal(19,8);"` // The rate the original amount has been converted with
+
+	// For storing the coupon that has been redeemed for the transaction and the discount it has given on the original amount
+	CouponID       string
+	DiscountAmount money.Amount `gorm:"type:decimal(19,2);"`
+
+	// For storing the transaction a refund reverses, refunds hold a negative received amount
+	ReversedTransactionID string
+
+	// For storing the subscription a renewal transaction has been created for, so any of its attempts renews it
+	RenewedSubscriptionID string
+
+	// For storing the remaining value of the changed subscription a plan change has been credited with at checkout,
+	// held in the currency of the new plan
+	CreditedAmount money.Amount `gorm:"type:decimal(19,2);"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 // SPSubscriptionTransaction (ServiceProviderSubscriptionTransaction) is a type that defines
 // a transaction performed during subscription by service provider
 type SPSubscriptionTransaction struct {
 	ID             string `gorm:"primary_key; unique;"`
-	ProviderID     string
+	ProviderID     string `gorm:"unique_index:unique_sp_subscription_transaction_idempotency_key;"` // Defining composite unique key
 	PlanID         string
 	AppID          string // Used to identify which gateway was used
 	ReceiverName   string
 	Subject        string
-	ReceivedAmount float64 // Indicates the amount that will be provided to service provider
-	TransactionFee float64 // The amount that will be deducted from the total amount where TotalAmount = ReceivedAmount + Fee
+	ReceivedAmount money.Amount `gorm:"type:decimal(19,2);"` // Indicates the amount that will be provided to service provider
+	TransactionFee money.Amount `gorm:"type:decimal(19,2);"` // The amount that will be deducted from the total amount where TotalAmount = ReceivedAmount + Fee
 	CurrencyType   string
 	TimeoutExpress int64
 	Nonce          string
 	OutTradeNo     string
-	TradeNo        string
-	Status         string // Can be used to identify the status of the transaction
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
+	TradeNo        NullableString `gorm:"unique_index;"` // A trade number can only settle a single transaction
+	Status         string         // Can be used to identify the status of the transaction
+
+	// For returning the same order when the caller repeats a request, such as the bot update id or web request id,
+	// a service provider can only create a single transaction with the same key
+	IdempotencyKey NullableString `gorm:"unique_index:unique_sp_subscription_transaction_idempotency_key;"`
+	PayURL         string         `gorm:"type:text;"`
+
+	// For auditing how the transaction fee has been calculated, the ids are separated by commas
+	FeeRuleIDs    string
+	FeeAbsorbedBy string
+
+	// For storing the amount that has been requested before the fee and the conversion to the paid currency
+	OriginalAmount   money.Amount `gorm:"type:decimal(19,2);"`
+	OriginalCurrency string
+	ExchangeRate     money.Rate `gorm:"type:decimal(19,8);"` // The rate the original amount has been converted with
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 // SPSubscriptionTransaction (ServiceProviderSubscriptionTransaction) is a type that defines
@@ -58,10 +120,28 @@ type SPSubscriptionTransaction struct {
 type SPPayrollTransaction struct {
 	ID                    string `gorm:"primary_key; unique;"`
 	ProviderID            string
-	PayedAmount           float64
-	LinkedAccount         string // Indicates to which account payment was made
+	PayedAmount           money.Amount `gorm:"type:decimal(19,2);"`
+	CurrencyType          string
+	LinkedAccount         string `redact:"mask"` // Indicates to which account payment was made
 	LinkedAccountProvider string // Indicates to which account provider payment was made
 	Status                string
 	CreatedAt             time.Time
 	UpdatedAt             time.Time
 }
+
+// Receipt is a type that defines a numbered receipt that has been issued for a completed transaction
+type Receipt struct {
+	ID              string `gorm:"primary_key; unique;"`
+	ProviderID      string `gorm:"unique_index:unique_provider_receipt_number;"` // Defining composite unique key
+	ReceiptNumber   int64  `gorm:"unique_index:unique_provider_receipt_number;"` // Sequential per service provider without gaps
+	TransactionID   string `gorm:"unique_index:unique_transaction_receipt;"`     // Defining composite unique key
+	TransactionType string `gorm:"unique_index:unique_transaction_receipt;"`     // Defining composite unique key
+	CreatedAt       time.Time
+}
+
+// ReceiptSequence is a type that defines the last receipt number that has been issued for a service provider
+type ReceiptSequence struct {
+	ProviderID string `gorm:"primary_key; unique;"`
+	LastNumber int64
+	UpdatedAt  time.Time
+}
@@ -3,13 +3,15 @@ package entity
 import (
 	"encoding/json"
 	"fmt"
+
+	"github.com/Benyam-S/onemembership/redact"
 )
 
 // ToString is a method that converts a User struct to readable JSON string format
 func (user *User) ToString() string {
-	output, err := json.Marshal(user)
+	output, err := json.Marshal(redact.Struct(user))
 	if err != nil {
-		return fmt.Sprint(user)
+		return fmt.Sprint(redact.Struct(user))
 	}
 
 	return string(output)
@@ -17,9 +19,9 @@ func (user *User) ToString() string {
 
 // ToString is a method that converts a User Password struct to readable JSON string format
 func (userPassword *UserPassword) ToString() string {
-	output, err := json.Marshal(userPassword)
+	output, err := json.Marshal(redact.Struct(userPassword))
 	if err != nil {
-		return fmt.Sprint(userPassword)
+		return fmt.Sprint(redact.Struct(userPassword))
 	}
 
 	return string(output)
@@ -27,9 +29,9 @@ func (userPassword *UserPassword) ToString() string {
 
 // ToString is a method that converts a Service Provider struct to readable JSON string format
 func (serviceProvider *ServiceProvider) ToString() string {
-	output, err := json.Marshal(serviceProvider)
+	output, err := json.Marshal(redact.Struct(serviceProvider))
 	if err != nil {
-		return fmt.Sprint(serviceProvider)
+		return fmt.Sprint(redact.Struct(serviceProvider))
 	}
 
 	return string(output)
@@ -37,9 +39,9 @@ func (serviceProvider *ServiceProvider) ToString() string {
 
 // ToString is a method that converts a Service Provider Password struct to readable JSON string format
 func (serviceProviderPassword *SPPassword) ToString() string {
-	output, err := json.Marshal(serviceProviderPassword)
+	output, err := json.Marshal(redact.Struct(serviceProviderPassword))
 	if err != nil {
-		return fmt.Sprint(serviceProviderPassword)
+		return fmt.Sprint(redact.Struct(serviceProviderPassword))
 	}
 
 	return string(output)
@@ -47,9 +49,19 @@ func (serviceProviderPassword *SPPassword) ToString() string {
 
 // ToString is a method that converts a Service Provider Wallet struct to readable JSON string format
 func (spWallet *SPWallet) ToString() string {
-	output, err := json.Marshal(spWallet)
+	output, err := json.Marshal(redact.Struct(spWallet))
+	if err != nil {
+		return fmt.Sprint(redact.Struct(spWallet))
+	}
+
+	return string(output)
+}
+
+// ToString is a method that converts a Service Provider Wallet Balance struct to readable JSON string format
+func (spWalletBalance *SPWalletBalance) ToString() string {
+	output, err := json.Marshal(redact.Struct(spWalletBalance))
 	if err != nil {
-		return fmt.Sprint(spWallet)
+		return fmt.Sprint(redact.Struct(spWalletBalance))
 	}
 
 	return string(output)
@@ -57,9 +69,9 @@ func (spWallet *SPWallet) ToString() string {
 
 // ToString is a method that converts a Service Provider Subscription struct to readable JSON string format
 func (spSubscription *SPSubscription) ToString() string {
-	output, err := json.Marshal(spSubscription)
+	output, err := json.Marshal(redact.Struct(spSubscription))
 	if err != nil {
-		return fmt.Sprint(spSubscription)
+		return fmt.Sprint(redact.Struct(spSubscription))
 	}
 
 	return string(output)
@@ -67,9 +79,9 @@ func (spSubscription *SPSubscription) ToString() string {
 
 // ToString is a method that converts a Service Provider Subscription Transaction struct to readable JSON string format
 func (spSubscriptionTransaction *SPSubscriptionTransaction) ToString() string {
-	output, err := json.Marshal(spSubscriptionTransaction)
+	output, err := json.Marshal(redact.Struct(spSubscriptionTransaction))
 	if err != nil {
-		return fmt.Sprint(spSubscriptionTransaction)
+		return fmt.Sprint(redact.Struct(spSubscriptionTransaction))
 	}
 
 	return string(output)
@@ -77,9 +89,9 @@ func (spSubscriptionTransaction *SPSubscriptionTransaction) ToString() string {
 
 // ToString is a method that converts a Service Provider Payroll Transaction struct to readable JSON string format
 func (spPayrollTransaction *SPPayrollTransaction) ToString() string {
-	output, err := json.Marshal(spPayrollTransaction)
+	output, err := json.Marshal(redact.Struct(spPayrollTransaction))
 	if err != nil {
-		return fmt.Sprint(spPayrollTransaction)
+		return fmt.Sprint(redact.Struct(spPayrollTransaction))
 	}
 
 	return string(output)
@@ -87,9 +99,9 @@ func (spPayrollTransaction *SPPayrollTransaction) ToString() string {
 
 // ToString is a method that converts a Service Provider Subscription Plan struct to readable JSON string format
 func (spSubscriptionPlan *SPSubscriptionPlan) ToString() string {
-	output, err := json.Marshal(spSubscriptionPlan)
+	output, err := json.Marshal(redact.Struct(spSubscriptionPlan))
 	if err != nil {
-		return fmt.Sprint(spSubscriptionPlan)
+		return fmt.Sprint(redact.Struct(spSubscriptionPlan))
 	}
 
 	return string(output)
@@ -97,9 +109,9 @@ func (spSubscriptionPlan *SPSubscriptionPlan) ToString() string {
 
 // ToString is a method that converts a Subscription struct to readable JSON string format
 func (subscription *Subscription) ToString() string {
-	output, err := json.Marshal(subscription)
+	output, err := json.Marshal(redact.Struct(subscription))
 	if err != nil {
-		return fmt.Sprint(subscription)
+		return fmt.Sprint(redact.Struct(subscription))
 	}
 
 	return string(output)
@@ -107,9 +119,9 @@ func (subscription *Subscription) ToString() string {
 
 // ToString is a method that converts a Subscription Transaction struct to readable JSON string format
 func (subscriptionTransaction *SubscriptionTransaction) ToString() string {
-	output, err := json.Marshal(subscriptionTransaction)
+	output, err := json.Marshal(redact.Struct(subscriptionTransaction))
 	if err != nil {
-		return fmt.Sprint(subscriptionTransaction)
+		return fmt.Sprint(redact.Struct(subscriptionTransaction))
 	}
 
 	return string(output)
@@ -117,9 +129,29 @@ func (subscriptionTransaction *SubscriptionTransaction) ToString() string {
 
 // ToString is a method that converts a Subscription Plan struct to readable JSON string format
 func (subscriptionPlan *SubscriptionPlan) ToString() string {
-	output, err := json.Marshal(subscriptionPlan)
+	output, err := json.Marshal(redact.Struct(subscriptionPlan))
+	if err != nil {
+		return fmt.Sprint(redact.Struct(subscriptionPlan))
+	}
+
+	return string(output)
+}
+
+// ToString is a method that converts a Coupon struct to readable JSON string format
+func (coupon *Coupon) ToString() string {
+	output, err := json.Marshal(redact.Struct(coupon))
+	if err != nil {
+		return fmt.Sprint(redact.Struct(coupon))
+	}
+
+	return string(output)
+}
+
+// ToString is a method that converts a Coupon Redemption struct to readable JSON string format
+func (couponRedemption *CouponRedemption) ToString() string {
+	output, err := json.Marshal(redact.Struct(couponRedemption))
 	if err != nil {
-		return fmt.Sprint(subscriptionPlan)
+		return fmt.Sprint(redact.Struct(couponRedemption))
 	}
 
 	return string(output)
@@ -127,9 +159,9 @@ func (subscriptionPlan *SubscriptionPlan) ToString() string {
 
 // ToString is a method that converts a PlanChatLink struct to readable JSON string format
 func (planChatLink *PlanChatLink) ToString() string {
-	output, err := json.Marshal(planChatLink)
+	output, err := json.Marshal(redact.Struct(planChatLink))
 	if err != nil {
-		return fmt.Sprint(planChatLink)
+		return fmt.Sprint(redact.Struct(planChatLink))
 	}
 
 	return string(output)
@@ -137,9 +169,9 @@ func (planChatLink *PlanChatLink) ToString() string {
 
 // ToString is a method that converts a UserChatLink struct to readable JSON string format
 func (userChatLink *UserChatLink) ToString() string {
-	output, err := json.Marshal(userChatLink)
+	output, err := json.Marshal(redact.Struct(userChatLink))
 	if err != nil {
-		return fmt.Sprint(userChatLink)
+		return fmt.Sprint(redact.Struct(userChatLink))
 	}
 
 	return string(output)
@@ -147,9 +179,9 @@ func (userChatLink *UserChatLink) ToString() string {
 
 // ToString is a method that converts a Project struct to readable JSON string format
 func (project *Project) ToString() string {
-	output, err := json.Marshal(project)
+	output, err := json.Marshal(redact.Struct(project))
 	if err != nil {
-		return fmt.Sprint(project)
+		return fmt.Sprint(redact.Struct(project))
 	}
 
 	return string(output)
@@ -157,9 +189,9 @@ func (project *Project) ToString() string {
 
 // ToString is a method that converts a ProjectChatLink struct to readable JSON string format
 func (projectChatLink *ProjectChatLink) ToString() string {
-	output, err := json.Marshal(projectChatLink)
+	output, err := json.Marshal(redact.Struct(projectChatLink))
 	if err != nil {
-		return fmt.Sprint(projectChatLink)
+		return fmt.Sprint(redact.Struct(projectChatLink))
 	}
 
 	return string(output)
@@ -167,9 +199,39 @@ func (projectChatLink *ProjectChatLink) ToString() string {
 
 // ToString is a method that converts a PaymentGateway struct to readable JSON string format
 func (paymentGateway *PaymentGateway) ToString() string {
-	output, err := json.Marshal(paymentGateway)
+	output, err := json.Marshal(redact.Struct(paymentGateway))
+	if err != nil {
+		return fmt.Sprint(redact.Struct(paymentGateway))
+	}
+
+	return string(output)
+}
+
+// ToString is a method that converts a Currency struct to readable JSON string format
+func (currency *Currency) ToString() string {
+	output, err := json.Marshal(redact.Struct(currency))
 	if err != nil {
-		return fmt.Sprint(paymentGateway)
+		return fmt.Sprint(redact.Struct(currency))
+	}
+
+	return string(output)
+}
+
+// ToString is a method that converts an Exchange Rate struct to readable JSON string format
+func (exchangeRate *ExchangeRate) ToString() string {
+	output, err := json.Marshal(redact.Struct(exchangeRate))
+	if err != nil {
+		return fmt.Sprint(redact.Struct(exchangeRate))
+	}
+
+	return string(output)
+}
+
+// ToString is a method that converts a Fee Rule struct to readable JSON string format
+func (feeRule *FeeRule) ToString() string {
+	output, err := json.Marshal(redact.Struct(feeRule))
+	if err != nil {
+		return fmt.Sprint(redact.Struct(feeRule))
 	}
 
 	return string(output)
@@ -177,9 +239,9 @@ func (paymentGateway *PaymentGateway) ToString() string {
 
 // ToString is a method that converts a Feedback struct to readable JSON string format
 func (feedback *Feedback) ToString() string {
-	output, err := json.Marshal(feedback)
+	output, err := json.Marshal(redact.Struct(feedback))
 	if err != nil {
-		return fmt.Sprint(feedback)
+		return fmt.Sprint(redact.Struct(feedback))
 	}
 
 	return string(output)
@@ -187,9 +249,9 @@ func (feedback *Feedback) ToString() string {
 
 // ToString is a method that converts a Deleted User struct to readable JSON string format
 func (deletedUser *DeletedUser) ToString() string {
-	output, err := json.Marshal(deletedUser)
+	output, err := json.Marshal(redact.Struct(deletedUser))
 	if err != nil {
-		return fmt.Sprint(deletedUser)
+		return fmt.Sprint(redact.Struct(deletedUser))
 	}
 
 	return string(output)
@@ -197,9 +259,9 @@ func (deletedUser *DeletedUser) ToString() string {
 
 // ToString is a method that converts a Deleted Service Provider struct to readable JSON string format
 func (deletedServiceProvider *DeletedServiceProvider) ToString() string {
-	output, err := json.Marshal(deletedServiceProvider)
+	output, err := json.Marshal(redact.Struct(deletedServiceProvider))
 	if err != nil {
-		return fmt.Sprint(deletedServiceProvider)
+		return fmt.Sprint(redact.Struct(deletedServiceProvider))
 	}
 
 	return string(output)
@@ -207,9 +269,9 @@ func (deletedServiceProvider *DeletedServiceProvider) ToString() string {
 
 // ToString is a method that converts a Deleted Service Provider Subscription Transaction struct to readable JSON string format
 func (deletedSPSubscriptionTransaction *DeletedSPSubscriptionTransaction) ToString() string {
-	output, err := json.Marshal(deletedSPSubscriptionTransaction)
+	output, err := json.Marshal(redact.Struct(deletedSPSubscriptionTransaction))
 	if err != nil {
-		return fmt.Sprint(deletedSPSubscriptionTransaction)
+		return fmt.Sprint(redact.Struct(deletedSPSubscriptionTransaction))
 	}
 
 	return string(output)
@@ -217,9 +279,9 @@ func (deletedSPSubscriptionTransaction *DeletedSPSubscriptionTransaction) ToStri
 
 // ToString is a method that converts a Deleted Service Provider Payroll Transaction struct to readable JSON string format
 func (deletedSPPayrollTransaction *DeletedSPPayrollTransaction) ToString() string {
-	output, err := json.Marshal(deletedSPPayrollTransaction)
+	output, err := json.Marshal(redact.Struct(deletedSPPayrollTransaction))
 	if err != nil {
-		return fmt.Sprint(deletedSPPayrollTransaction)
+		return fmt.Sprint(redact.Struct(deletedSPPayrollTransaction))
 	}
 
 	return string(output)
@@ -227,9 +289,9 @@ func (deletedSPPayrollTransaction *DeletedSPPayrollTransaction) ToString() strin
 
 // ToString is a method that converts a Deleted User Subscription Transaction struct to readable JSON string format
 func (deletedSubscriptionTransaction *DeletedSubscriptionTransaction) ToString() string {
-	output, err := json.Marshal(deletedSubscriptionTransaction)
+	output, err := json.Marshal(redact.Struct(deletedSubscriptionTransaction))
 	if err != nil {
-		return fmt.Sprint(deletedSubscriptionTransaction)
+		return fmt.Sprint(redact.Struct(deletedSubscriptionTransaction))
 	}
 
 	return string(output)
@@ -237,9 +299,9 @@ func (deletedSubscriptionTransaction *DeletedSubscriptionTransaction) ToString()
 
 // ToString is a method that converts a Client Preference struct to readable JSON string format
 func (preference *ClientPreference) ToString() string {
-	output, err := json.Marshal(preference)
+	output, err := json.Marshal(redact.Struct(preference))
 	if err != nil {
-		return fmt.Sprint(preference)
+		return fmt.Sprint(redact.Struct(preference))
 	}
 
 	return string(output)
@@ -247,9 +309,39 @@ func (preference *ClientPreference) ToString() string {
 
 // ToString is a method that converts a Language Entry struct to readable JSON string format
 func (language *LanguageEntry) ToString() string {
-	output, err := json.Marshal(language)
+	output, err := json.Marshal(redact.Struct(language))
+	if err != nil {
+		return fmt.Sprint(redact.Struct(language))
+	}
+
+	return string(output)
+}
+
+// ToString is a method that converts a Journal Entry struct to readable JSON string format
+func (journalEntry *JournalEntry) ToString() string {
+	output, err := json.Marshal(redact.Struct(journalEntry))
+	if err != nil {
+		return fmt.Sprint(redact.Struct(journalEntry))
+	}
+
+	return string(output)
+}
+
+// ToString is a method that converts a Receipt struct to readable JSON string format
+func (receipt *Receipt) ToString() string {
+	output, err := json.Marshal(redact.Struct(receipt))
+	if err != nil {
+		return fmt.Sprint(redact.Struct(receipt))
+	}
+
+	return string(output)
+}
+
+// ToString is a method that converts an Audit Record struct to readable JSON string format
+func (auditRecord *AuditRecord) ToString() string {
+	output, err := json.Marshal(redact.Struct(auditRecord))
 	if err != nil {
-		return fmt.Sprint(language)
+		return fmt.Sprint(redact.Struct(auditRecord))
 	}
 
 	return string(output)
@@ -0,0 +1,19 @@
+package fee
+
+import (
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+)
+
+// IFeeRuleRepository is an interface that defines all the repository methods of a fee rule struct
+type IFeeRuleRepository interface {
+	Create(newFeeRule *entity.FeeRule) error
+	Find(id string) (*entity.FeeRule, error)
+	FindApplicable(gatewayID int64, spSubscriptionPlanID, currencyType string) []*entity.FeeRule
+	FindProviderTier(providerID string, moment time.Time) string
+	FindPlanProviderTier(subscriptionPlanID string, moment time.Time) string
+	All() []*entity.FeeRule
+	Update(feeRule *entity.FeeRule) error
+	Delete(id string) (*entity.FeeRule, error)
+}
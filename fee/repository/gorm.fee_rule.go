@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/fee"
+	"github.com/Benyam-S/onemembership/tools"
+	"github.com/jinzhu/gorm"
+)
+
+// FeeRuleRepository is a type that defines a fee rule repository type
+type FeeRuleRepository struct {
+	conn *gorm.DB
+}
+
+// NewFeeRuleRepository is a function that creates a new fee rule repository type
+func NewFeeRuleRepository(connection *gorm.DB) fee.IFeeRuleRepository {
+	return &FeeRuleRepository{conn: connection}
+}
+
+// Create is a method that adds a new fee rule to the database
+func (repo *FeeRuleRepository) Create(newFeeRule *entity.FeeRule) error {
+
+	totalNumOfFeeRules := tools.CountMembers("fee_rules", repo.conn)
+	newFeeRule.ID = fmt.Sprintf("FR-%s%d", tools.RandomStringGN(7), totalNumOfFeeRules+1)
+
+	for !tools.IsUnique("id", newFeeRule.ID, "fee_rules", repo.conn) {
+		totalNumOfFeeRules++
+		newFeeRule.ID = fmt.Sprintf("FR-%s%d", tools.RandomStringGN(7), totalNumOfFeeRules+1)
+	}
+
+	err := repo.conn.Create(newFeeRule).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Find is a method that finds a certain fee rule from the database using an fee rule id,
+// also Find() uses only id as a key for selection
+func (repo *FeeRuleRepository) Find(id string) (*entity.FeeRule, error) {
+
+	feeRule := new(entity.FeeRule)
+	err := repo.conn.Model(feeRule).Where("id = ?", id).First(feeRule).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return feeRule, nil
+}
+
+// FindApplicable is a method that finds the fee rules that apply to a payment made through the given gateway,
+// by a service provider on the given tier and in the given currency, including the rules that apply to any value
+func (repo *FeeRuleRepository) FindApplicable(gatewayID int64, spSubscriptionPlanID,
+	currencyType string) []*entity.FeeRule {
+
+	var feeRules []*entity.FeeRule
+	err := repo.conn.Model(entity.FeeRule{}).Where("(gateway_id = ? || gateway_id = 0) && "+
+		"(sp_subscription_plan_id = ? || sp_subscription_plan_id = '') && (currency_type = ? || currency_type = '')",
+		gatewayID, spSubscriptionPlanID, currencyType).Order("created_at ASC").Find(&feeRules).Error
+
+	if err != nil {
+		return []*entity.FeeRule{}
+	}
+	return feeRules
+}
+
+// FindProviderTier is a method that returns the platform subscription plan of a service provider
+// whose subscription hasn't expired at the given moment, an empty string is returned if there is none
+func (repo *FeeRuleRepository) FindProviderTier(providerID string, moment time.Time) string {
+
+	spSubscription := new(entity.SPSubscription)
+	err := repo.conn.Model(spSubscription).Where("provider_id = ? && expires_at > ?", providerID, moment).
+		First(spSubscription).Error
+
+	if err != nil {
+		return ""
+	}
+	return spSubscription.SubscriptionPlanID
+}
+
+// FindPlanProviderTier is a method that returns the platform subscription plan of the service provider owning
+// the given subscription plan whose subscription hasn't expired at the given moment, an empty string is returned if there is none
+func (repo *FeeRuleRepository) FindPlanProviderTier(subscriptionPlanID string, moment time.Time) string {
+
+	spSubscription := new(entity.SPSubscription)
+	err := repo.conn.Model(spSubscription).Select("sp_subscriptions.*").
+		Joins("INNER JOIN projects ON projects.provider_id = sp_subscriptions.provider_id").
+		Joins("INNER JOIN subscription_plans ON subscription_plans.project_id = projects.id").
+		Where("subscription_plans.id = ? && sp_subscriptions.expires_at > ?", subscriptionPlanID, moment).
+		First(spSubscription).Error
+
+	if err != nil {
+		return ""
+	}
+	return spSubscription.SubscriptionPlanID
+}
+
+// All is a method that returns all the fee rules found in the database
+func (repo *FeeRuleRepository) All() []*entity.FeeRule {
+
+	var feeRules []*entity.FeeRule
+	repo.conn.Model(entity.FeeRule{}).Order("created_at ASC").Find(&feeRules)
+
+	return feeRules
+}
+
+// Update is a method that updates a certain fee rule entries in the database
+func (repo *FeeRuleRepository) Update(feeRule *entity.FeeRule) error {
+
+	prevFeeRule := new(entity.FeeRule)
+	err := repo.conn.Model(prevFeeRule).Where("id = ?", feeRule.ID).First(prevFeeRule).Error
+
+	if err != nil {
+		return err
+	}
+
+	/* --------------------------- can change layer if needed --------------------------- */
+	feeRule.CreatedAt = prevFeeRule.CreatedAt
+	/* -------------------------------------- end --------------------------------------- */
+
+	err = repo.conn.Save(feeRule).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Delete is a method that deletes a certain fee rule from the database using an fee rule id.
+// In Delete() id is only used as an key
+func (repo *FeeRuleRepository) Delete(id string) (*entity.FeeRule, error) {
+	feeRule := new(entity.FeeRule)
+	err := repo.conn.Model(feeRule).Where("id = ?", id).First(feeRule).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	repo.conn.Delete(feeRule)
+	return feeRule, nil
+}
@@ -0,0 +1,39 @@
+package fee
+
+import (
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/money"
+)
+
+// BasisPoints is a constant that holds the rate of a whole, so a rate of BasisPoints is 100%
+const BasisPoints = 10000
+
+// Request is a type that defines the details the fee engine needs to calculate the fee of a payment
+type Request struct {
+	GatewayID          int64
+	ProviderID         string // The service provider whose tier is used, set for service provider subscription transactions
+	SubscriptionPlanID string // The plan whose service provider's tier is used, set for subscription transactions
+	CurrencyType       string
+	Amount             money.Amount // The price of what is being paid for
+	DefaultFee         money.Amount // The payment provider's own fee, used when no fee rule applies
+}
+
+// Quote is a type that defines the amounts of a payment after the fee engine has applied the fee rules
+type Quote struct {
+	ReceivedAmount money.Amount // The amount the receiver gets
+	TransactionFee money.Amount
+	TotalAmount    money.Amount // The amount the payer pays, where TotalAmount = ReceivedAmount + TransactionFee
+	AbsorbedBy     string
+	RuleIDs        []string
+}
+
+// IService is an interface that defines all the service methods of a fee engine
+type IService interface {
+	AddFeeRule(newFeeRule *entity.FeeRule) error
+	ValidateFeeRule(feeRule *entity.FeeRule) entity.ErrMap
+	FindFeeRule(id string) (*entity.FeeRule, error)
+	AllFeeRules() []*entity.FeeRule
+	UpdateFeeRule(feeRule *entity.FeeRule) error
+	DeleteFeeRule(id string) (*entity.FeeRule, error)
+	CalculateFee(request *Request) (*Quote, error)
+}
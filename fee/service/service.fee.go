@@ -0,0 +1,305 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Benyam-S/onemembership/common"
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/fee"
+	"github.com/Benyam-S/onemembership/log"
+	"github.com/Benyam-S/onemembership/money"
+)
+
+// Service is a type that defines a fee engine service
+type Service struct {
+	feeRuleRepo fee.IFeeRuleRepository
+	cmService   common.IService
+	logger      *log.Logger
+}
+
+// NewFeeService is a function that returns a new fee engine service
+func NewFeeService(feeRuleRepository fee.IFeeRuleRepository, commonService common.IService,
+	transactionLogger *log.Logger) fee.IService {
+	return &Service{feeRuleRepo: feeRuleRepository, cmService: commonService, logger: transactionLogger}
+}
+
+// AddFeeRule is a method that adds a new fee rule to the system
+func (service *Service) AddFeeRule(newFeeRule *entity.FeeRule) error {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started fee rule adding process, Fee Rule => %s",
+		newFeeRule.ToString()), service.logger.Logs.TransactionLogFile)
+
+	err := service.feeRuleRepo.Create(newFeeRule)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For adding Fee Rule => %s, %s",
+			newFeeRule.ToString(), err.Error()))
+
+		return errors.New("unable to add new fee rule")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished fee rule adding process, Fee Rule => %s",
+		newFeeRule.ToString()), service.logger.Logs.TransactionLogFile)
+
+	return nil
+}
+
+// ValidateFeeRule is a method that validates a fee rule entries.
+// It checks if the fee rule has a valid entries or not and return map of errors if any.
+func (service *Service) ValidateFeeRule(feeRule *entity.FeeRule) entity.ErrMap {
+
+	errMap := make(map[string]error)
+
+	empty, _ := regexp.MatchString(`^\s*$`, feeRule.Name)
+	if empty {
+		errMap["name"] = errors.New(`fee rule name can not be empty`)
+	} else if len(feeRule.Name) > 255 {
+		errMap["name"] = errors.New(`fee rule name should not be longer than 255 characters`)
+	}
+
+	switch feeRule.Type {
+	case entity.FeeTypeFixed:
+		if feeRule.FixedAmount <= 0 {
+			errMap["fixed_amount"] = errors.New(`fixed fee rule should have a fixed amount`)
+		}
+		if feeRule.Rate != 0 {
+			errMap["rate"] = errors.New(`fixed fee rule can not have a rate`)
+		}
+	case entity.FeeTypePercentage, entity.FeeTypeDiscount:
+		if feeRule.FixedAmount != 0 {
+			errMap["fixed_amount"] = errors.New(`fee rule can not have a fixed amount`)
+		}
+		if feeRule.Rate <= 0 || feeRule.Rate > fee.BasisPoints {
+			errMap["rate"] = errors.New(`invalid fee rule rate used`)
+		}
+	case entity.FeeTypeFixedPlusPercentage:
+		if feeRule.FixedAmount < 0 {
+			errMap["fixed_amount"] = errors.New(`invalid fee rule fixed amount used`)
+		}
+		if feeRule.Rate < 0 || feeRule.Rate > fee.BasisPoints {
+			errMap["rate"] = errors.New(`invalid fee rule rate used`)
+		}
+	default:
+		errMap["type"] = errors.New(`invalid fee rule type selected`)
+	}
+
+	if feeRule.Type == entity.FeeTypeDiscount {
+		feeRule.AbsorbedBy = ""
+	} else if feeRule.AbsorbedBy == "" {
+		// The payer absorbs the fee unless stated otherwise, same as the payment provider's own fee
+		feeRule.AbsorbedBy = entity.FeeAbsorbedByPayer
+	} else if feeRule.AbsorbedBy != entity.FeeAbsorbedByPayer && feeRule.AbsorbedBy != entity.FeeAbsorbedByProvider {
+		errMap["absorbed_by"] = errors.New(`invalid fee absorber selected`)
+	}
+
+	if feeRule.GatewayID != 0 && service.cmService.IsUnique("id", feeRule.GatewayID, "payment_gateways") {
+		errMap["gateway_id"] = errors.New(`no payment gateway found`)
+	}
+
+	if feeRule.SPSubscriptionPlanID != "" &&
+		service.cmService.IsUnique("id", feeRule.SPSubscriptionPlanID, "sp_subscription_plans") {
+		errMap["sp_subscription_plan_id"] = errors.New(`no service provider subscription plan found`)
+	}
+
+	emptyCurrency, _ := regexp.MatchString(`^\s*$`, feeRule.CurrencyType)
+	if emptyCurrency {
+		feeRule.CurrencyType = ""
+
+		// A fixed amount is meaningless without its currency
+		if feeRule.FixedAmount != 0 && errMap["fixed_amount"] == nil {
+			errMap["currency_type"] = errors.New(`fee rule with a fixed amount should have a currency type`)
+		}
+	} else {
+		var isValidCurrencyType bool
+		currencyTypes := service.cmService.GetAllValidCurrencyTypes()
+		for _, currencyType := range currencyTypes {
+			if strings.ToUpper(currencyType) == strings.ToUpper(feeRule.CurrencyType) {
+				isValidCurrencyType = true
+				break
+			}
+		}
+
+		if !isValidCurrencyType {
+			errMap["currency_type"] = errors.New(`invalid currency type selected`)
+		}
+	}
+
+	if len(errMap) > 0 {
+		return errMap
+	}
+
+	return nil
+}
+
+// FindFeeRule is a method that find and return a fee rule that matches the id value
+func (service *Service) FindFeeRule(id string) (*entity.FeeRule, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Single fee rule finding process { Fee Rule ID : %s }", id),
+		service.logger.Logs.TransactionLogFile)
+
+	empty, _ := regexp.MatchString(`^\s*$`, id)
+	if empty {
+		return nil, errors.New("no fee rule found")
+	}
+
+	feeRule, err := service.feeRuleRepo.Find(id)
+	if err != nil {
+		return nil, errors.New("no fee rule found")
+	}
+	return feeRule, nil
+}
+
+// AllFeeRules is a method that returns all the fee rules in the system
+func (service *Service) AllFeeRules() []*entity.FeeRule {
+	return service.feeRuleRepo.All()
+}
+
+// UpdateFeeRule is a method that updates a fee rule in the system
+func (service *Service) UpdateFeeRule(feeRule *entity.FeeRule) error {
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started fee rule updating process, Fee Rule => %s",
+		feeRule.ToString()), service.logger.Logs.TransactionLogFile)
+
+	err := service.feeRuleRepo.Update(feeRule)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For updating Fee Rule => %s, %s",
+			feeRule.ToString(), err.Error()))
+
+		return errors.New("unable to update fee rule")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished fee rule updating process, Fee Rule => %s",
+		feeRule.ToString()), service.logger.Logs.TransactionLogFile)
+
+	return nil
+}
+
+// DeleteFeeRule is a method that deletes a fee rule from the system using an id
+func (service *Service) DeleteFeeRule(id string) (*entity.FeeRule, error) {
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started fee rule deleting process { Fee Rule ID : %s }", id),
+		service.logger.Logs.TransactionLogFile)
+
+	feeRule, err := service.feeRuleRepo.Delete(id)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For deleting fee rule { Fee Rule ID : %s }, %s",
+			id, err.Error()))
+
+		return nil, errors.New("unable to delete fee rule")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished fee rule deleting process, Deleted Fee Rule => %s",
+		feeRule.ToString()), service.logger.Logs.TransactionLogFile)
+
+	return feeRule, nil
+}
+
+// CalculateFee is a method that calculates the transaction fee of a payment using the most specific fee rule
+// that applies to it, followed by the most specific discount of the service provider's tier.
+// When no fee rule applies the payment provider's own fee is charged to the payer.
+func (service *Service) CalculateFee(request *fee.Request) (*fee.Quote, error) {
+
+	if request.Amount < 0 {
+		return nil, errors.New("invalid payment amount")
+	}
+
+	var tier string
+	if request.ProviderID != "" {
+		tier = service.feeRuleRepo.FindProviderTier(request.ProviderID, time.Now())
+	} else if request.SubscriptionPlanID != "" {
+		tier = service.feeRuleRepo.FindPlanProviderTier(request.SubscriptionPlanID, time.Now())
+	}
+
+	var baseRule, discountRule *entity.FeeRule
+	feeRules := service.feeRuleRepo.FindApplicable(request.GatewayID, tier, request.CurrencyType)
+	for _, feeRule := range feeRules {
+		if feeRule.Type == entity.FeeTypeDiscount {
+			discountRule = moreSpecific(discountRule, feeRule)
+		} else {
+			baseRule = moreSpecific(baseRule, feeRule)
+		}
+	}
+
+	quote := &fee.Quote{TransactionFee: request.DefaultFee, AbsorbedBy: entity.FeeAbsorbedByPayer,
+		RuleIDs: make([]string, 0)}
+
+	if baseRule != nil {
+		quote.TransactionFee = 0
+		// A fixed amount can only be charged on payments made in the currency it has been set in
+		if baseRule.Type != entity.FeeTypePercentage && baseRule.FixedAmount != 0 {
+			fixedFee, err := money.New(0, request.CurrencyType).Add(money.New(baseRule.FixedAmount, baseRule.CurrencyType))
+			if err != nil {
+				/* ---------------------------- Logging ---------------------------- */
+				service.logger.LogToErrorFile(fmt.Sprintf("Error: For applying fee rule fixed amount "+
+					"{ Fee Rule ID : %s, Currency : %s, Rule Currency : %s }, %s", baseRule.ID, request.CurrencyType,
+					baseRule.CurrencyType, err.Error()))
+
+				return nil, errors.New("fee rule currency doesn't match the payment currency")
+			}
+
+			quote.TransactionFee = quote.TransactionFee.Add(fixedFee.Amount)
+		}
+		if baseRule.Type != entity.FeeTypeFixed {
+			quote.TransactionFee = quote.TransactionFee.Add(request.Amount.Prorate(baseRule.Rate, fee.BasisPoints))
+		}
+
+		quote.AbsorbedBy = baseRule.AbsorbedBy
+		quote.RuleIDs = append(quote.RuleIDs, baseRule.ID)
+	}
+
+	if discountRule != nil && quote.TransactionFee > 0 {
+		quote.TransactionFee = quote.TransactionFee.Sub(quote.TransactionFee.Prorate(discountRule.Rate, fee.BasisPoints))
+		quote.RuleIDs = append(quote.RuleIDs, discountRule.ID)
+	}
+
+	if quote.AbsorbedBy == entity.FeeAbsorbedByProvider {
+		if quote.TransactionFee > request.Amount {
+			return nil, errors.New("transaction fee exceeds the payment amount")
+		}
+
+		quote.ReceivedAmount = request.Amount.Sub(quote.TransactionFee)
+		quote.TotalAmount = request.Amount
+	} else {
+		quote.ReceivedAmount = request.Amount
+		quote.TotalAmount = request.Amount.Add(quote.TransactionFee)
+	}
+
+	return quote, nil
+}
+
+// moreSpecific is a function that returns the fee rule limited to more values, the current rule is kept on a tie
+// so the earliest created rule wins
+func moreSpecific(current, candidate *entity.FeeRule) *entity.FeeRule {
+	if current == nil || specificity(candidate) > specificity(current) {
+		return candidate
+	}
+	return current
+}
+
+// specificity is a function that returns the number of values a fee rule is limited to
+func specificity(feeRule *entity.FeeRule) int {
+
+	count := 0
+	if feeRule.GatewayID != 0 {
+		count++
+	}
+	if feeRule.SPSubscriptionPlanID != "" {
+		count++
+	}
+	if feeRule.CurrencyType != "" {
+		count++
+	}
+
+	return count
+}
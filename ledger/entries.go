@@ -0,0 +1,134 @@
+package ledger
+
+import (
+	"errors"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/money"
+)
+
+// SubscriptionPaymentEntry is a function that returns the journal entry of a completed subscription transaction,
+// the whole paid amount enters the gateway account and is split between the platform fee and the provider
+func SubscriptionPaymentEntry(subscriptionTransaction *entity.SubscriptionTransaction, providerID string) *entity.JournalEntry {
+	return newEntry(subscriptionTransaction.ID, entity.TransactionTypeSubscription, entity.JournalSubscriptionPayment,
+		subscriptionTransaction.CurrencyType,
+		debit(entity.AccountGateway, "", subscriptionTransaction.Total().Amount),
+		credit(entity.AccountPlatformFee, "", subscriptionTransaction.TransactionFee),
+		credit(entity.AccountProviderPayable, providerID, subscriptionTransaction.ReceivedAmount))
+}
+
+// SPSubscriptionPaymentEntry is a function that returns the journal entry of a completed service provider subscription transaction
+func SPSubscriptionPaymentEntry(spSubscriptionTransaction *entity.SPSubscriptionTransaction) *entity.JournalEntry {
+	return newEntry(spSubscriptionTransaction.ID, entity.TransactionTypeSPSubscription, entity.JournalSPSubscriptionPayment,
+		spSubscriptionTransaction.CurrencyType,
+		debit(entity.AccountGateway, "", spSubscriptionTransaction.Total().Amount),
+		credit(entity.AccountPlatformFee, "", spSubscriptionTransaction.TransactionFee),
+		credit(entity.AccountPlatformRevenue, "", spSubscriptionTransaction.ReceivedAmount))
+}
+
+// RefundEntry is a function that returns the journal entry of a requested refund, refunds hold a negative received amount
+func RefundEntry(refund *entity.SubscriptionTransaction, providerID string) *entity.JournalEntry {
+	return newEntry(refund.ID, entity.TransactionTypeSubscription, entity.JournalRefund, refund.CurrencyType,
+		debit(entity.AccountProviderPayable, providerID, refund.ReceivedAmount.Neg()),
+		credit(entity.AccountGateway, "", refund.ReceivedAmount.Neg()))
+}
+
+// RefundFailureEntry is a function that returns the journal entry reversing a failed refund
+func RefundFailureEntry(refund *entity.SubscriptionTransaction, providerID string) *entity.JournalEntry {
+	return newEntry(refund.ID, entity.TransactionTypeSubscription, entity.JournalRefundFailure, refund.CurrencyType,
+		debit(entity.AccountGateway, "", refund.ReceivedAmount.Neg()),
+		credit(entity.AccountProviderPayable, providerID, refund.ReceivedAmount.Neg()))
+}
+
+// WithdrawalEntry is a function that returns the journal entry of a requested withdrawal
+func WithdrawalEntry(payrollTransaction *entity.SPPayrollTransaction) *entity.JournalEntry {
+	return newEntry(payrollTransaction.ID, entity.TransactionTypeSPPayroll, entity.JournalWithdrawal,
+		payrollTransaction.CurrencyType,
+		debit(entity.AccountProviderPayable, payrollTransaction.ProviderID, payrollTransaction.PayedAmount),
+		credit(entity.AccountProviderPendingPayout, payrollTransaction.ProviderID, payrollTransaction.PayedAmount))
+}
+
+// WithdrawalRejectionEntry is a function that returns the journal entry reversing a rejected withdrawal
+func WithdrawalRejectionEntry(payrollTransaction *entity.SPPayrollTransaction) *entity.JournalEntry {
+	return newEntry(payrollTransaction.ID, entity.TransactionTypeSPPayroll, entity.JournalWithdrawalRejection,
+		payrollTransaction.CurrencyType,
+		debit(entity.AccountProviderPendingPayout, payrollTransaction.ProviderID, payrollTransaction.PayedAmount),
+		credit(entity.AccountProviderPayable, payrollTransaction.ProviderID, payrollTransaction.PayedAmount))
+}
+
+// PayoutEntry is a function that returns the journal entry of a withdrawal that has been paid to the provider
+func PayoutEntry(payrollTransaction *entity.SPPayrollTransaction) *entity.JournalEntry {
+	return newEntry(payrollTransaction.ID, entity.TransactionTypeSPPayroll, entity.JournalPayout,
+		payrollTransaction.CurrencyType,
+		debit(entity.AccountProviderPendingPayout, payrollTransaction.ProviderID, payrollTransaction.PayedAmount),
+		credit(entity.AccountGateway, "", payrollTransaction.PayedAmount))
+}
+
+// OpeningBalanceEntry is a function that returns the journal entry bringing the ledger balances of a wallet up to
+// the figures the wallet held before the ledger was introduced, the difference is taken from the opening balance account
+func OpeningBalanceEntry(walletBalance *WalletBalance) *entity.JournalEntry {
+
+	runningDifference := walletBalance.WalletRunningAmount.Sub(walletBalance.LedgerRunningAmount)
+	pendingDifference := walletBalance.WalletPendingAmount.Sub(walletBalance.LedgerPendingAmount)
+
+	return newEntry(walletBalance.ProviderID+":"+walletBalance.CurrencyType, entity.TransactionTypeSPWallet,
+		entity.JournalOpeningBalance, walletBalance.CurrencyType,
+		adjust(entity.AccountProviderPayable, walletBalance.ProviderID, runningDifference),
+		adjust(entity.AccountProviderPendingPayout, walletBalance.ProviderID, pendingDifference),
+		adjust(entity.AccountOpeningBalance, "", runningDifference.Add(pendingDifference).Neg()))
+}
+
+// Validate is a function that checks whether a journal entry can be posted, journal line amounts can't be negative
+// and the debits and credits of the entry have to balance
+func Validate(entry *entity.JournalEntry) error {
+
+	var totalDebit, totalCredit money.Amount
+	for _, line := range entry.Lines {
+		if line.Debit < 0 || line.Credit < 0 {
+			return errors.New("journal line amounts can not be negative")
+		}
+
+		totalDebit = totalDebit.Add(line.Debit)
+		totalCredit = totalCredit.Add(line.Credit)
+	}
+
+	if totalDebit != totalCredit {
+		return errors.New("journal entry debits and credits don't balance")
+	}
+
+	return nil
+}
+
+// newEntry is a function that creates a journal entry from the given lines, lines without an amount are left out
+func newEntry(transactionID, transactionType, kind, currency string, lines ...*entity.JournalLine) *entity.JournalEntry {
+
+	entry := &entity.JournalEntry{TransactionID: transactionID, TransactionType: transactionType, Kind: kind,
+		Currency: currency, Lines: make([]*entity.JournalLine, 0)}
+
+	for _, line := range lines {
+		if line.Debit != 0 || line.Credit != 0 {
+			entry.Lines = append(entry.Lines, line)
+		}
+	}
+
+	return entry
+}
+
+// debit is a function that creates a journal line debiting the given account
+func debit(account, ownerID string, amount money.Amount) *entity.JournalLine {
+	return &entity.JournalLine{Account: account, OwnerID: ownerID, Debit: amount}
+}
+
+// credit is a function that creates a journal line crediting the given account
+func credit(account, ownerID string, amount money.Amount) *entity.JournalLine {
+	return &entity.JournalLine{Account: account, OwnerID: ownerID, Credit: amount}
+}
+
+// adjust is a function that creates a journal line crediting the given account with a positive amount
+// or debiting it with a negative one
+func adjust(account, ownerID string, amount money.Amount) *entity.JournalLine {
+	if amount < 0 {
+		return debit(account, ownerID, amount.Neg())
+	}
+	return credit(account, ownerID, amount)
+}
@@ -0,0 +1,124 @@
+package ledger_test
+
+import (
+	"testing"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/ledger"
+	"github.com/Benyam-S/onemembership/money"
+)
+
+func TestValidate(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		lines []*entity.JournalLine
+		valid bool
+	}{
+		{"empty", nil, true},
+		{"balanced", []*entity.JournalLine{{Account: entity.AccountGateway, Debit: 10000},
+			{Account: entity.AccountPlatformFee, Credit: 200}, {Account: entity.AccountProviderPayable, Credit: 9800}}, true},
+		{"more debits", []*entity.JournalLine{{Account: entity.AccountGateway, Debit: 10000},
+			{Account: entity.AccountProviderPayable, Credit: 9800}}, false},
+		{"more credits", []*entity.JournalLine{{Account: entity.AccountGateway, Debit: 9800},
+			{Account: entity.AccountProviderPayable, Credit: 10000}}, false},
+		{"single line", []*entity.JournalLine{{Account: entity.AccountGateway, Debit: 10000}}, false},
+		{"negative debit", []*entity.JournalLine{{Account: entity.AccountGateway, Debit: -10000},
+			{Account: entity.AccountProviderPayable, Credit: -10000}}, false},
+		{"negative credit", []*entity.JournalLine{{Account: entity.AccountGateway, Debit: 0},
+			{Account: entity.AccountProviderPayable, Credit: -100}, {Account: entity.AccountPlatformFee, Credit: 100}}, false},
+	}
+
+	for _, test := range tests {
+		err := ledger.Validate(&entity.JournalEntry{Lines: test.lines})
+		if test.valid && err != nil {
+			t.Errorf("%s: Validate returned %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: Validate accepted the entry", test.name)
+		}
+	}
+}
+
+func TestEntriesBalance(t *testing.T) {
+
+	subscriptionTransaction := &entity.SubscriptionTransaction{ID: "ST-1", ReceivedAmount: 9800, TransactionFee: 200,
+		CurrencyType: "ETB"}
+	spSubscriptionTransaction := &entity.SPSubscriptionTransaction{ID: "SPT-1", ReceivedAmount: 4900,
+		TransactionFee: 100, CurrencyType: "ETB"}
+	refund := &entity.SubscriptionTransaction{ID: "ST-2", ReceivedAmount: -9800, CurrencyType: "ETB"}
+	payrollTransaction := &entity.SPPayrollTransaction{ID: "SPP-1", ProviderID: "SP-1", PayedAmount: 5000,
+		CurrencyType: "ETB"}
+
+	tests := []struct {
+		name     string
+		entry    *entity.JournalEntry
+		numLines int
+	}{
+		{"subscription payment", ledger.SubscriptionPaymentEntry(subscriptionTransaction, "SP-1"), 3},
+		{"sp subscription payment", ledger.SPSubscriptionPaymentEntry(spSubscriptionTransaction), 3},
+		{"refund", ledger.RefundEntry(refund, "SP-1"), 2},
+		{"refund failure", ledger.RefundFailureEntry(refund, "SP-1"), 2},
+		{"withdrawal", ledger.WithdrawalEntry(payrollTransaction), 2},
+		{"withdrawal rejection", ledger.WithdrawalRejectionEntry(payrollTransaction), 2},
+		{"payout", ledger.PayoutEntry(payrollTransaction), 2},
+		{"free subscription", ledger.SubscriptionPaymentEntry(&entity.SubscriptionTransaction{ID: "ST-3",
+			CurrencyType: "ETB"}, "SP-1"), 0},
+	}
+
+	for _, test := range tests {
+		if err := ledger.Validate(test.entry); err != nil {
+			t.Errorf("%s: Validate returned %v", test.name, err)
+		}
+		if len(test.entry.Lines) != test.numLines {
+			t.Errorf("%s: entry has %d lines, expected %d", test.name, len(test.entry.Lines), test.numLines)
+		}
+	}
+}
+
+func TestOpeningBalanceEntry(t *testing.T) {
+
+	tests := []struct {
+		name            string
+		walletBalance   ledger.WalletBalance
+		expectedRunning money.Amount
+		expectedPending money.Amount
+		numLines        int
+	}{
+		{"unposted wallet", ledger.WalletBalance{WalletRunningAmount: 50000, WalletPendingAmount: 10000},
+			50000, 10000, 3},
+		{"partly posted wallet", ledger.WalletBalance{WalletRunningAmount: 50000, LedgerRunningAmount: 20000},
+			30000, 0, 2},
+		{"overposted wallet", ledger.WalletBalance{WalletRunningAmount: 10000, LedgerRunningAmount: 15000,
+			WalletPendingAmount: 5000}, -5000, 5000, 2},
+		{"reconciled wallet", ledger.WalletBalance{WalletRunningAmount: 10000, LedgerRunningAmount: 10000}, 0, 0, 0},
+	}
+
+	for _, test := range tests {
+		test.walletBalance.ProviderID = "SP-1"
+		test.walletBalance.CurrencyType = "ETB"
+		entry := ledger.OpeningBalanceEntry(&test.walletBalance)
+
+		if err := ledger.Validate(entry); err != nil {
+			t.Errorf("%s: Validate returned %v", test.name, err)
+		}
+		if len(entry.Lines) != test.numLines {
+			t.Errorf("%s: entry has %d lines, expected %d", test.name, len(entry.Lines), test.numLines)
+		}
+
+		var running, pending money.Amount
+		for _, line := range entry.Lines {
+			switch line.Account {
+			case entity.AccountProviderPayable:
+				running = running.Add(line.Credit).Sub(line.Debit)
+			case entity.AccountProviderPendingPayout:
+				pending = pending.Add(line.Credit).Sub(line.Debit)
+			}
+		}
+
+		if running != test.expectedRunning || pending != test.expectedPending {
+			t.Errorf("%s: entry moves %s running and %s pending, expected %s and %s", test.name, running, pending,
+				test.expectedRunning, test.expectedPending)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package ledger
+
+import (
+	"errors"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/money"
+)
+
+// ErrEntryPosted is an error returned when a journal entry of the same kind has already been posted for the transaction
+var ErrEntryPosted = errors.New("journal entry has already been posted")
+
+// IJournalRepository is an interface that defines all the repository methods of a journal entry struct,
+// journal entries are immutable so they can only be posted and read
+type IJournalRepository interface {
+	Post(newEntry *entity.JournalEntry) error
+	Find(id string) (*entity.JournalEntry, error)
+	FindMultiple(transactionID string) []*entity.JournalEntry
+	Balance(account, ownerID, currency string) money.Amount
+	WalletBalances() []*WalletBalance
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/ledger"
+	"github.com/Benyam-S/onemembership/money"
+	"github.com/Benyam-S/onemembership/tools"
+	"github.com/jinzhu/gorm"
+)
+
+// JournalRepository is a type that defines a journal entry repository type
+type JournalRepository struct {
+	conn *gorm.DB
+}
+
+// NewJournalRepository is a function that creates a new journal entry repository type,
+// passing a database transaction as the connection posts the entries as part of that transaction
+func NewJournalRepository(connection *gorm.DB) ledger.IJournalRepository {
+	return &JournalRepository{conn: connection}
+}
+
+// Post is a method that adds a new journal entry together with its lines to the database.
+// An entry without any line is ignored since no money has been moved, and unbalanced entries are rejected.
+func (repo *JournalRepository) Post(newEntry *entity.JournalEntry) error {
+
+	if len(newEntry.Lines) == 0 {
+		return nil
+	}
+
+	err := ledger.Validate(newEntry)
+	if err != nil {
+		return err
+	}
+
+	var count int64
+	repo.conn.Model(entity.JournalEntry{}).Where("transaction_id = ? && kind = ?", newEntry.TransactionID,
+		newEntry.Kind).Count(&count)
+	if count > 0 {
+		return ledger.ErrEntryPosted
+	}
+
+	totalNumOfEntries := tools.CountMembers("journal_entries", repo.conn)
+	newEntry.ID = fmt.Sprintf("JE-%s%d", tools.RandomStringGN(10), totalNumOfEntries+1)
+
+	for !tools.IsUnique("id", newEntry.ID, "journal_entries", repo.conn) {
+		totalNumOfEntries++
+		newEntry.ID = fmt.Sprintf("JE-%s%d", tools.RandomStringGN(10), totalNumOfEntries+1)
+	}
+
+	for _, line := range newEntry.Lines {
+		line.EntryID = newEntry.ID
+	}
+
+	err = repo.conn.Create(newEntry).Error
+	if tools.IsDuplicateKey(err) {
+		return ledger.ErrEntryPosted
+	}
+
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Find is a method that finds a certain journal entry together with its lines from the database using an entry id,
+// also Find() uses only id as a key for selection
+func (repo *JournalRepository) Find(id string) (*entity.JournalEntry, error) {
+
+	entry := new(entity.JournalEntry)
+	err := repo.conn.Model(entry).Preload("Lines").Where("id = ?", id).First(entry).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// FindMultiple is a method that finds the journal entries posted for a certain transaction in the order they were posted
+// In FindMultiple() transaction_id is only used as a key
+func (repo *JournalRepository) FindMultiple(transactionID string) []*entity.JournalEntry {
+
+	var entries []*entity.JournalEntry
+	err := repo.conn.Model(entity.JournalEntry{}).Preload("Lines").Where("transaction_id = ?", transactionID).
+		Order("created_at ASC").Find(&entries).Error
+
+	if err != nil {
+		return []*entity.JournalEntry{}
+	}
+	return entries
+}
+
+// Balance is a method that derives the balance an account holds in a currency from its journal lines,
+// the balance is credit based so asset accounts such as the gateway account hold a negative balance
+func (repo *JournalRepository) Balance(account, ownerID, currency string) money.Amount {
+
+	var balance struct{ Total money.Amount }
+	repo.conn.Raw("SELECT COALESCE(SUM(L.credit - L.debit), 0) AS total FROM journal_lines L "+
+		"INNER JOIN journal_entries E ON E.id = L.entry_id WHERE L.account = ? && L.owner_id = ? && E.currency = ?",
+		account, ownerID, currency).Scan(&balance)
+
+	return balance.Total
+}
+
+// WalletBalances is a method that returns the stored figures of every currency balance of the service provider wallets
+// next to the balances derived from the ledger
+func (repo *JournalRepository) WalletBalances() []*ledger.WalletBalance {
+
+	var walletBalances []*ledger.WalletBalance
+	err := repo.conn.Raw("SELECT W.provider_id, W.currency_type, W.running_amount AS wallet_running_amount, "+
+		"W.pending_amount AS wallet_pending_amount, "+
+		"COALESCE(SUM(CASE WHEN L.account = ? THEN L.credit - L.debit ELSE 0 END), 0) AS ledger_running_amount, "+
+		"COALESCE(SUM(CASE WHEN L.account = ? THEN L.credit - L.debit ELSE 0 END), 0) AS ledger_pending_amount "+
+		"FROM sp_wallet_balances W LEFT JOIN (journal_lines L INNER JOIN journal_entries E ON E.id = L.entry_id) "+
+		"ON L.owner_id = W.provider_id && E.currency = W.currency_type "+
+		"GROUP BY W.provider_id, W.currency_type, W.running_amount, W.pending_amount",
+		entity.AccountProviderPayable, entity.AccountProviderPendingPayout).Scan(&walletBalances).Error
+
+	if err != nil {
+		return []*ledger.WalletBalance{}
+	}
+	return walletBalances
+}
@@ -0,0 +1,28 @@
+package ledger
+
+import (
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/money"
+)
+
+// WalletBalance is a type that defines the stored wallet figures of a service provider in a single currency
+// next to the ones derived from the ledger
+type WalletBalance struct {
+	ProviderID          string
+	CurrencyType        string
+	WalletRunningAmount money.Amount
+	LedgerRunningAmount money.Amount
+	WalletPendingAmount money.Amount
+	LedgerPendingAmount money.Amount
+}
+
+// IService is an interface that defines all the service methods of a ledger
+type IService interface {
+	PostJournalEntry(newEntry *entity.JournalEntry) error
+	FindJournalEntry(id string) (*entity.JournalEntry, error)
+	FindMultipleJournalEntries(transactionID string) []*entity.JournalEntry
+	AccountBalance(account, ownerID, currency string) money.Amount
+	ProviderBalance(providerID, currency string) (money.Amount, money.Amount)
+	Reconcile() []*WalletBalance
+	BackfillOpeningBalances() error
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/ledger"
+	"github.com/Benyam-S/onemembership/log"
+	"github.com/Benyam-S/onemembership/money"
+)
+
+// Service is a type that defines a ledger service
+type Service struct {
+	journalRepo ledger.IJournalRepository
+	logger      *log.Logger
+}
+
+// NewLedgerService is a function that returns a new ledger service
+func NewLedgerService(journalRepository ledger.IJournalRepository, transactionLogger *log.Logger) ledger.IService {
+	return &Service{journalRepo: journalRepository, logger: transactionLogger}
+}
+
+// PostJournalEntry is a method that adds a new journal entry to the ledger
+func (service *Service) PostJournalEntry(newEntry *entity.JournalEntry) error {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started journal entry posting process, Journal Entry => %s",
+		newEntry.ToString()), service.logger.Logs.TransactionLogFile)
+
+	err := service.journalRepo.Post(newEntry)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For posting Journal Entry => %s, %s",
+			newEntry.ToString(), err.Error()))
+
+		return errors.New("unable to post journal entry")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished journal entry posting process, Journal Entry => %s",
+		newEntry.ToString()), service.logger.Logs.TransactionLogFile)
+
+	return nil
+}
+
+// FindJournalEntry is a method that find and return a journal entry that matches the id value
+func (service *Service) FindJournalEntry(id string) (*entity.JournalEntry, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Single journal entry finding process { Journal Entry ID : %s }", id),
+		service.logger.Logs.TransactionLogFile)
+
+	empty, _ := regexp.MatchString(`^\s*$`, id)
+	if empty {
+		return nil, errors.New("no journal entry found")
+	}
+
+	entry, err := service.journalRepo.Find(id)
+	if err != nil {
+		return nil, errors.New("no journal entry found")
+	}
+	return entry, nil
+}
+
+// FindMultipleJournalEntries is a method that find and return the journal entries posted for the given transaction
+func (service *Service) FindMultipleJournalEntries(transactionID string) []*entity.JournalEntry {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Multiple journal entries finding process { Transaction ID : %s }", transactionID),
+		service.logger.Logs.TransactionLogFile)
+
+	empty, _ := regexp.MatchString(`^\s*$`, transactionID)
+	if empty {
+		return []*entity.JournalEntry{}
+	}
+
+	return service.journalRepo.FindMultiple(transactionID)
+}
+
+// AccountBalance is a method that returns the credit based balance an account holds in a currency derived from the ledger
+func (service *Service) AccountBalance(account, ownerID, currency string) money.Amount {
+	return service.journalRepo.Balance(account, ownerID, currency)
+}
+
+// ProviderBalance is a method that returns the running and pending amounts a service provider holds in a currency
+// derived from the ledger
+func (service *Service) ProviderBalance(providerID, currency string) (money.Amount, money.Amount) {
+	return service.journalRepo.Balance(entity.AccountProviderPayable, providerID, currency),
+		service.journalRepo.Balance(entity.AccountProviderPendingPayout, providerID, currency)
+}
+
+// Reconcile is a method that compares every currency balance of the service provider wallets with the ledger
+// and returns the wallet balances whose stored figures have drifted from the derived balances
+func (service *Service) Reconcile() []*ledger.WalletBalance {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log("Started ledger reconciliation process", service.logger.Logs.TransactionLogFile)
+
+	drifted := make([]*ledger.WalletBalance, 0)
+	for _, walletBalance := range service.journalRepo.WalletBalances() {
+		if walletBalance.WalletRunningAmount != walletBalance.LedgerRunningAmount ||
+			walletBalance.WalletPendingAmount != walletBalance.LedgerPendingAmount {
+
+			/* ---------------------------- Logging ---------------------------- */
+			service.logger.LogToErrorFile(fmt.Sprintf("Error: For reconciling wallet { Provider ID : %s, Currency : %s }, "+
+				"running amount %s against ledger %s, pending amount %s against ledger %s",
+				walletBalance.ProviderID, walletBalance.CurrencyType, walletBalance.WalletRunningAmount, walletBalance.LedgerRunningAmount,
+				walletBalance.WalletPendingAmount, walletBalance.LedgerPendingAmount))
+
+			drifted = append(drifted, walletBalance)
+		}
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished ledger reconciliation process { Drifted Wallets : %d }", len(drifted)),
+		service.logger.Logs.TransactionLogFile)
+
+	return drifted
+}
+
+// BackfillOpeningBalances is a method that posts an opening balance entry for every currency balance of the service
+// provider wallets, so the balances accumulated before the ledger was introduced are accounted for.
+// A wallet balance gets a single opening balance entry, so running the backfill again leaves the posted ones as they are.
+func (service *Service) BackfillOpeningBalances() error {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log("Started opening balance backfill process", service.logger.Logs.TransactionLogFile)
+
+	numOfPosted := 0
+	for _, walletBalance := range service.journalRepo.WalletBalances() {
+
+		openingEntry := ledger.OpeningBalanceEntry(walletBalance)
+		if len(openingEntry.Lines) == 0 {
+			continue
+		}
+
+		err := service.journalRepo.Post(openingEntry)
+		if err == ledger.ErrEntryPosted {
+			continue
+		}
+
+		if err != nil {
+			/* ---------------------------- Logging ---------------------------- */
+			service.logger.LogToErrorFile(fmt.Sprintf("Error: For posting opening balance { Provider ID : %s, "+
+				"Currency : %s }, %s", walletBalance.ProviderID, walletBalance.CurrencyType, err.Error()))
+
+			return errors.New("unable to post opening balance")
+		}
+
+		numOfPosted++
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished opening balance backfill process { Posted Entries : %d }", numOfPosted),
+		service.logger.Logs.TransactionLogFile)
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/ledger"
+	"github.com/Benyam-S/onemembership/ledger/service"
+	"github.com/Benyam-S/onemembership/log"
+	"github.com/Benyam-S/onemembership/money"
+)
+
+// journalRepository is a type that defines an in-memory journal repository that rejects the entries
+// the database would reject, the methods the tests don't reach are left to the embedded interface
+type journalRepository struct {
+	ledger.IJournalRepository
+	entries        []*entity.JournalEntry
+	walletBalances []*ledger.WalletBalance
+}
+
+// Post is a method that stores a valid journal entry once per transaction and kind
+func (repo *journalRepository) Post(newEntry *entity.JournalEntry) error {
+
+	if len(newEntry.Lines) == 0 {
+		return nil
+	}
+
+	if err := ledger.Validate(newEntry); err != nil {
+		return err
+	}
+
+	for _, entry := range repo.entries {
+		if entry.TransactionID == newEntry.TransactionID && entry.Kind == newEntry.Kind {
+			return ledger.ErrEntryPosted
+		}
+	}
+
+	repo.entries = append(repo.entries, newEntry)
+	return nil
+}
+
+// WalletBalances is a method that returns the stored wallet balances
+func (repo *journalRepository) WalletBalances() []*ledger.WalletBalance {
+	return repo.walletBalances
+}
+
+func TestPostJournalEntry(t *testing.T) {
+
+	repo := &journalRepository{}
+	ledgerService := service.NewLedgerService(repo, log.NewLogger(&log.LogContainer{}, log.None))
+
+	payment := &entity.SubscriptionTransaction{ID: "ST-1", ReceivedAmount: 9800, TransactionFee: 200, CurrencyType: "ETB"}
+	unbalanced := &entity.JournalEntry{TransactionID: "ST-2", Kind: entity.JournalSubscriptionPayment,
+		Lines: []*entity.JournalLine{{Account: entity.AccountGateway, Debit: 10000},
+			{Account: entity.AccountProviderPayable, OwnerID: "SP-1", Credit: 9800}}}
+
+	tests := []struct {
+		name   string
+		entry  *entity.JournalEntry
+		posted bool
+	}{
+		{"payment", ledger.SubscriptionPaymentEntry(payment, "SP-1"), true},
+		{"duplicate payment", ledger.SubscriptionPaymentEntry(payment, "SP-1"), false},
+		{"refund of the payment", ledger.RefundEntry(&entity.SubscriptionTransaction{ID: "ST-1",
+			ReceivedAmount: -9800, CurrencyType: "ETB"}, "SP-1"), true},
+		{"unbalanced entry", unbalanced, false},
+	}
+
+	for _, test := range tests {
+		numOfEntries := len(repo.entries)
+		err := ledgerService.PostJournalEntry(test.entry)
+		if test.posted && err != nil {
+			t.Errorf("%s: PostJournalEntry returned %v", test.name, err)
+		}
+		if !test.posted && err == nil {
+			t.Errorf("%s: PostJournalEntry accepted the entry", test.name)
+		}
+
+		expectedNumOfEntries := numOfEntries
+		if test.posted {
+			expectedNumOfEntries++
+		}
+		if len(repo.entries) != expectedNumOfEntries {
+			t.Errorf("%s: ledger holds %d entries, expected %d", test.name, len(repo.entries), expectedNumOfEntries)
+		}
+	}
+}
+
+func TestBackfillOpeningBalances(t *testing.T) {
+
+	repo := &journalRepository{walletBalances: []*ledger.WalletBalance{
+		{ProviderID: "SP-1", CurrencyType: "ETB", WalletRunningAmount: 50000, WalletPendingAmount: 10000},
+		{ProviderID: "SP-1", CurrencyType: "USD", WalletRunningAmount: 2000, LedgerRunningAmount: 2000},
+		{ProviderID: "SP-2", CurrencyType: "ETB", WalletRunningAmount: 30000, LedgerRunningAmount: 10000},
+	}}
+	ledgerService := service.NewLedgerService(repo, log.NewLogger(&log.LogContainer{}, log.None))
+
+	for run := 1; run <= 2; run++ {
+		if err := ledgerService.BackfillOpeningBalances(); err != nil {
+			t.Fatalf("run %d: BackfillOpeningBalances returned %v", run, err)
+		}
+
+		if len(repo.entries) != 2 {
+			t.Fatalf("run %d: ledger holds %d opening balance entries, expected 2", run, len(repo.entries))
+		}
+	}
+
+	expected := map[string]money.Amount{"SP-1:ETB": 60000, "SP-2:ETB": 20000}
+	for _, entry := range repo.entries {
+		var opened money.Amount
+		for _, line := range entry.Lines {
+			if line.Account == entity.AccountOpeningBalance {
+				opened = opened.Add(line.Debit)
+			}
+		}
+
+		if entry.Kind != entity.JournalOpeningBalance || opened != expected[entry.TransactionID] {
+			t.Errorf("unexpected opening balance entry %s opening %s", entry.TransactionID, opened)
+		}
+	}
+}
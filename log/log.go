@@ -1,5 +1,11 @@
 package log
 
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
 // Debug is a constant that indicates the logger is in debug mode
 const Debug = "Debug"
 
@@ -9,6 +15,49 @@ const Normal = "Normal"
 // None is a constant that indicates the logger isn't logging
 const None = "None"
 
+// Level is a type that defines the severity of a log entry
+type Level int
+
+// LevelDebug is a constant that defines the level of entries that are only useful while debugging
+const LevelDebug Level = -1
+
+// LevelInfo is a constant that defines the level of entries that record the normal flow of the system
+const LevelInfo Level = 0
+
+// LevelWarning is a constant that defines the level of entries that record unexpected but handled situations
+const LevelWarning Level = 1
+
+// LevelError is a constant that defines the level of entries that record failures
+const LevelError Level = 2
+
+// String is a method that returns the name of the level as written in the log entries
+func (level Level) String() string {
+	switch level {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarning:
+		return "warning"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("level(%d)", int(level))
+}
+
+// ParseLevel is a function that returns the level that matches the given name
+func ParseLevel(name string) (Level, error) {
+	for _, level := range []Level{LevelDebug, LevelInfo, LevelWarning, LevelError} {
+		if strings.EqualFold(level.String(), name) {
+			return level, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %s", name)
+}
+
+// Fields is a type that defines the key value pairs that are added to a structured log entry
+type Fields map[string]interface{}
+
 // ILogger is an interface that defines the logging style
 type ILogger interface {
 	SetFlag(state string)
@@ -18,6 +67,15 @@ type ILogger interface {
 	LogToArchiveFile(stmt string)
 }
 
+// IStructuredLogger is an interface that defines a logger with levels and key value fields
+type IStructuredLogger interface {
+	ILogger
+	SetLevel(level Level)
+	LogWithFields(level Level, stmt, logFile string, fields Fields)
+	Reopen() error
+	Close() error
+}
+
 // LogContainer is a type that defines all the available logs
 type LogContainer struct {
 	UserLogFile             string
@@ -32,3 +90,29 @@ type LogContainer struct {
 	ErrorLogFile            string
 	ArchiveLogFile          string
 }
+
+// NewLogContainer is a function that returns a new log container from the logs path and the log file names
+// of the system config, the names are keyed as in the config such as user_log_file. Missing names are left empty
+func NewLogContainer(logsPath string, logs map[string]string) *LogContainer {
+	return &LogContainer{
+		UserLogFile:             logPath(logsPath, logs["user_log_file"]),
+		ServiceProviderLogFile:  logPath(logsPath, logs["service_provider_log_file"]),
+		ProjectLogFile:          logPath(logsPath, logs["project_log_file"]),
+		SubscriptionPlanLogFile: logPath(logsPath, logs["subscription_plan_log_file"]),
+		SubscriptionLogFile:     logPath(logsPath, logs["subscription_log_file"]),
+		TransactionLogFile:      logPath(logsPath, logs["transaction_log_file"]),
+		DeletedLogFile:          logPath(logsPath, logs["deleted_log_file"]),
+		ServerLogFile:           logPath(logsPath, logs["server_log_file"]),
+		BotLogFile:              logPath(logsPath, logs["bot_log_file"]),
+		ErrorLogFile:            logPath(logsPath, logs["error_log_file"]),
+		ArchiveLogFile:          logPath(logsPath, logs["archive_log_file"]),
+	}
+}
+
+// logPath is a function that joins the logs path with a log file name, an empty name is kept empty
+func logPath(logsPath, name string) string {
+	if name == "" {
+		return ""
+	}
+	return filepath.Join(logsPath, name)
+}
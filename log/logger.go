@@ -1,22 +1,43 @@
 package log
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/Benyam-S/onemembership/redact"
 )
 
-// Logger is a type that defines the logger
+// reopenCheckInterval is the interval the logger checks whether an open log file has been rotated
+const reopenCheckInterval = time.Second
+
+// logHandle is a type that defines an open log file along with the last time it has been checked for rotation
+type logHandle struct {
+	file      *os.File
+	checkedAt time.Time
+}
+
+// Logger is a type that defines the logger, it writes every entry as a single json line
+// to the log file of the entry and keeps the log files open for the lifetime of the logger
 type Logger struct {
-	mu   sync.Mutex
-	Logs *LogContainer
-	flag string // Can define that state of the logger wheather to log or not
+	mu     sync.Mutex
+	Logs   *LogContainer
+	flag   string // Can define that state of the logger wheather to log or not
+	level  Level  // The minimum level of the entries that will be logged
+	files  map[string]*logHandle
+	parent io.Writer // The output the entries are written to in debug mode
 }
 
 // NewLogger is a function that returns a new logger
 func NewLogger(logContainer *LogContainer, flag string) *Logger {
-	logger := &Logger{Logs: logContainer}
+	logger := &Logger{Logs: logContainer, level: LevelInfo, files: make(map[string]*logHandle), parent: os.Stdout}
 	logger.SetFlag(flag)
 	return logger
 }
@@ -27,110 +48,223 @@ func (l *Logger) SetFlag(state string) {
 		state = None
 	}
 
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	l.flag = state
 }
 
+// SetLevel is a method that sets the minimum level of the entries that will be logged
+func (l *Logger) SetLevel(level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.level = level
+}
+
 // Log is a method that will log the given statement to the selected log file
 func (l *Logger) Log(stmt, logFile string) {
+	l.LogWithFields(LevelInfo, stmt, logFile, nil)
+}
+
+// LogToParent is a method that will log the given statement to the program starter
+func (l *Logger) LogToParent(stmt string) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
 	// Checking the status of the logger
 	if l.flag == None {
 		return
-	} else if l.flag == Debug {
-		l.LogToParent(stmt)
-		return
 	}
 
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.parent.Write(encodeEntry(time.Now(), LevelInfo, "parent", redact.Text(stmt), nil))
+}
 
-	var isValidLogFile bool
+// LogToErrorFile is a method that will log the given statement as an error to the error log file
+func (l *Logger) LogToErrorFile(stmt string) {
+	l.LogWithFields(LevelError, stmt, l.Logs.ErrorLogFile, nil)
+}
 
-	// Checking the validity of the given log file
-	validLogFiles := []string{l.Logs.ServerLogFile, l.Logs.BotLogFile, l.Logs.ErrorLogFile}
+// LogToArchiveFile is a method that will log the given statement to archive log file
+func (l *Logger) LogToArchiveFile(stmt string) {
+	l.LogWithFields(LevelInfo, stmt, l.Logs.ArchiveLogFile, nil)
+}
 
-	for _, validLogFile := range validLogFiles {
-		if validLogFile == logFile {
-			isValidLogFile = true
-		}
+// LogWithFields is a method that will log the given statement at the given level to the selected log file,
+// the fields are added to the entry as key value pairs. Sensitive values in the statement and the fields are redacted
+// and log files that aren't part of the log container are redirected to the server log file
+func (l *Logger) LogWithFields(level Level, stmt, logFile string, fields Fields) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Checking the status of the logger
+	if l.flag == None || (level < l.level && l.flag != Debug) {
+		return
 	}
 
-	if !isValidLogFile {
+	if !l.Logs.contains(logFile) {
 		logFile = l.Logs.ServerLogFile
 	}
 
-	file, err := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0644)
-	if err == nil {
-		defer file.Close()
+	// Redacting before choosing the output so that secrets don't reach the console in debug mode either
+	entry := encodeEntry(time.Now(), level, logName(logFile), redact.Text(stmt), redact.Fields(fields))
 
-		stmt = fmt.Sprintf("[ %s ] %s", time.Now(), stmt)
-
-		fmt.Fprintln(file, stmt)
+	if l.flag == Debug {
+		l.parent.Write(entry)
+		return
+	}
 
+	file, err := l.open(logFile)
+	if err != nil {
+		// The entry shouldn't be lost because of the log file, so writing it to the standard error
+		fmt.Fprintf(os.Stderr, "unable to open log file %s, %s\n", logFile, err.Error())
+		os.Stderr.Write(entry)
+		return
 	}
+
+	file.Write(entry)
 }
 
-// LogToParent is a method that will log the given statement to the program starter
-func (l *Logger) LogToParent(stmt string) {
+// Reopen is a method that closes all the open log files so that they will be opened again on the next entry,
+// it should be called after the log files have been moved or replaced
+func (l *Logger) Reopen() error {
+	return l.Close()
+}
 
-	// Checking the status of the logger
-	if l.flag == None {
-		return
-	}
+// Close is a method that closes all the log files that have been opened by the logger
+func (l *Logger) Close() error {
 
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	stmt = fmt.Sprintf("[ %s ] %s", time.Now(), stmt)
-	fmt.Println(stmt)
+	var closeErr error
+	for logFile, handle := range l.files {
+		if err := handle.file.Close(); err != nil && closeErr == nil {
+			closeErr = err
+		}
+		delete(l.files, logFile)
+	}
+
+	return closeErr
 }
 
-// LogToErrorFile is a method that will log the given statement as an error to the error log file
-func (l *Logger) LogToErrorFile(stmt string) {
+// open is a method that returns the open handle of a log file, opening the log file if needed.
+// Log files that have been renamed by a rotation, possibly from another process, are opened again.
+// It should only be called while holding the logger's lock
+func (l *Logger) open(logFile string) (*os.File, error) {
 
-	// Checking the status of the logger
-	if l.flag == None {
-		return
-	} else if l.flag == Debug {
-		l.LogToParent(stmt)
-		return
+	if handle, ok := l.files[logFile]; ok {
+		if time.Since(handle.checkedAt) < reopenCheckInterval {
+			return handle.file, nil
+		}
+
+		handle.checkedAt = time.Now()
+		openInfo, err := handle.file.Stat()
+		pathInfo, pathErr := os.Stat(logFile)
+		if err == nil && pathErr == nil && os.SameFile(openInfo, pathInfo) {
+			return handle.file, nil
+		}
+
+		handle.file.Close()
+		delete(l.files, logFile)
 	}
 
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	file, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
 
-	file, err := os.OpenFile(l.Logs.ErrorLogFile, os.O_APPEND|os.O_WRONLY, 0644)
-	if err == nil {
-		defer file.Close()
+	l.files[logFile] = &logHandle{file: file, checkedAt: time.Now()}
+	return file, nil
+}
 
-		stmt = fmt.Sprintf("[ %s ] Error: %s", time.Now(), stmt)
+// contains is a method that checks whether the given log file is one of the container's log files
+func (logs *LogContainer) contains(logFile string) bool {
 
-		fmt.Fprintln(file, stmt)
+	if logFile == "" {
+		return false
+	}
 
+	for _, containedLogFile := range logs.All() {
+		if containedLogFile == logFile {
+			return true
+		}
 	}
+
+	return false
 }
 
-// LogToArchiveFile is a method that will log the given statement to archive log file
-func (l *Logger) LogToArchiveFile(stmt string) {
+// All is a method that returns all the log files of the container
+func (logs *LogContainer) All() []string {
+	return []string{logs.UserLogFile, logs.ServiceProviderLogFile, logs.ProjectLogFile,
+		logs.SubscriptionPlanLogFile, logs.SubscriptionLogFile, logs.TransactionLogFile, logs.DeletedLogFile,
+		logs.ServerLogFile, logs.BotLogFile, logs.ErrorLogFile, logs.ArchiveLogFile}
+}
 
-	// Checking the status of the logger
-	if l.flag == None {
-		return
-	} else if l.flag == Debug {
-		l.LogToParent(stmt)
-		return
+// logName is a function that returns the name of a log file without its directory and extension
+func logName(logFile string) string {
+	name := filepath.Base(logFile)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// encodeEntry is a function that encodes a log entry as a single json line,
+// the fields are added after the standard keys in a sorted order so that the lines stay comparable
+func encodeEntry(moment time.Time, level Level, name, stmt string, fields Fields) []byte {
+
+	var buffer bytes.Buffer
+	buffer.WriteString(`{"time":`)
+	writeJSON(&buffer, moment.Format(time.RFC3339Nano))
+	buffer.WriteString(`,"level":`)
+	writeJSON(&buffer, level.String())
+	buffer.WriteString(`,"log":`)
+	writeJSON(&buffer, name)
+	buffer.WriteString(`,"msg":`)
+	writeJSON(&buffer, stmt)
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	for _, key := range keys {
+
+		// Fields can't override the standard keys, so prefixing the clashing ones
+		name := key
+		switch key {
+		case "time", "level", "log", "msg":
+			name = "fields." + key
+		}
+
+		buffer.WriteByte(',')
+		writeJSON(&buffer, name)
+		buffer.WriteByte(':')
+		writeJSON(&buffer, fields[key])
+	}
 
-	file, err := os.OpenFile(l.Logs.ArchiveLogFile, os.O_APPEND|os.O_WRONLY, 0644)
-	if err == nil {
-		defer file.Close()
+	buffer.WriteString("}\n")
+	return buffer.Bytes()
+}
 
-		stmt = fmt.Sprintf("[ %s ] %s", time.Now(), stmt)
+// writeJSON is a function that writes the json encoding of a value to the buffer,
+// values that can't be encoded are written as their string format
+func writeJSON(buffer *bytes.Buffer, value interface{}) {
 
-		fmt.Fprintln(file, stmt)
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
 
+	// Not escaping html characters so that statements such as "Subscription => {...}" stay readable
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(value); err != nil {
+		encoded.Reset()
+		encoder.Encode(fmt.Sprintf("%+v", value))
 	}
+
+	buffer.Write(bytes.TrimSuffix(encoded.Bytes(), []byte("\n")))
 }
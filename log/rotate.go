@@ -0,0 +1,298 @@
+package log
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ArchiveTimeLayout is the layout of the timestamp that is added to the name of archived log files
+const ArchiveTimeLayout = "20060102150405"
+
+// rotationGrace is the time a rotated log file is left in place before being compressed,
+// so that loggers of other processes can notice the rotation and finish their pending writes
+const rotationGrace = 2 * reopenCheckInterval
+
+// RotationPolicy is a type that defines when log files are rotated and how long their archives are kept,
+// zero values disable the matching rule
+type RotationPolicy struct {
+	MaxSize       int64         // Rotates the log file when it reaches the given size in bytes
+	MaxAge        time.Duration // Rotates the log file when its first entry is older than the given duration
+	MaxArchives   int           // Keeps only the given number of the latest archives of a log file
+	MaxArchiveAge time.Duration // Removes the archives of a log file that are older than the given duration
+}
+
+// Archive is a type that defines an archived log file
+type Archive struct {
+	Path       string
+	LogName    string    // The base name of the log file the archive has been created from
+	ArchivedAt time.Time // The time the log file has been archived
+	Compressed bool      // Tells whether the archive is gzip compressed or a tar archive of the former archiver
+}
+
+// Rotate is a method that rotates the log files of the logger that are due according to the policy,
+// the logger's handles are closed right away so the following entries are written to the new log files
+func (l *Logger) Rotate(archivesPath string, policy RotationPolicy) []error {
+
+	var errs []error
+	for _, logFile := range l.Logs.All() {
+
+		if logFile == "" {
+			continue
+		}
+
+		archive, err := RotateFile(logFile, archivesPath, policy, l.Reopen)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if archive != "" {
+			l.Log(fmt.Sprintf("Rotated log file { Log File : %s, Archive : %s }", logFile, archive),
+				l.Logs.ArchiveLogFile)
+		}
+	}
+
+	return errs
+}
+
+// RotateFile is a function that rotates a log file if it is due according to the policy and applies the retention
+// of its archives. The log file is renamed atomically, so that writers keep appending to the renamed file until they
+// reopen the log file, and is then streamed to a gzip compressed archive. The path of the archive is returned if the
+// log file has been rotated, reopen is called right after renaming the log file and may be nil.
+func RotateFile(logFile, archivesPath string, policy RotationPolicy, reopen func() error) (string, error) {
+
+	due, err := isRotationDue(logFile, policy)
+	if err != nil || !due {
+		return "", err
+	}
+
+	if err := os.MkdirAll(archivesPath, 0755); err != nil {
+		return "", err
+	}
+
+	moment := time.Now()
+	rotatedFile := fmt.Sprintf("%s_%s", logFile, moment.Format(ArchiveTimeLayout))
+	for index := 1; fileExists(rotatedFile); index++ {
+		rotatedFile = fmt.Sprintf("%s_%s.%d", logFile, moment.Format(ArchiveTimeLayout), index)
+	}
+
+	if err := os.Rename(logFile, rotatedFile); err != nil {
+		return "", err
+	}
+
+	// Creating the new log file right away so that readers always find a live log file
+	if file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+		file.Close()
+	}
+
+	if reopen != nil {
+		reopen()
+	}
+
+	time.Sleep(rotationGrace)
+
+	archive := filepath.Join(archivesPath, filepath.Base(rotatedFile)+".gz")
+	if err := compressFile(rotatedFile, archive); err != nil {
+		return "", err
+	}
+
+	if err := os.Remove(rotatedFile); err != nil {
+		return archive, err
+	}
+
+	return archive, ApplyRetention(logFile, archivesPath, policy)
+}
+
+// ApplyRetention is a function that removes the archives of a log file that aren't retained by the policy
+func ApplyRetention(logFile, archivesPath string, policy RotationPolicy) error {
+
+	archives, err := FindArchives(archivesPath, filepath.Base(logFile))
+	if err != nil {
+		return err
+	}
+
+	var removeErr error
+	for index := range archives {
+
+		// Archives are ordered from the oldest, so the latest ones are at the end
+		archive := archives[index]
+		expired := policy.MaxArchiveAge > 0 && time.Since(archive.ArchivedAt) > policy.MaxArchiveAge
+		exceeded := policy.MaxArchives > 0 && len(archives)-index > policy.MaxArchives
+
+		if expired || exceeded {
+			if err := os.Remove(archive.Path); err != nil && removeErr == nil {
+				removeErr = err
+			}
+		}
+	}
+
+	return removeErr
+}
+
+// FindArchives is a function that returns the archives of a log file found in the archives path ordered
+// from the oldest, an empty log name returns the archives of every log file
+func FindArchives(archivesPath, logName string) ([]*Archive, error) {
+
+	entries, err := os.ReadDir(archivesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var archives []*Archive
+	for _, entry := range entries {
+
+		if entry.IsDir() {
+			continue
+		}
+
+		archive := parseArchiveName(entry.Name())
+		if archive == nil || (logName != "" && archive.LogName != logName) {
+			continue
+		}
+
+		archive.Path = filepath.Join(archivesPath, entry.Name())
+		archives = append(archives, archive)
+	}
+
+	sort.SliceStable(archives, func(i, j int) bool {
+		return archives[i].ArchivedAt.Before(archives[j].ArchivedAt)
+	})
+
+	return archives, nil
+}
+
+// parseArchiveName is a function that reads the log name and the archiving time from the name of an archive,
+// both the gzip archives and the tar archives of the former archiver are named as <log name>_<timestamp>
+func parseArchiveName(name string) *Archive {
+
+	archive := new(Archive)
+	switch {
+	case strings.HasSuffix(name, ".gz"):
+		archive.Compressed = true
+		name = strings.TrimSuffix(name, ".gz")
+	case strings.HasSuffix(name, ".tar"):
+		name = strings.TrimSuffix(name, ".tar")
+	default:
+		return nil
+	}
+
+	// Removing the index added to archives that have been rotated within the same second
+	if dot := strings.LastIndex(name, "."); dot > strings.LastIndex(name, "_") {
+		name = name[:dot]
+	}
+
+	separator := strings.LastIndex(name, "_")
+	if separator <= 0 {
+		return nil
+	}
+
+	archivedAt, err := time.ParseInLocation(ArchiveTimeLayout, name[separator+1:], time.Local)
+	if err != nil {
+		return nil
+	}
+
+	archive.LogName = name[:separator]
+	archive.ArchivedAt = archivedAt
+	return archive
+}
+
+// isRotationDue is a function that checks whether a log file should be rotated according to the policy,
+// empty and missing log files are never rotated
+func isRotationDue(logFile string, policy RotationPolicy) (bool, error) {
+
+	status, err := os.Stat(logFile)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if status.Size() == 0 {
+		return false, nil
+	}
+
+	if policy.MaxSize > 0 && status.Size() >= policy.MaxSize {
+		return true, nil
+	}
+
+	if policy.MaxAge > 0 {
+		firstEntryAt, ok := readFirstEntryTime(logFile)
+		return ok && time.Since(firstEntryAt) >= policy.MaxAge, nil
+	}
+
+	return false, nil
+}
+
+// readFirstEntryTime is a function that reads the time of the first entry of a log file,
+// false is returned if the first line isn't a structured log entry
+func readFirstEntryTime(logFile string) (time.Time, bool) {
+
+	file, err := os.Open(logFile)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer file.Close()
+
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return time.Time{}, false
+	}
+
+	var entry struct {
+		Time time.Time `json:"time"`
+	}
+	if err := json.Unmarshal(line, &entry); err != nil || entry.Time.IsZero() {
+		return time.Time{}, false
+	}
+
+	return entry.Time, true
+}
+
+// compressFile is a function that streams a file into a new gzip compressed file,
+// the compressed file is written under a temporary name and renamed once it is complete
+func compressFile(source, destination string) error {
+
+	sourceFile, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+
+	temporary := destination + ".tmp"
+	destinationFile, err := os.Create(temporary)
+	if err != nil {
+		return err
+	}
+
+	writer := gzip.NewWriter(destinationFile)
+	writer.Name = filepath.Base(source)
+
+	_, err = io.Copy(writer, sourceFile)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := destinationFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(temporary)
+		return err
+	}
+
+	return os.Rename(temporary, destination)
+}
+
+// fileExists is a function that checks whether a file exists in the given path
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,325 @@
+package log
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxLineSize is the size of the longest log line that can be searched
+const maxLineSize = 10 * 1024 * 1024
+
+// legacyTimeLayout is the layout of the time written at the start of the lines of the former text logger
+const legacyTimeLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+// SearchQuery is a type that defines the lines a log search looks for, zero values match every line
+type SearchQuery struct {
+	Pattern  *regexp.Regexp
+	From     time.Time // Inclusive lower bound of the entry time
+	To       time.Time // Exclusive upper bound of the entry time
+	LogNames []string  // The base names of the log files to search, such as server.log
+}
+
+// Match is a type that defines a log line that has matched a search
+type Match struct {
+	LogName string
+	Source  string // The live log file or the archive the line has been read from
+	Time    time.Time
+	Line    string
+}
+
+// ListArchives is a function that returns the archives of a log file that hold entries of the given time range,
+// an empty log name lists the archives of every log file and zero times leave the range open
+func ListArchives(archivesPath, logName string, from, to time.Time) ([]*Archive, error) {
+
+	archives, err := FindArchives(archivesPath, logName)
+	if err != nil {
+		return nil, err
+	}
+
+	// An archive holds the entries written since the previous archive of the same log file
+	var listed []*Archive
+	previousArchivedAt := make(map[string]time.Time)
+	for _, archive := range archives {
+
+		startedAt := previousArchivedAt[archive.LogName]
+		previousArchivedAt[archive.LogName] = archive.ArchivedAt
+
+		if !from.IsZero() && archive.ArchivedAt.Before(from) {
+			continue
+		}
+
+		if !to.IsZero() && !startedAt.Before(to) {
+			continue
+		}
+
+		listed = append(listed, archive)
+	}
+
+	return listed, nil
+}
+
+// Search is a function that searches the given live log files and their archives for the lines that match the query,
+// the matches are passed to handle in chronological order. Archives are read as streams without being extracted
+// and the search stops at the first error returned by handle.
+func Search(logFiles []string, archivesPath string, query *SearchQuery, handle func(match *Match) error) error {
+
+	var readers []*matchReader
+	defer func() {
+		for _, reader := range readers {
+			reader.close()
+		}
+	}()
+
+	for _, logFile := range logFiles {
+
+		logName := filepath.Base(logFile)
+		if logFile == "" || !query.includes(logName) {
+			continue
+		}
+
+		reader := &matchReader{query: query, logName: logName}
+		if archivesPath != "" {
+			archives, err := ListArchives(archivesPath, logName, query.From, query.To)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+
+			for _, archive := range archives {
+				reader.sources = append(reader.sources, archive.Path)
+			}
+		}
+
+		if fileExists(logFile) {
+			reader.sources = append(reader.sources, logFile)
+		}
+
+		readers = append(readers, reader)
+	}
+
+	for _, reader := range readers {
+		if err := reader.advance(); err != nil {
+			return err
+		}
+	}
+
+	// Merging the matches of the log files, each of them is already in chronological order
+	for {
+
+		var earliest *matchReader
+		for _, reader := range readers {
+			if reader.next != nil && (earliest == nil || reader.next.Time.Before(earliest.next.Time)) {
+				earliest = reader
+			}
+		}
+
+		if earliest == nil {
+			return nil
+		}
+
+		if err := handle(earliest.next); err != nil {
+			return err
+		}
+
+		if err := earliest.advance(); err != nil {
+			return err
+		}
+	}
+}
+
+// includes is a method that checks whether the log file with the given name should be searched
+func (query *SearchQuery) includes(logName string) bool {
+
+	if len(query.LogNames) == 0 {
+		return true
+	}
+
+	for _, name := range query.LogNames {
+		if name == logName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matches is a method that checks whether a line written at the given time matches the query
+func (query *SearchQuery) matches(line string, moment time.Time) bool {
+
+	if !query.From.IsZero() && moment.Before(query.From) {
+		return false
+	}
+
+	if !query.To.IsZero() && !moment.Before(query.To) {
+		return false
+	}
+
+	return query.Pattern == nil || query.Pattern.MatchString(line)
+}
+
+// matchReader is a type that reads the matching lines of a single log file from its archives and the live log file
+type matchReader struct {
+	query    *SearchQuery
+	logName  string
+	sources  []string
+	source   string
+	closers  []io.Closer
+	scanner  *bufio.Scanner
+	lastTime time.Time // Lines without a time, such as continued lines, are given the time of the previous line
+	next     *Match
+}
+
+// advance is a method that reads the next matching line into next, next is set to nil when all the sources have been read
+func (reader *matchReader) advance() error {
+
+	reader.next = nil
+	for {
+
+		if reader.scanner == nil {
+			if len(reader.sources) == 0 {
+				return nil
+			}
+
+			if err := reader.open(reader.sources[0]); err != nil {
+				return err
+			}
+			reader.sources = reader.sources[1:]
+		}
+
+		for reader.scanner.Scan() {
+
+			line := reader.scanner.Text()
+			if moment, ok := parseLineTime(line); ok {
+				reader.lastTime = moment
+			}
+
+			if reader.query.matches(line, reader.lastTime) {
+				reader.next = &Match{LogName: reader.logName, Source: reader.source, Time: reader.lastTime, Line: line}
+				return nil
+			}
+		}
+
+		if err := reader.scanner.Err(); err != nil {
+			return err
+		}
+
+		reader.close()
+	}
+}
+
+// open is a method that opens a source for scanning, gzip and tar archives are decompressed while being read
+func (reader *matchReader) open(source string) error {
+
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	reader.closers = append(reader.closers, file)
+
+	var content io.Reader = file
+	switch {
+	case strings.HasSuffix(source, ".gz"):
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			reader.close()
+			return err
+		}
+		reader.closers = append(reader.closers, gzipReader)
+		content = gzipReader
+	case strings.HasSuffix(source, ".tar"):
+		content = &tarReader{reader: tar.NewReader(file)}
+	}
+
+	reader.source = source
+	reader.scanner = bufio.NewScanner(content)
+	reader.scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	return nil
+}
+
+// close is a method that closes the source that is being read
+func (reader *matchReader) close() {
+	for index := len(reader.closers) - 1; index >= 0; index-- {
+		reader.closers[index].Close()
+	}
+	reader.closers = nil
+	reader.scanner = nil
+}
+
+// tarReader is a type that reads the content of all the files of a tar archive one after the other
+type tarReader struct {
+	reader  *tar.Reader
+	started bool
+}
+
+// Read is a method that reads the content of the current file of the tar archive, moving to the next file when needed
+func (reader *tarReader) Read(p []byte) (int, error) {
+
+	for {
+		if reader.started {
+			n, err := reader.reader.Read(p)
+			if err != io.EOF || n > 0 {
+				return n, err
+			}
+		}
+
+		if _, err := reader.reader.Next(); err != nil {
+			return 0, err
+		}
+		reader.started = true
+	}
+}
+
+// parseLineTime is a function that reads the time of a log line,
+// both the json lines and the lines of the former text logger are supported
+func parseLineTime(line string) (time.Time, bool) {
+
+	if strings.HasPrefix(line, "{") {
+
+		// The logger always writes the time first, so avoiding decoding the whole line when possible
+		if strings.HasPrefix(line, `{"time":"`) {
+			stamp := line[len(`{"time":"`):]
+			if end := strings.IndexByte(stamp, '"'); end > 0 {
+				if moment, err := time.Parse(time.RFC3339Nano, stamp[:end]); err == nil {
+					return moment, true
+				}
+			}
+		}
+
+		var entry struct {
+			Time time.Time `json:"time"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err == nil && !entry.Time.IsZero() {
+			return entry.Time, true
+		}
+		return time.Time{}, false
+	}
+
+	// The former logger wrote lines such as [ 2021-03-01 10:00:00.0000001 +0300 EAT m=+0.001 ] statement
+	if !strings.HasPrefix(line, "[ ") {
+		return time.Time{}, false
+	}
+
+	end := strings.Index(line, " ]")
+	if end < 0 {
+		return time.Time{}, false
+	}
+
+	stamp := line[2:end]
+	if monotonic := strings.Index(stamp, " m="); monotonic >= 0 {
+		stamp = stamp[:monotonic]
+	}
+
+	moment, err := time.Parse(legacyTimeLayout, stamp)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return moment, true
+}
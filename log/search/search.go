@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/log"
+)
+
+// timeLayouts are the layouts the time range flags can be given with
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+func main() {
+
+	configFilesDir := flag.String("config", os.Getenv("config_files_dir"),
+		"directory of the config files, defaults to the config_files_dir environment variable")
+	logNames := flag.String("log", "", "comma separated names of the log files to search such as server.log, defaults to all")
+	from := flag.String("from", "", "start of the time range such as 2021-03-01 or 2021-03-01T10:00:00+03:00")
+	to := flag.String("to", "", "end of the time range, exclusive")
+	pattern := flag.String("pattern", "", "regular expression the lines should match")
+	id := flag.String("id", "", "entity id, trade number or any other literal the lines should contain")
+	list := flag.Bool("list", false, "lists the archives of the time range instead of searching them")
+	flag.Parse()
+
+	if *configFilesDir == "" {
+		exit("the config files directory should be provided with -config or config_files_dir")
+	}
+
+	var sysConfig entity.SystemConfig
+
+	// Reading data from config.server.json file and creating the systemconfig  object
+	sysConfigDir := filepath.Join(*configFilesDir, "/config.server.json")
+	sysConfigData, err := ioutil.ReadFile(sysConfigDir)
+	if err != nil {
+		exit(err.Error())
+	}
+
+	err = json.Unmarshal(sysConfigData, &sysConfig)
+	if err != nil {
+		exit(err.Error())
+	}
+
+	query := new(log.SearchQuery)
+	if query.From, err = parseTime(*from); err != nil {
+		exit(err.Error())
+	}
+
+	if query.To, err = parseTime(*to); err != nil {
+		exit(err.Error())
+	}
+
+	if *logNames != "" {
+		query.LogNames = strings.Split(*logNames, ",")
+	}
+
+	if *list {
+		listArchives(sysConfig.ArchivesPath, query)
+		return
+	}
+
+	switch {
+	case *pattern != "" && *id != "":
+		exit("only one of -pattern and -id can be provided")
+	case *pattern != "":
+		query.Pattern, err = regexp.Compile(*pattern)
+	case *id != "":
+		query.Pattern, err = regexp.Compile(regexp.QuoteMeta(*id))
+	default:
+		exit("a -pattern or an -id should be provided")
+	}
+
+	if err != nil {
+		exit(err.Error())
+	}
+
+	logs := log.NewLogContainer(sysConfig.LogsPath, sysConfig.Logs)
+	err = log.Search(uniqueLogFiles(logs.All()), sysConfig.ArchivesPath, query, func(match *log.Match) error {
+		_, err := fmt.Printf("%s\t%s\n", match.LogName, match.Line)
+		return err
+	})
+
+	if err != nil {
+		exit(err.Error())
+	}
+}
+
+// listArchives is a function that prints the archives that hold entries of the query's time range
+func listArchives(archivesPath string, query *log.SearchQuery) {
+
+	archives, err := log.ListArchives(archivesPath, "", query.From, query.To)
+	if err != nil {
+		exit(err.Error())
+	}
+
+	for _, archive := range archives {
+		if len(query.LogNames) > 0 && !contains(query.LogNames, archive.LogName) {
+			continue
+		}
+		fmt.Printf("%s\t%s\t%s\n", archive.LogName, archive.ArchivedAt.Format(time.RFC3339), archive.Path)
+	}
+}
+
+// parseTime is a function that parses the value of a time range flag, an empty value leaves the range open
+func parseTime(value string) (time.Time, error) {
+
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range timeLayouts {
+		if moment, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return moment, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %s", value)
+}
+
+// uniqueLogFiles is a function that removes the empty and the repeated log files, since some logs may share a file
+func uniqueLogFiles(logFiles []string) []string {
+
+	var unique []string
+	for _, logFile := range logFiles {
+		if logFile != "" && !contains(unique, logFile) {
+			unique = append(unique, logFile)
+		}
+	}
+
+	return unique
+}
+
+// contains is a function that checks whether a value is in the given list
+func contains(values []string, value string) bool {
+	for _, listed := range values {
+		if listed == value {
+			return true
+		}
+	}
+	return false
+}
+
+// exit is a function that prints the error message and stops the command
+func exit(message string) {
+	fmt.Fprintln(os.Stderr, message)
+	os.Exit(1)
+}
@@ -0,0 +1,320 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Scale is a constant that holds the number of minor units in a single major unit of an amount
+const Scale = 100
+
+// ErrCurrencyMismatch is an error returned when money of different currencies is combined or compared
+var ErrCurrencyMismatch = errors.New("money of different currencies can not be combined")
+
+// Amount is a type that defines a fixed-point monetary amount stored as a whole number of minor units,
+// so adding and subtracting amounts is exact. It is stored in the database as a decimal.
+type Amount int64
+
+// FromFloat is a function that converts a floating point value to an amount, rounding to the nearest minor unit
+func FromFloat(value float64) Amount {
+	return Amount(math.Round(value * Scale))
+}
+
+// Parse is a function that converts a decimal string such as "12.50" to an amount,
+// digits beyond the minor unit are rounded half away from zero
+func Parse(value string) (Amount, error) {
+
+	parsedValue, err := parseFixed(value, 2)
+	if err != nil {
+		return 0, errors.New("invalid amount")
+	}
+
+	return Amount(parsedValue), nil
+}
+
+// parseFixed is a function that converts a decimal string to a whole number of units holding the given number of
+// fraction digits, digits beyond them are rounded half away from zero
+func parseFixed(value string, digits int) (int64, error) {
+
+	scale := int64(math.Pow10(digits))
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, errors.New("invalid value")
+	}
+
+	// Only a single leading sign is accepted, so values such as "-+5" are rejected
+	negative := strings.HasPrefix(value, "-")
+	unsigned := value
+	if negative || strings.HasPrefix(value, "+") {
+		unsigned = value[1:]
+	}
+	if strings.HasPrefix(unsigned, "-") || strings.HasPrefix(unsigned, "+") {
+		return 0, errors.New("invalid value")
+	}
+
+	parts := strings.SplitN(unsigned, ".", 2)
+	if parts[0] == "" && (len(parts) == 1 || parts[1] == "") {
+		return 0, errors.New("invalid value")
+	}
+
+	if strings.ContainsAny(unsigned, "eE") {
+		// Exponent notation can only come from floating point columns or values
+		floatValue, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, errors.New("invalid value")
+		}
+		return int64(math.Round(floatValue * float64(scale))), nil
+	}
+
+	var major int64
+	if parts[0] != "" {
+		parsedMajor, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || parsedMajor < 0 {
+			return 0, errors.New("invalid value")
+		}
+		major = parsedMajor
+	}
+
+	var minor int64
+	if len(parts) == 2 && parts[1] != "" {
+		fraction := parts[1]
+		for _, digit := range fraction {
+			if digit < '0' || digit > '9' {
+				return 0, errors.New("invalid value")
+			}
+		}
+
+		roundUp := len(fraction) > digits && fraction[digits] >= '5'
+		fraction = (fraction + strings.Repeat("0", digits))[:digits]
+
+		minor, _ = strconv.ParseInt(fraction, 10, 64)
+		if roundUp {
+			minor++
+		}
+	}
+
+	if major > (math.MaxInt64-minor)/scale {
+		return 0, errors.New("value is out of range")
+	}
+
+	parsedValue := major*scale + minor
+	if negative {
+		parsedValue = -parsedValue
+	}
+
+	return parsedValue, nil
+}
+
+// Add is a method that returns the sum of the amounts
+func (amount Amount) Add(other Amount) Amount {
+	return amount + other
+}
+
+// Sub is a method that returns the difference of the amounts
+func (amount Amount) Sub(other Amount) Amount {
+	return amount - other
+}
+
+// Neg is a method that returns the amount with its sign flipped
+func (amount Amount) Neg() Amount {
+	return -amount
+}
+
+// Prorate is a method that returns the share of the amount given by numerator / denominator,
+// rounded half away from zero to the nearest minor unit
+func (amount Amount) Prorate(numerator, denominator int64) Amount {
+
+	if denominator == 0 {
+		return 0
+	}
+
+	product := new(big.Int).Mul(big.NewInt(int64(amount)), big.NewInt(numerator))
+	divisor := big.NewInt(denominator)
+
+	quotient, remainder := new(big.Int).QuoRem(product, divisor, new(big.Int))
+
+	// Rounding half away from zero by comparing twice the remainder with the divisor
+	doubled := new(big.Int).Abs(new(big.Int).Mul(remainder, big.NewInt(2)))
+	if doubled.Cmp(new(big.Int).Abs(divisor)) >= 0 {
+		if product.Sign()*divisor.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+
+	return Amount(quotient.Int64())
+}
+
+// Convert is a method that returns the amount converted with the given exchange rate,
+// rounded half away from zero to the nearest minor unit
+func (amount Amount) Convert(rate Rate) Amount {
+	return amount.Prorate(int64(rate), RateScale)
+}
+
+// Round is a method that returns the amount rounded half away from zero to the given number of fraction digits,
+// used for currencies whose smallest unit is larger than the minor unit
+func (amount Amount) Round(precision int64) Amount {
+
+	if precision >= 2 || precision < 0 {
+		return amount
+	}
+
+	unit := int64(math.Pow10(2 - int(precision)))
+	return amount.Prorate(1, unit) * Amount(unit)
+}
+
+// Float64 is a method that returns the amount as a floating point value in major units,
+// it should only be used for presentation or for ratios that don't need to be exact
+func (amount Amount) Float64() float64 {
+	return float64(amount) / Scale
+}
+
+// String is a method that returns the amount as a decimal string with two fraction digits
+func (amount Amount) String() string {
+	return formatFixed(int64(amount), 2)
+}
+
+// formatFixed is a function that returns a whole number of units holding the given number of fraction digits
+// as a decimal string
+func formatFixed(value int64, digits int) string {
+
+	scale := int64(math.Pow10(digits))
+
+	sign := ""
+	if value < 0 {
+		sign = "-"
+	}
+
+	major := value / scale
+	minor := value % scale
+	if major < 0 {
+		major = -major
+	}
+	if minor < 0 {
+		minor = -minor
+	}
+
+	return fmt.Sprintf("%s%d.%0*d", sign, major, digits, minor)
+}
+
+// Value is a method that converts the amount to a database value
+func (amount Amount) Value() (driver.Value, error) {
+	return amount.String(), nil
+}
+
+// Scan is a method that reads the amount from a database value, NULL values such as the sum of no rows are read as zero
+func (amount *Amount) Scan(src interface{}) error {
+
+	switch value := src.(type) {
+	case nil:
+		*amount = 0
+	case []byte:
+		parsedAmount, err := Parse(string(value))
+		if err != nil {
+			return err
+		}
+		*amount = parsedAmount
+	case string:
+		parsedAmount, err := Parse(value)
+		if err != nil {
+			return err
+		}
+		*amount = parsedAmount
+	case float64:
+		*amount = FromFloat(value)
+	case float32:
+		*amount = FromFloat(float64(value))
+	case int64:
+		*amount = Amount(value * Scale)
+	default:
+		return fmt.Errorf("unable to scan %T into an amount", src)
+	}
+
+	return nil
+}
+
+// MarshalJSON is a method that converts the amount to a JSON number with two fraction digits
+func (amount Amount) MarshalJSON() ([]byte, error) {
+	return []byte(amount.String()), nil
+}
+
+// UnmarshalJSON is a method that reads the amount from a JSON number or string
+func (amount *Amount) UnmarshalJSON(data []byte) error {
+
+	value := strings.Trim(string(data), `"`)
+	if value == "null" {
+		return nil
+	}
+
+	parsedAmount, err := Parse(value)
+	if err != nil {
+		return err
+	}
+
+	*amount = parsedAmount
+	return nil
+}
+
+// Min is a function that returns the smaller of the given amounts
+func Min(amount, other Amount) Amount {
+	if other < amount {
+		return other
+	}
+	return amount
+}
+
+// Money is a type that defines an amount together with the currency it is held in,
+// combining money of different currencies is rejected
+type Money struct {
+	Amount   Amount
+	Currency string
+}
+
+// New is a function that returns money of the given amount and currency
+func New(amount Amount, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Add is a method that returns the sum of the money, both should be held in the same currency
+func (money Money) Add(other Money) (Money, error) {
+	if money.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return New(money.Amount.Add(other.Amount), money.Currency), nil
+}
+
+// Sub is a method that returns the difference of the money, both should be held in the same currency
+func (money Money) Sub(other Money) (Money, error) {
+	if money.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return New(money.Amount.Sub(other.Amount), money.Currency), nil
+}
+
+// Cmp is a method that compares the money with the other money held in the same currency,
+// it returns -1, 0 or +1 when the money is less than, equal to or greater than the other money
+func (money Money) Cmp(other Money) (int, error) {
+	if money.Currency != other.Currency {
+		return 0, ErrCurrencyMismatch
+	}
+
+	switch {
+	case money.Amount < other.Amount:
+		return -1, nil
+	case money.Amount > other.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// String is a method that returns the money as the amount followed by its currency
+func (money Money) String() string {
+	return fmt.Sprintf("%s %s", money.Amount, money.Currency)
+}
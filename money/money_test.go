@@ -0,0 +1,245 @@
+package money_test
+
+import (
+	"testing"
+
+	"github.com/Benyam-S/onemembership/money"
+)
+
+func TestParse(t *testing.T) {
+
+	tests := []struct {
+		value    string
+		expected money.Amount
+		valid    bool
+	}{
+		{"12.50", 1250, true},
+		{"12.5", 1250, true},
+		{"12", 1200, true},
+		{".5", 50, true},
+		{"5.", 500, true},
+		{" 7.25 ", 725, true},
+		{"+5", 500, true},
+		{"-5", -500, true},
+		{"-0.015", -2, true},
+		{"0.004", 0, true},
+		{"0.005", 1, true},
+		{"1.995", 200, true},
+		{"1.5e1", 1500, true},
+		{"", 0, false},
+		{".", 0, false},
+		{"-", 0, false},
+		{"--5", 0, false},
+		{"++5", 0, false},
+		{"-+5", 0, false},
+		{"+-5", 0, false},
+		{"5.-1", 0, false},
+		{"1.2.3", 0, false},
+		{"abc", 0, false},
+		{"92233720368547758.08", 0, false},
+	}
+
+	for _, test := range tests {
+		amount, err := money.Parse(test.value)
+		if test.valid && err != nil {
+			t.Errorf("Parse(%q) returned %v", test.value, err)
+			continue
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Parse(%q) = %s, expected an error", test.value, amount)
+			continue
+		}
+		if amount != test.expected {
+			t.Errorf("Parse(%q) = %d, expected %d", test.value, amount, test.expected)
+		}
+	}
+}
+
+func TestAmountString(t *testing.T) {
+
+	tests := []struct {
+		amount   money.Amount
+		expected string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{1250, "12.50"},
+		{-5, "-0.05"},
+		{-1250, "-12.50"},
+	}
+
+	for _, test := range tests {
+		if output := test.amount.String(); output != test.expected {
+			t.Errorf("Amount(%d).String() = %q, expected %q", test.amount, output, test.expected)
+		}
+	}
+}
+
+func TestAmountRound(t *testing.T) {
+
+	tests := []struct {
+		amount    money.Amount
+		precision int64
+		expected  money.Amount
+	}{
+		{1249, 2, 1249},
+		{1249, 1, 1250},
+		{1244, 1, 1240},
+		{1245, 1, 1250},
+		{-1245, 1, -1250},
+		{1249, 0, 1200},
+		{1250, 0, 1300},
+		{-1250, 0, -1300},
+		{1249, -1, 1249},
+	}
+
+	for _, test := range tests {
+		if output := test.amount.Round(test.precision); output != test.expected {
+			t.Errorf("Amount(%d).Round(%d) = %d, expected %d", test.amount, test.precision, output, test.expected)
+		}
+	}
+}
+
+func TestAmountProrate(t *testing.T) {
+
+	tests := []struct {
+		amount      money.Amount
+		numerator   int64
+		denominator int64
+		expected    money.Amount
+	}{
+		{1000, 1, 3, 333},
+		{1000, 2, 3, 667},
+		{1001, 1, 2, 501},
+		{-1001, 1, 2, -501},
+		{1001, -1, 2, -501},
+		{1000, 0, 30, 0},
+		{1000, 30, 30, 1000},
+		{1000, 1, 0, 0},
+		{money.Amount(1) << 62, 3, 4, money.Amount(3) << 60},
+	}
+
+	for _, test := range tests {
+		output := test.amount.Prorate(test.numerator, test.denominator)
+		if output != test.expected {
+			t.Errorf("Amount(%d).Prorate(%d, %d) = %d, expected %d", test.amount,
+				test.numerator, test.denominator, output, test.expected)
+		}
+	}
+}
+
+func TestAmountConvert(t *testing.T) {
+
+	rate, err := money.ParseRate("56.25")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output := money.Amount(1001).Convert(rate); output != 56306 {
+		t.Errorf("Amount(1001).Convert(56.25) = %d, expected 56306", output)
+	}
+
+	if output := money.Amount(56306).Convert(rate.Invert()); output != 1001 {
+		t.Errorf("Amount(56306).Convert(1 / 56.25) = %d, expected 1001", output)
+	}
+}
+
+func TestMoneyCurrencyMismatch(t *testing.T) {
+
+	birr := money.New(1000, "ETB")
+	dollar := money.New(1000, "USD")
+
+	if _, err := birr.Add(dollar); err != money.ErrCurrencyMismatch {
+		t.Errorf("Add returned %v, expected %v", err, money.ErrCurrencyMismatch)
+	}
+	if _, err := birr.Sub(dollar); err != money.ErrCurrencyMismatch {
+		t.Errorf("Sub returned %v, expected %v", err, money.ErrCurrencyMismatch)
+	}
+	if _, err := birr.Cmp(dollar); err != money.ErrCurrencyMismatch {
+		t.Errorf("Cmp returned %v, expected %v", err, money.ErrCurrencyMismatch)
+	}
+
+	total, err := birr.Add(money.New(250, "ETB"))
+	if err != nil || total != money.New(1250, "ETB") {
+		t.Errorf("Add returned %v, %v, expected 12.50 ETB", total, err)
+	}
+
+	difference, err := birr.Sub(money.New(250, "ETB"))
+	if err != nil || difference != money.New(750, "ETB") {
+		t.Errorf("Sub returned %v, %v, expected 7.50 ETB", difference, err)
+	}
+
+	for _, test := range []struct {
+		other    money.Amount
+		expected int
+	}{{999, 1}, {1000, 0}, {1001, -1}} {
+		comparison, err := birr.Cmp(money.New(test.other, "ETB"))
+		if err != nil || comparison != test.expected {
+			t.Errorf("Cmp(%d) returned %d, %v, expected %d", test.other, comparison, err, test.expected)
+		}
+	}
+}
+
+func TestAmountDatabaseRoundTrip(t *testing.T) {
+
+	for _, amount := range []money.Amount{0, 5, 1250, -1250, 123456789} {
+
+		value, err := amount.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The driver returns decimal columns as bytes
+		var scanned money.Amount
+		if err := scanned.Scan([]byte(value.(string))); err != nil {
+			t.Fatalf("Scan(%q) returned %v", value, err)
+		}
+		if scanned != amount {
+			t.Errorf("round trip of %d returned %d", amount, scanned)
+		}
+	}
+
+	tests := []struct {
+		src      interface{}
+		expected money.Amount
+	}{
+		{nil, 0},
+		{"12.50", 1250},
+		{12.345, 1235},
+		{float32(0.5), 50},
+		{int64(12), 1200},
+	}
+
+	for _, test := range tests {
+		amount := money.Amount(99)
+		if err := amount.Scan(test.src); err != nil || amount != test.expected {
+			t.Errorf("Scan(%v) returned %d, %v, expected %d", test.src, amount, err, test.expected)
+		}
+	}
+
+	var amount money.Amount
+	if err := amount.Scan(true); err == nil {
+		t.Error("Scan(true) returned no error")
+	}
+	if err := amount.Scan([]byte("-+5")); err == nil {
+		t.Error("Scan(-+5) returned no error")
+	}
+}
+
+func TestRateDatabaseRoundTrip(t *testing.T) {
+
+	rate, err := money.ParseRate("0.01777778")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := rate.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var scanned money.Rate
+	if err := scanned.Scan([]byte(value.(string))); err != nil || scanned != rate {
+		t.Errorf("round trip of %s returned %s, %v", rate, scanned, err)
+	}
+}
@@ -0,0 +1,118 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// RateScale is a constant that holds the number of fractional units in a single unit of an exchange rate
+const RateScale = 100000000
+
+// Rate is a type that defines a fixed-point exchange rate stored as a whole number of units with eight fraction digits,
+// it holds how much of the quote currency a single unit of the base currency is worth
+type Rate int64
+
+// ParseRate is a function that converts a decimal string such as "56.25" to an exchange rate,
+// digits beyond the eighth fraction digit are rounded half away from zero
+func ParseRate(value string) (Rate, error) {
+
+	parsedValue, err := parseFixed(value, 8)
+	if err != nil {
+		return 0, errors.New("invalid exchange rate")
+	}
+
+	return Rate(parsedValue), nil
+}
+
+// Invert is a method that returns the exchange rate of the opposite direction, rounded half away from zero
+func (rate Rate) Invert() Rate {
+
+	if rate == 0 {
+		return 0
+	}
+
+	numerator := new(big.Int).Mul(big.NewInt(RateScale), big.NewInt(RateScale))
+	divisor := big.NewInt(int64(rate))
+
+	quotient, remainder := new(big.Int).QuoRem(numerator, divisor, new(big.Int))
+
+	// Rounding half away from zero by comparing twice the remainder with the divisor
+	doubled := new(big.Int).Abs(new(big.Int).Mul(remainder, big.NewInt(2)))
+	if doubled.Cmp(new(big.Int).Abs(divisor)) >= 0 {
+		if divisor.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+
+	return Rate(quotient.Int64())
+}
+
+// String is a method that returns the exchange rate as a decimal string with eight fraction digits
+func (rate Rate) String() string {
+	return formatFixed(int64(rate), 8)
+}
+
+// Value is a method that converts the exchange rate to a database value
+func (rate Rate) Value() (driver.Value, error) {
+	return rate.String(), nil
+}
+
+// Scan is a method that reads the exchange rate from a database value
+func (rate *Rate) Scan(src interface{}) error {
+
+	switch value := src.(type) {
+	case nil:
+		*rate = 0
+	case []byte:
+		parsedRate, err := ParseRate(string(value))
+		if err != nil {
+			return err
+		}
+		*rate = parsedRate
+	case string:
+		parsedRate, err := ParseRate(value)
+		if err != nil {
+			return err
+		}
+		*rate = parsedRate
+	case float64:
+		parsedRate, err := ParseRate(fmt.Sprintf("%.8f", value))
+		if err != nil {
+			return err
+		}
+		*rate = parsedRate
+	case int64:
+		*rate = Rate(value * RateScale)
+	default:
+		return fmt.Errorf("unable to scan %T into an exchange rate", src)
+	}
+
+	return nil
+}
+
+// MarshalJSON is a method that converts the exchange rate to a JSON number with eight fraction digits
+func (rate Rate) MarshalJSON() ([]byte, error) {
+	return []byte(rate.String()), nil
+}
+
+// UnmarshalJSON is a method that reads the exchange rate from a JSON number or string
+func (rate *Rate) UnmarshalJSON(data []byte) error {
+
+	value := strings.Trim(string(data), `"`)
+	if value == "null" {
+		return nil
+	}
+
+	parsedRate, err := ParseRate(value)
+	if err != nil {
+		return err
+	}
+
+	*rate = parsedRate
+	return nil
+}
@@ -35,6 +35,11 @@ func (service *Service) AddProject(newProject *entity.Project) error {
 	service.logger.Log(fmt.Sprintf("Started project adding process, Project => %s",
 		newProject.ToString()), service.logger.Logs.ProjectLogFile)
 
+	// Only service providers with an active platform subscription can add new projects
+	if !service.cmService.HasActiveSPSubscription(newProject.ProviderID) {
+		return errors.New("service provider doesn't have an active subscription")
+	}
+
 	err := service.projectRepo.Create(newProject)
 	if err != nil {
 		/* ---------------------------- Logging ---------------------------- */
@@ -0,0 +1,10 @@
+package receipt
+
+import "github.com/Benyam-S/onemembership/entity"
+
+// IReceiptRepository is an interface that defines all the repository methods of a receipt struct
+type IReceiptRepository interface {
+	Issue(newReceipt *entity.Receipt) error
+	Find(id string) (*entity.Receipt, error)
+	FindMultiple(providerID string) []*entity.Receipt
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/receipt"
+	"github.com/Benyam-S/onemembership/tools"
+	"github.com/jinzhu/gorm"
+)
+
+// ReceiptRepository is a type that defines a receipt repository type
+type ReceiptRepository struct {
+	conn *gorm.DB
+}
+
+// NewReceiptRepository is a function that creates a new receipt repository type
+func NewReceiptRepository(connection *gorm.DB) receipt.IReceiptRepository {
+	return &ReceiptRepository{conn: connection}
+}
+
+// Issue is a method that adds a new receipt to the database with the next receipt number of its service provider.
+// The provider's receipt sequence is locked and advanced in the same database transaction the receipt is added in,
+// so numbers are handed out one at a time and a failed receipt doesn't leave a gap.
+// A transaction only gets a single receipt, issuing it again returns the receipt it already has.
+func (repo *ReceiptRepository) Issue(newReceipt *entity.Receipt) error {
+
+	return repo.conn.Transaction(func(tx *gorm.DB) error {
+
+		err := tx.Exec("INSERT IGNORE INTO receipt_sequences (provider_id, last_number, updated_at) VALUES (?, ?, ?)",
+			newReceipt.ProviderID, 0, time.Now()).Error
+		if err != nil {
+			return err
+		}
+
+		lockedSequence := new(entity.ReceiptSequence)
+		err = tx.Set("gorm:query_option", "FOR UPDATE").Model(lockedSequence).
+			Where("provider_id = ?", newReceipt.ProviderID).First(lockedSequence).Error
+		if err != nil {
+			return err
+		}
+
+		prevReceipt := new(entity.Receipt)
+		err = tx.Model(prevReceipt).Where("transaction_id = ? && transaction_type = ?",
+			newReceipt.TransactionID, newReceipt.TransactionType).First(prevReceipt).Error
+		if err == nil {
+			*newReceipt = *prevReceipt
+			return nil
+		}
+
+		lockedSequence.LastNumber++
+		err = tx.Model(entity.ReceiptSequence{}).Where("provider_id = ?", lockedSequence.ProviderID).
+			Update(map[string]interface{}{"last_number": lockedSequence.LastNumber}).Error
+		if err != nil {
+			return err
+		}
+
+		totalNumOfReceipts := tools.CountMembers("receipts", tx)
+		newReceipt.ID = fmt.Sprintf("RCP-%s%d", tools.RandomStringGN(7), totalNumOfReceipts+1)
+
+		for !tools.IsUnique("id", newReceipt.ID, "receipts", tx) {
+			totalNumOfReceipts++
+			newReceipt.ID = fmt.Sprintf("RCP-%s%d", tools.RandomStringGN(7), totalNumOfReceipts+1)
+		}
+
+		newReceipt.ReceiptNumber = lockedSequence.LastNumber
+		return tx.Create(newReceipt).Error
+	})
+}
+
+// Find is a method that finds a certain receipt from the database using a receipt id,
+// also Find() uses only id as a key for selection
+func (repo *ReceiptRepository) Find(id string) (*entity.Receipt, error) {
+
+	receipt := new(entity.Receipt)
+	err := repo.conn.Model(receipt).Where("id = ?", id).First(receipt).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// FindMultiple is a method that finds all the receipts issued for a service provider, the latest receipt comes first
+func (repo *ReceiptRepository) FindMultiple(providerID string) []*entity.Receipt {
+
+	var receipts []*entity.Receipt
+	err := repo.conn.Model(entity.Receipt{}).Where("provider_id = ?", providerID).
+		Order("receipt_number DESC").Find(&receipts).Error
+
+	if err != nil {
+		return []*entity.Receipt{}
+	}
+	return receipts
+}
@@ -0,0 +1,46 @@
+package receipt
+
+import (
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/money"
+)
+
+// Field is a type that defines a labelled detail of a receipt
+type Field struct {
+	Label string
+	Value string
+}
+
+// Line is a type that defines a labelled amount of a receipt
+type Line struct {
+	Label    string
+	Amount   money.Amount
+	Currency string
+}
+
+// Document is a type that defines the content of a receipt, ready to be rendered
+type Document struct {
+	Receipt  *entity.Receipt
+	Number   string // The receipt number shown to the reader, prefixed with the service provider id
+	Title    string
+	IssuedBy string
+	IssuedTo string
+	PaidAt   time.Time
+	Details  []Field
+	Lines    []Line
+	Total    Line
+}
+
+// IService is an interface that defines all the service methods of a receipt struct
+type IService interface {
+	IssueSubscriptionReceipt(transactionID string) (*Document, error)
+	IssueSPSubscriptionReceipt(transactionID string) (*Document, error)
+	IssueSPPayrollReceipt(transactionID string) (*Document, error)
+	FindReceipt(id string) (*entity.Receipt, error)
+	FindMultipleReceipts(providerID string) []*entity.Receipt
+
+	RenderText(document *Document) string
+	RenderPDF(document *Document) ([]byte, error)
+}
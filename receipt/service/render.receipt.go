@@ -0,0 +1,100 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Benyam-S/onemembership/receipt"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// receiptTimeLayout is the layout used for printing the payment time on receipts
+const receiptTimeLayout = "Jan 02, 2006 15:04 MST"
+
+// RenderText is a method that renders a receipt document as plain text, suitable for bot messages and emails
+func (service *Service) RenderText(document *receipt.Document) string {
+
+	var buffer bytes.Buffer
+	writer := tabwriter.NewWriter(&buffer, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(writer, "%s\n", strings.ToUpper(document.Title))
+	fmt.Fprintf(writer, "Receipt No:\t%s\n", document.Number)
+	fmt.Fprintf(writer, "Date:\t%s\n", document.PaidAt.Format(receiptTimeLayout))
+	fmt.Fprintf(writer, "Issued By:\t%s\n", document.IssuedBy)
+	fmt.Fprintf(writer, "Issued To:\t%s\n", document.IssuedTo)
+	fmt.Fprintln(writer)
+
+	for _, field := range document.Details {
+		if field.Value == "" {
+			continue
+		}
+		fmt.Fprintf(writer, "%s:\t%s\n", field.Label, field.Value)
+	}
+	fmt.Fprintln(writer)
+
+	for _, line := range document.Lines {
+		fmt.Fprintf(writer, "%s\t%s %s\n", line.Label, line.Amount, line.Currency)
+	}
+	fmt.Fprintf(writer, "%s\t%s %s\n", document.Total.Label, document.Total.Amount, document.Total.Currency)
+
+	writer.Flush()
+	return buffer.String()
+}
+
+// RenderPDF is a method that renders a receipt document as a single page A4 pdf file
+func (service *Service) RenderPDF(document *receipt.Document) ([]byte, error) {
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	translate := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle(document.Title+" "+document.Number, true)
+	pdf.SetAuthor(document.IssuedBy, true)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 12, translate(document.Title), "", 1, "L", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 11)
+	header := []receipt.Field{
+		{Label: "Receipt No", Value: document.Number},
+		{Label: "Date", Value: document.PaidAt.Format(receiptTimeLayout)},
+		{Label: "Issued By", Value: document.IssuedBy},
+		{Label: "Issued To", Value: document.IssuedTo},
+	}
+	for _, field := range header {
+		pdf.CellFormat(40, 7, translate(field.Label+":"), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 7, translate(field.Value), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(5)
+
+	for _, field := range document.Details {
+		if field.Value == "" {
+			continue
+		}
+		pdf.CellFormat(40, 7, translate(field.Label+":"), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 7, translate(field.Value), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(5)
+
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(120, 8, "Description", "B", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 8, "Amount", "B", 1, "R", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 11)
+	for _, line := range document.Lines {
+		pdf.CellFormat(120, 8, translate(line.Label), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 8, fmt.Sprintf("%s %s", line.Amount, line.Currency), "", 1, "R", false, 0, "")
+	}
+
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(120, 8, translate(document.Total.Label), "T", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 8, fmt.Sprintf("%s %s", document.Total.Amount, document.Total.Currency), "T", 1, "R", false, 0, "")
+
+	var buffer bytes.Buffer
+	if err := pdf.Output(&buffer); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/log"
+	"github.com/Benyam-S/onemembership/money"
+	"github.com/Benyam-S/onemembership/receipt"
+	"github.com/Benyam-S/onemembership/serviceprovider"
+	"github.com/Benyam-S/onemembership/subscription"
+	"github.com/Benyam-S/onemembership/subscriptionplan"
+	"github.com/Benyam-S/onemembership/transaction"
+)
+
+// Service is a type that defines a receipt service
+type Service struct {
+	receiptRepo             receipt.IReceiptRepository
+	transactionService      transaction.IService
+	subscriptionService     subscription.IService
+	subscriptionPlanService subscriptionplan.IService
+	serviceProviderService  serviceprovider.IService
+	platformName            string // The name the platform issues the service provider receipts with
+	logger                  *log.Logger
+}
+
+// NewReceiptService is a function that returns a new receipt service
+func NewReceiptService(receiptRepository receipt.IReceiptRepository, transactionService transaction.IService,
+	subscriptionService subscription.IService, subscriptionPlanService subscriptionplan.IService,
+	serviceProviderService serviceprovider.IService, platformName string, transactionLogger *log.Logger) receipt.IService {
+	return &Service{receiptRepo: receiptRepository, transactionService: transactionService,
+		subscriptionService: subscriptionService, subscriptionPlanService: subscriptionPlanService,
+		serviceProviderService: serviceProviderService, platformName: platformName, logger: transactionLogger}
+}
+
+// IssueSubscriptionReceipt is a method that issues the receipt of a completed subscription transaction,
+// the receipt is numbered in the series of the service provider the subscriber has paid
+// and holds the snapshot of the subscription the transaction has activated
+func (service *Service) IssueSubscriptionReceipt(transactionID string) (*receipt.Document, error) {
+
+	subscriptionTransaction, err := service.transactionService.FindSubscriptionTransaction(transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if subscriptionTransaction.Status != entity.TransactionStatusComplete {
+		return nil, errors.New("receipts can only be issued for completed transactions")
+	}
+
+	subscription, err := service.subscriptionService.FindActivatedSubscription(subscriptionTransaction.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	newReceipt, err := service.issue(subscription.ProviderID, subscriptionTransaction.ID,
+		entity.TransactionTypeSubscription)
+	if err != nil {
+		return nil, err
+	}
+
+	document := &receipt.Document{
+		Receipt:  newReceipt,
+		Number:   receiptNumber(newReceipt),
+		Title:    "Subscription Receipt",
+		IssuedBy: fullName(subscription.ProviderFirstName, subscription.ProviderLastName),
+		IssuedTo: fullName(subscription.SubscriberFirstName, subscription.SubscriberLastName),
+		PaidAt:   subscriptionTransaction.UpdatedAt,
+		Details: []receipt.Field{
+			{Label: "Project", Value: subscription.ProjectName},
+			{Label: "Plan", Value: subscription.SubscriptionPlanName},
+			{Label: "Duration", Value: fmt.Sprintf("%d days", subscription.SubscriptionPlanDuration)},
+			{Label: "Plan Price", Value: subscription.SubscriptionPlanPrice.String() + " " +
+				subscription.SubscriptionPlanCurrency},
+			{Label: "Transaction ID", Value: subscriptionTransaction.ID},
+			{Label: "Trade No", Value: string(subscriptionTransaction.TradeNo)},
+			{Label: "Out Trade No", Value: subscriptionTransaction.OutTradeNo},
+		},
+	}
+
+	// Transactions made before the conversion at checkout don't hold an original amount
+	if subscriptionTransaction.OriginalCurrency == "" {
+		document.Lines = append(document.Lines, receipt.Line{Label: "Amount",
+			Amount: subscriptionTransaction.ReceivedAmount, Currency: subscriptionTransaction.CurrencyType})
+	} else {
+		document.Lines = append(document.Lines, receipt.Line{Label: "Amount",
+			Amount:   subscriptionTransaction.OriginalAmount.Add(subscriptionTransaction.DiscountAmount),
+			Currency: subscriptionTransaction.OriginalCurrency})
+
+		if subscriptionTransaction.DiscountAmount > 0 {
+			document.Lines = append(document.Lines, receipt.Line{Label: "Discount",
+				Amount: subscriptionTransaction.DiscountAmount.Neg(), Currency: subscriptionTransaction.OriginalCurrency})
+		}
+
+		if subscriptionTransaction.OriginalCurrency != subscriptionTransaction.CurrencyType {
+			document.Details = append(document.Details, receipt.Field{Label: "Exchange Rate",
+				Value: fmt.Sprintf("1 %s = %s %s", subscriptionTransaction.OriginalCurrency,
+					subscriptionTransaction.ExchangeRate, subscriptionTransaction.CurrencyType)})
+		}
+	}
+
+	service.addFee(document, subscriptionTransaction.TransactionFee, subscriptionTransaction.Total(),
+		subscriptionTransaction.FeeAbsorbedBy)
+
+	return document, nil
+}
+
+// IssueSPSubscriptionReceipt is a method that issues the receipt of a completed service provider subscription transaction,
+// the receipt is numbered in the series of the service provider that has paid for the platform
+func (service *Service) IssueSPSubscriptionReceipt(transactionID string) (*receipt.Document, error) {
+
+	spSubscriptionTransaction, err := service.transactionService.FindSPSubscriptionTransaction(transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if spSubscriptionTransaction.Status != entity.TransactionStatusComplete {
+		return nil, errors.New("receipts can only be issued for completed transactions")
+	}
+
+	serviceProvider, err := service.serviceProviderService.FindServiceProvider(spSubscriptionTransaction.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The plan may have been removed since the payment, so falling back to the subject the payment has been made for
+	planName := spSubscriptionTransaction.Subject
+	if spSubscriptionPlan, err := service.subscriptionPlanService.FindSPSubscriptionPlan(
+		spSubscriptionTransaction.PlanID); err == nil {
+		planName = spSubscriptionPlan.Name
+	}
+
+	newReceipt, err := service.issue(serviceProvider.ID, spSubscriptionTransaction.ID,
+		entity.TransactionTypeSPSubscription)
+	if err != nil {
+		return nil, err
+	}
+
+	document := &receipt.Document{
+		Receipt:  newReceipt,
+		Number:   receiptNumber(newReceipt),
+		Title:    "Platform Subscription Receipt",
+		IssuedBy: spSubscriptionTransaction.ReceiverName,
+		IssuedTo: fullName(serviceProvider.FirstName, serviceProvider.LastName),
+		PaidAt:   spSubscriptionTransaction.UpdatedAt,
+		Details: []receipt.Field{
+			{Label: "Plan", Value: planName},
+			{Label: "Transaction ID", Value: spSubscriptionTransaction.ID},
+			{Label: "Trade No", Value: string(spSubscriptionTransaction.TradeNo)},
+			{Label: "Out Trade No", Value: spSubscriptionTransaction.OutTradeNo},
+		},
+	}
+
+	if spSubscriptionTransaction.OriginalCurrency == "" {
+		document.Lines = append(document.Lines, receipt.Line{Label: "Amount",
+			Amount: spSubscriptionTransaction.ReceivedAmount, Currency: spSubscriptionTransaction.CurrencyType})
+	} else {
+		document.Lines = append(document.Lines, receipt.Line{Label: "Amount",
+			Amount: spSubscriptionTransaction.OriginalAmount, Currency: spSubscriptionTransaction.OriginalCurrency})
+
+		if spSubscriptionTransaction.OriginalCurrency != spSubscriptionTransaction.CurrencyType {
+			document.Details = append(document.Details, receipt.Field{Label: "Exchange Rate",
+				Value: fmt.Sprintf("1 %s = %s %s", spSubscriptionTransaction.OriginalCurrency,
+					spSubscriptionTransaction.ExchangeRate, spSubscriptionTransaction.CurrencyType)})
+		}
+	}
+
+	service.addFee(document, spSubscriptionTransaction.TransactionFee, spSubscriptionTransaction.Total(),
+		spSubscriptionTransaction.FeeAbsorbedBy)
+
+	return document, nil
+}
+
+// IssueSPPayrollReceipt is a method that issues the receipt of a completed payroll transaction,
+// the receipt is numbered in the series of the service provider that has been paid
+func (service *Service) IssueSPPayrollReceipt(transactionID string) (*receipt.Document, error) {
+
+	payrollTransaction, err := service.transactionService.FindSPPayrollTransaction(transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if payrollTransaction.Status != entity.PayrollStatusComplete {
+		return nil, errors.New("receipts can only be issued for completed transactions")
+	}
+
+	serviceProvider, err := service.serviceProviderService.FindServiceProvider(payrollTransaction.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+
+	newReceipt, err := service.issue(serviceProvider.ID, payrollTransaction.ID, entity.TransactionTypeSPPayroll)
+	if err != nil {
+		return nil, err
+	}
+
+	document := &receipt.Document{
+		Receipt:  newReceipt,
+		Number:   receiptNumber(newReceipt),
+		Title:    "Payout Receipt",
+		IssuedBy: service.platformName,
+		IssuedTo: fullName(serviceProvider.FirstName, serviceProvider.LastName),
+		PaidAt:   payrollTransaction.UpdatedAt,
+		Details: []receipt.Field{
+			{Label: "Transaction ID", Value: payrollTransaction.ID},
+			{Label: "Linked Account", Value: payrollTransaction.LinkedAccount},
+			{Label: "Account Provider", Value: payrollTransaction.LinkedAccountProvider},
+		},
+		Total: receipt.Line{Label: "Total Paid Out", Amount: payrollTransaction.PayedAmount,
+			Currency: payrollTransaction.CurrencyType},
+	}
+
+	return document, nil
+}
+
+// addFee is a method that adds the transaction fee and the total paid to a receipt document,
+// the fee is only charged on top of the amount when it hasn't been absorbed by the service provider
+func (service *Service) addFee(document *receipt.Document, transactionFee money.Amount, total money.Money,
+	feeAbsorbedBy string) {
+
+	if transactionFee > 0 && feeAbsorbedBy != entity.FeeAbsorbedByProvider {
+		document.Lines = append(document.Lines, receipt.Line{Label: "Transaction Fee", Amount: transactionFee,
+			Currency: total.Currency})
+	}
+
+	document.Total = receipt.Line{Label: "Total Paid", Amount: total.Amount, Currency: total.Currency}
+}
+
+// issue is a method that issues the receipt of a transaction with the next receipt number of the service provider
+func (service *Service) issue(providerID, transactionID, transactionType string) (*entity.Receipt, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started receipt issuing process { Provider ID : %s, Transaction ID : %s, "+
+		"Transaction Type : %s }", providerID, transactionID, transactionType), service.logger.Logs.TransactionLogFile)
+
+	newReceipt := &entity.Receipt{ProviderID: providerID, TransactionID: transactionID, TransactionType: transactionType}
+	err := service.receiptRepo.Issue(newReceipt)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For issuing receipt { Provider ID : %s, "+
+			"Transaction ID : %s, Transaction Type : %s }, %s", providerID, transactionID, transactionType, err.Error()))
+
+		return nil, errors.New("unable to issue receipt")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished receipt issuing process, Receipt => %s", newReceipt.ToString()),
+		service.logger.Logs.TransactionLogFile)
+
+	return newReceipt, nil
+}
+
+// FindReceipt is a method that find and return a receipt that matches the id value
+func (service *Service) FindReceipt(id string) (*entity.Receipt, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Single receipt finding process { Receipt ID : %s }", id),
+		service.logger.Logs.TransactionLogFile)
+
+	receipt, err := service.receiptRepo.Find(id)
+	if err != nil {
+		return nil, errors.New("no receipt found")
+	}
+	return receipt, nil
+}
+
+// FindMultipleReceipts is a method that find and return multiple receipts that have been issued for a service provider
+func (service *Service) FindMultipleReceipts(providerID string) []*entity.Receipt {
+	return service.receiptRepo.FindMultiple(providerID)
+}
+
+// receiptNumber is a function that returns the number of a receipt as shown to the reader
+func receiptNumber(receipt *entity.Receipt) string {
+	return fmt.Sprintf("%s-%06d", receipt.ProviderID, receipt.ReceiptNumber)
+}
+
+// fullName is a function that joins a first and a last name
+func fullName(firstName, lastName string) string {
+	return strings.TrimSpace(firstName + " " + lastName)
+}
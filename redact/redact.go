@@ -0,0 +1,187 @@
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Secret is a constant that defines the policy of values that are removed entirely, such as passwords and keys
+const Secret = "secret"
+
+// Mask is a constant that defines the policy of values that are partly hidden, such as phone numbers and emails
+const Mask = "mask"
+
+// Hash is a constant that defines the policy of values that are replaced by a short hash,
+// so that entries of the same value can still be related without revealing it
+const Hash = "hash"
+
+// Tag is the struct tag the policy of a field is defined with, such as `redact:"mask"`
+const Tag = "redact"
+
+// secretReplacement is the value secrets are replaced with
+const secretReplacement = "[REDACTED]"
+
+// hashPrefix is the prefix of hashed values
+const hashPrefix = "sha256:"
+
+// Policies is the policy table of the keys that are redacted from log fields and from JSON text written to the logs.
+// Keys are matched after being lower cased and stripped from underscores, dashes and spaces.
+var Policies = map[string]string{
+	"password":              Secret,
+	"salt":                  Secret,
+	"appkey":                Secret,
+	"publickey":             Secret,
+	"privatekey":            Secret,
+	"secret":                Secret,
+	"token":                 Secret,
+	"phonenumber":           Mask,
+	"subscriberphonenumber": Mask,
+	"providerphonenumber":   Mask,
+	"email":                 Mask,
+	"subscriberemail":       Mask,
+	"provideremail":         Mask,
+	"superadminemail":       Mask,
+	"linkedaccount":         Mask,
+}
+
+var (
+	jsonPairRx = regexp.MustCompile(`"([A-Za-z][A-Za-z0-9_\- ]*)"\s*:\s*"((?:[^"\\]|\\.)*)"`)
+	emailRx    = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	phoneRx    = regexp.MustCompile(`(?:\+251|\b251|\b0)[79]\d{8}\b`)
+)
+
+// Value is a function that redacts a single value according to the given policy, empty values are kept as they are.
+// Masking and hashing are always applied, since a value that only looks redacted may still be sensitive.
+func Value(policy, value string) string {
+
+	if value == "" || IsRedacted(value) {
+		return value
+	}
+
+	switch policy {
+	case Secret:
+		return secretReplacement
+	case Hash:
+		sum := sha256.Sum256([]byte(value))
+		return hashPrefix + hex.EncodeToString(sum[:])[:16]
+	case Mask:
+		// Keeping the domain of emails and the last digits of other values so that they can still be recognized
+		if at := strings.LastIndex(value, "@"); at > 0 {
+			return value[:1] + strings.Repeat("*", at-1) + value[at:]
+		}
+
+		visible := 4
+		if len(value) <= visible*2 {
+			visible = len(value) / 4
+		}
+		return strings.Repeat("*", len(value)-visible) + value[len(value)-visible:]
+	}
+
+	return value
+}
+
+// IsRedacted is a function that checks whether a value is the replacement of a removed secret
+func IsRedacted(value string) bool {
+	return value == secretReplacement
+}
+
+// Policy is a function that returns the policy of a key from the policy table, or an empty string if the key isn't sensitive
+func Policy(key string) string {
+	normalizer := strings.NewReplacer("_", "", "-", "", " ", "")
+	return Policies[strings.ToLower(normalizer.Replace(key))]
+}
+
+// Struct is a function that returns a copy of a struct, or of a struct pointer, where the string fields
+// that have a redact tag or are listed in the policy table are redacted. Other values are returned as they are.
+func Struct(value interface{}) interface{} {
+
+	reflectValue := reflect.ValueOf(value)
+	if reflectValue.Kind() == reflect.Ptr {
+		if reflectValue.IsNil() {
+			return value
+		}
+		reflectValue = reflectValue.Elem()
+	}
+
+	if reflectValue.Kind() != reflect.Struct {
+		return value
+	}
+
+	reflectType := reflectValue.Type()
+	redacted := reflect.New(reflectType).Elem()
+	redacted.Set(reflectValue)
+
+	for index := 0; index < reflectType.NumField(); index++ {
+
+		field := redacted.Field(index)
+		if field.Kind() != reflect.String || !field.CanSet() {
+			continue
+		}
+
+		policy, ok := reflectType.Field(index).Tag.Lookup(Tag)
+		if !ok {
+			policy = Policy(reflectType.Field(index).Name)
+		}
+
+		if policy != "" {
+			field.SetString(Value(policy, field.String()))
+		}
+	}
+
+	// Returning a pointer so the methods with pointer receivers, such as MarshalJSON, are still used
+	return redacted.Addr().Interface()
+}
+
+// Text is a function that redacts a free text statement before it is written to the logs,
+// the values of sensitive JSON keys as well as emails and phone numbers found in the text are redacted
+func Text(stmt string) string {
+
+	stmt = jsonPairRx.ReplaceAllStringFunc(stmt, func(pair string) string {
+
+		match := jsonPairRx.FindStringSubmatch(pair)
+		policy := Policy(match[1])
+		if policy == "" || match[2] == "" || IsRedacted(match[2]) {
+			return pair
+		}
+
+		return pair[:len(pair)-len(match[2])-1] + Value(policy, match[2]) + `"`
+	})
+
+	stmt = emailRx.ReplaceAllStringFunc(stmt, func(email string) string { return Value(Mask, email) })
+	stmt = phoneRx.ReplaceAllStringFunc(stmt, func(phone string) string { return Value(Mask, phone) })
+
+	return stmt
+}
+
+// Fields is a function that returns a copy of the given key value pairs with the values of sensitive keys redacted
+func Fields(fields map[string]interface{}) map[string]interface{} {
+
+	if fields == nil {
+		return nil
+	}
+
+	redacted := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+
+		policy := Policy(key)
+		switch typedValue := value.(type) {
+		case string:
+			if policy != "" {
+				redacted[key] = Value(policy, typedValue)
+			} else {
+				redacted[key] = Text(typedValue)
+			}
+		default:
+			if policy != "" {
+				redacted[key] = secretReplacement
+			} else {
+				redacted[key] = Struct(value)
+			}
+		}
+	}
+
+	return redacted
+}
@@ -0,0 +1,152 @@
+package redact_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/redact"
+	"github.com/Benyam-S/onemembership/transaction"
+)
+
+// sample is a type that defines a sensitive value together with what it should be redacted to
+type sample struct {
+	value    string
+	redacted string
+}
+
+// Values that look as if they have already been redacted should still be redacted
+var (
+	secretSamples = []sample{
+		{"s3cr3t", "[REDACTED]"},
+		{"*abc123", "[REDACTED]"},
+		{"sha256:abc123", "[REDACTED]"},
+		{"abc*@123", "[REDACTED]"},
+	}
+	emailSamples = []sample{
+		{"abebe@example.com", "a****@example.com"},
+		{"*abc123@example.com", "*******@example.com"},
+		{"sha256:abc@example.com", "s*********@example.com"},
+	}
+	phoneSamples = []sample{
+		{"0911223344", "******3344"},
+		{"*251911223344", "*********3344"},
+		{"sha256:0911223344", "*************3344"},
+	}
+)
+
+// taggedEntities are the structs whose sensitive fields are marked with the redact tag
+var taggedEntities = []interface{}{
+	entity.User{},
+	entity.UserPassword{},
+	entity.ServiceProvider{},
+	entity.SPPassword{},
+	entity.SPWallet{},
+	entity.SystemConfig{},
+	entity.Subscription{},
+	entity.DeletedUser{},
+	entity.DeletedServiceProvider{},
+	entity.DeletedSPPayrollTransaction{},
+	entity.SPPayrollTransaction{},
+	transaction.TelebirrAPIAccount{},
+}
+
+// samplesOf is a function that returns the samples matching the policy and the name of a field
+func samplesOf(policy, name string) []sample {
+	switch {
+	case policy == redact.Secret:
+		return secretSamples
+	case policy == redact.Mask && strings.Contains(name, "Email"):
+		return emailSamples
+	case policy == redact.Mask:
+		return phoneSamples
+	}
+	return nil
+}
+
+func TestStructRedactsTaggedFields(t *testing.T) {
+
+	for _, taggedEntity := range taggedEntities {
+
+		entityType := reflect.TypeOf(taggedEntity)
+		tagged := 0
+
+		for index := 0; index < entityType.NumField(); index++ {
+
+			field := entityType.Field(index)
+			policy, ok := field.Tag.Lookup(redact.Tag)
+			if !ok {
+				continue
+			}
+			tagged++
+
+			samples := samplesOf(policy, field.Name)
+			if len(samples) == 0 {
+				t.Fatalf("%s.%s has the unknown policy %q", entityType.Name(), field.Name, policy)
+			}
+
+			for _, sample := range samples {
+
+				value := reflect.New(entityType)
+				value.Elem().Field(index).SetString(sample.value)
+
+				redacted := reflect.ValueOf(redact.Struct(value.Interface())).Elem().Field(index).String()
+				if redacted != sample.redacted {
+					t.Errorf("%s.%s redacted %q to %q, expected %q", entityType.Name(), field.Name,
+						sample.value, redacted, sample.redacted)
+				}
+
+				if value.Elem().Field(index).String() != sample.value {
+					t.Errorf("%s.%s has been changed in the original struct", entityType.Name(), field.Name)
+				}
+			}
+		}
+
+		if tagged == 0 {
+			t.Errorf("%s has no tagged field", entityType.Name())
+		}
+	}
+}
+
+func TestToStringRedactsTaggedFields(t *testing.T) {
+
+	user := &entity.User{ID: "USER-1", PhoneNumber: "*251911223344", Email: "*abc123@example.com"}
+	userPassword := &entity.UserPassword{UserID: "USER-1", Password: "*abc123", Salt: "sha256:abc123"}
+
+	for _, output := range []string{user.ToString(), userPassword.ToString()} {
+		for _, secret := range []string{"251911223", "abc123"} {
+			if strings.Contains(output, secret) {
+				t.Errorf("%q leaks %q", output, secret)
+			}
+		}
+	}
+}
+
+func TestTextRedactsSensitiveKeys(t *testing.T) {
+
+	output, err := json.Marshal(map[string]string{"app_key": "*abc123", "password": "[REDACTED]",
+		"phone_number": "*251911223344", "email": "*abc123@example.com", "name": "Abebe"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	redacted := redact.Text(string(output))
+	expected := `{"app_key":"[REDACTED]","email":"*******@example.com","name":"Abebe",` +
+		`"password":"[REDACTED]","phone_number":"*********3344"}`
+	if redacted != expected {
+		t.Fatalf("redacted text to %s, expected %s", redacted, expected)
+	}
+}
+
+func TestFieldsRedactsSensitiveKeys(t *testing.T) {
+
+	redacted := redact.Fields(map[string]interface{}{"AppKey": "*abc123", "token": 42,
+		"note": "reach me at *abc123@example.com"})
+
+	if redacted["AppKey"] != "[REDACTED]" || redacted["token"] != "[REDACTED]" ||
+		redacted["note"] != "reach me at *a*****@example.com" {
+		t.Fatalf("unexpected redacted fields %v", redacted)
+	}
+}
@@ -33,6 +33,7 @@ type ISPPasswordRepository interface {
 type ISPWalletRepository interface {
 	Create(newSPWallet *entity.SPWallet) error
 	Find(providerID string) (*entity.SPWallet, error)
+	FindBalances(providerID string) []*entity.SPWalletBalance
 	Update(spWallet *entity.SPWallet) error
 	UpdateValue(spWallet *entity.SPWallet, columnName string, columnValue interface{}) error
 	Delete(providerID string) (*entity.SPWallet, error)
@@ -42,6 +42,20 @@ func (repo *SPWalletRepository) Find(providerID string) (*entity.SPWallet, error
 	return spWallet, nil
 }
 
+// FindBalances is a method that finds the per currency balances of a service provider wallet from the database
+func (repo *SPWalletRepository) FindBalances(providerID string) []*entity.SPWalletBalance {
+
+	var spWalletBalances []*entity.SPWalletBalance
+	err := repo.conn.Model(entity.SPWalletBalance{}).Where("provider_id = ?", providerID).
+		Order("currency_type ASC").Find(&spWalletBalances).Error
+
+	if err != nil {
+		return []*entity.SPWalletBalance{}
+	}
+
+	return spWalletBalances
+}
+
 // Update is a method that updates a certain service provider wallet entries in the database
 func (repo *SPWalletRepository) Update(spWallet *entity.SPWallet) error {
 
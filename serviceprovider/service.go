@@ -4,6 +4,8 @@ import "github.com/Benyam-S/onemembership/entity"
 
 // IService is an interface that defines all the service methods of a service provider struct
 type IService interface {
+	WithActor(actor string) IService
+
 	AddServiceProvider(newServiceProvider *entity.ServiceProvider) error
 	ValidateProviderProfile(serviceProvider *entity.ServiceProvider) entity.ErrMap
 	FindServiceProvider(identifier string) (*entity.ServiceProvider, error)
@@ -24,6 +26,7 @@ type IService interface {
 	AddSPWallet(newServiceProviderWallet *entity.SPWallet) error
 	ValidateSPWallet(serviceProviderWallet *entity.SPWallet) entity.ErrMap
 	FindSPWallet(identifier string) (*entity.SPWallet, error)
+	FindSPWalletBalances(providerID string) []*entity.SPWalletBalance
 	UpdateSPWallet(serviceProviderWallet *entity.SPWallet) error
 	UpdateSPWalletSingleValue(providerID, columnName string, columnValue interface{}) error
 	DeleteSPWallet(providerID string) (*entity.SPWallet, error)
@@ -84,7 +84,16 @@ func (service *Service) UpdateSPPassword(spPassword *entity.SPPassword) error {
 	service.logger.Log(fmt.Sprintf("Started service provider password updating process, SP Password => %s",
 		spPassword.ToString()), service.logger.Logs.ServiceProviderLogFile)
 
-	err := service.spPasswordRepo.Update(spPassword)
+	prevSPPassword, err := service.spPasswordRepo.Find(spPassword.ProviderID)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding service provider password before updating "+
+			"{ Provider ID : %s }, %s", spPassword.ProviderID, err.Error()))
+
+		return errors.New("unable to update password")
+	}
+
+	err = service.spPasswordRepo.Update(spPassword)
 	if err != nil {
 		/* ---------------------------- Logging ---------------------------- */
 		service.logger.LogToErrorFile(fmt.Sprintf("Error: For updating SP Password => %s, %s",
@@ -93,6 +102,10 @@ func (service *Service) UpdateSPPassword(spPassword *entity.SPPassword) error {
 		return errors.New("unable to update password")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionUpdate, spPassword.ProviderID, prevSPPassword,
+		spPassword)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished service provider password updating process, SP Password => %s",
 		spPassword.ToString()), service.logger.Logs.ServiceProviderLogFile)
@@ -116,6 +129,9 @@ func (service *Service) DeleteSPPassword(providerID string) (*entity.SPPassword,
 		return nil, errors.New("unable to delete password")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionDelete, providerID, spPassword, nil)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished service provider password deleting process, Deleted SP Password => %s",
 		spPassword.ToString()), service.logger.Logs.ServiceProviderLogFile)
@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/Benyam-S/onemembership/audit"
 	"github.com/Benyam-S/onemembership/common"
 	"github.com/Benyam-S/onemembership/deleted"
 	"github.com/Benyam-S/onemembership/entity"
@@ -25,6 +26,8 @@ type Service struct {
 	feedbackService     feedback.IService
 	deletedService      deleted.IService
 	cmService           common.IService
+	auditService        audit.IService
+	actor               string // The actor the changes are recorded on behalf of in the audit trail
 	logger              *log.Logger
 }
 
@@ -32,11 +35,30 @@ type Service struct {
 func NewServiceProviderService(serviceProviderRepository serviceprovider.IServiceProviderRepository,
 	spPasswordRepository serviceprovider.ISPPasswordRepository, spWalletRepository serviceprovider.ISPWalletRepository,
 	preferenceService preference.IService, feedbackService feedback.IService, deletedService deleted.IService,
-	commonService common.IService, serviceProviderLogger *log.Logger) serviceprovider.IService {
+	commonService common.IService, auditService audit.IService, serviceProviderLogger *log.Logger) serviceprovider.IService {
 	return &Service{serviceProviderRepo: serviceProviderRepository, spPasswordRepo: spPasswordRepository,
 		spWalletRepo: spWalletRepository, preferenceService: preferenceService,
 		feedbackService: feedbackService, deletedService: deletedService, cmService: commonService,
-		logger: serviceProviderLogger}
+		auditService: auditService, actor: audit.SystemActor, logger: serviceProviderLogger}
+}
+
+// WithActor is a method that returns a copy of the service provider service that records its changes in the audit trail
+// on behalf of the given actor, such as the admin or the service provider that has made the request
+func (service *Service) WithActor(actor string) serviceprovider.IService {
+	serviceCopy := *service
+	serviceCopy.actor = actor
+	return &serviceCopy
+}
+
+// recordAudit is a method that appends an action performed on an entity to the audit trail on behalf of the actor,
+// the action has already taken place so a failure is only logged
+func (service *Service) recordAudit(action, entityID string, before, after interface{}) {
+	err := service.auditService.Record(service.actor, action, entityID, before, after)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For recording audit { Actor : %s, Action : %s, "+
+			"Entity ID : %s }, %s", service.actor, action, entityID, err.Error()))
+	}
 }
 
 // AddServiceProvider is a method that adds a new service provider to the system
@@ -270,7 +292,16 @@ func (service *Service) UpdateServiceProvider(serviceProvider *entity.ServicePro
 	service.logger.Log(fmt.Sprintf("Started service provider updating process, Service Provider => %s",
 		serviceProvider.ToString()), service.logger.Logs.ServiceProviderLogFile)
 
-	err := service.serviceProviderRepo.Update(serviceProvider)
+	prevServiceProvider, err := service.serviceProviderRepo.Find(serviceProvider.ID)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding service provider before updating "+
+			"{ Provider ID : %s }, %s", serviceProvider.ID, err.Error()))
+
+		return errors.New("unable to update service provider")
+	}
+
+	err = service.serviceProviderRepo.Update(serviceProvider)
 	if err != nil {
 		/* ---------------------------- Logging ---------------------------- */
 		service.logger.LogToErrorFile(fmt.Sprintf("Error: For updating Service Provider => %s, %s",
@@ -279,6 +310,10 @@ func (service *Service) UpdateServiceProvider(serviceProvider *entity.ServicePro
 		return errors.New("unable to update service provider")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionUpdate, serviceProvider.ID, prevServiceProvider,
+		serviceProvider)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished service provider updating process, Service Provider => %s",
 		serviceProvider.ToString()), service.logger.Logs.ServiceProviderLogFile)
@@ -292,8 +327,17 @@ func (service *Service) UpdateProviderSingleValue(providerID, columnName string,
 		"{ Service Provider ID : %s, Column Name : %s, Column Value : %s }", providerID, columnName, columnValue),
 		service.logger.Logs.ServiceProviderLogFile)
 
+	prevServiceProvider, err := service.serviceProviderRepo.Find(providerID)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding service provider before updating "+
+			"{ Provider ID : %s }, %s", providerID, err.Error()))
+
+		return errors.New("unable to update service provider")
+	}
+
 	serviceProvider := entity.ServiceProvider{ID: providerID}
-	err := service.serviceProviderRepo.UpdateValue(&serviceProvider, columnName, columnValue)
+	err = service.serviceProviderRepo.UpdateValue(&serviceProvider, columnName, columnValue)
 	if err != nil {
 		/* ---------------------------- Logging ---------------------------- */
 		service.logger.LogToErrorFile(fmt.Sprintf("Error: For single service provider value updating "+
@@ -303,6 +347,17 @@ func (service *Service) UpdateProviderSingleValue(providerID, columnName string,
 		return errors.New("unable to update service provider")
 	}
 
+	// Recording the change in the audit trail
+	updatedServiceProvider, err := service.serviceProviderRepo.Find(providerID)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding service provider after updating "+
+			"{ Provider ID : %s }, %s", providerID, err.Error()))
+	} else {
+		service.recordAudit(entity.AuditActionUpdate, providerID, prevServiceProvider,
+			updatedServiceProvider)
+	}
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished single service provider value updating process, Service Provider => %s",
 		serviceProvider.ToString()), service.logger.Logs.ServiceProviderLogFile)
@@ -319,13 +374,14 @@ func (service *Service) DeleteServiceProvider(providerID string) (*entity.Servic
 		return nil, err
 	}
 
-	deletedServiceProvider, err := service.deletedService.AddServiceProviderToTrash(serviceProvider)
+	deletedService := service.deletedService.WithActor(service.actor)
+	deletedServiceProvider, err := deletedService.AddServiceProviderToTrash(serviceProvider)
 	if err == nil {
 		// Trashing the service provider subscription transactions
-		service.deletedService.AddSPSubscriptionTranstactionsToTrash(providerID, deletedServiceProvider.ProviderID)
+		deletedService.AddSPSubscriptionTranstactionsToTrash(providerID, deletedServiceProvider.ProviderID)
 
 		// Trashing the service provider payroll transactions
-		service.deletedService.AddPayrollTranstactionsToTrash(providerID, deletedServiceProvider.ProviderID)
+		deletedService.AddPayrollTranstactionsToTrash(providerID, deletedServiceProvider.ProviderID)
 	}
 
 	/* ---------------------------- Logging ---------------------------- */
@@ -347,6 +403,9 @@ func (service *Service) DeleteServiceProvider(providerID string) (*entity.Servic
 	// Setting client id to null for feedbacks
 	service.feedbackService.SetFeedbackClientIDNull(providerID)
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionDelete, providerID, serviceProvider, nil)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished service provider deleting process, Deleted Service Provider => %s",
 		serviceProvider.ToString()), service.logger.Logs.ServiceProviderLogFile)
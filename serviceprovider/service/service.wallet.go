@@ -79,13 +79,27 @@ func (service *Service) FindSPWallet(providerID string) (*entity.SPWallet, error
 	return spWallet, nil
 }
 
+// FindSPWalletBalances is a method that returns the balances a service provider wallet holds in each currency
+func (service *Service) FindSPWalletBalances(providerID string) []*entity.SPWalletBalance {
+	return service.spWalletRepo.FindBalances(providerID)
+}
+
 // UpdateSPWallet is a method that updates a service provider wallet in the system
 func (service *Service) UpdateSPWallet(spWallet *entity.SPWallet) error {
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Started service provider wallet updating process, SP Wallet => %s",
 		spWallet.ToString()), service.logger.Logs.ServiceProviderLogFile)
 
-	err := service.spWalletRepo.Update(spWallet)
+	prevSPWallet, err := service.spWalletRepo.Find(spWallet.ProviderID)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding service provider wallet before updating "+
+			"{ Provider ID : %s }, %s", spWallet.ProviderID, err.Error()))
+
+		return errors.New("unable to update service provider wallet")
+	}
+
+	err = service.spWalletRepo.Update(spWallet)
 	if err != nil {
 		/* ---------------------------- Logging ---------------------------- */
 		service.logger.LogToErrorFile(fmt.Sprintf("Error: For updating SP Wallet => %s, %s",
@@ -94,6 +108,9 @@ func (service *Service) UpdateSPWallet(spWallet *entity.SPWallet) error {
 		return errors.New("unable to update service provider wallet")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionUpdate, spWallet.ProviderID, prevSPWallet, spWallet)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished service provider wallet updating process, SP => %s",
 		spWallet.ToString()), service.logger.Logs.ServiceProviderLogFile)
@@ -107,8 +124,17 @@ func (service *Service) UpdateSPWalletSingleValue(providerID, columnName string,
 		"{ Provider ID : %s, Column Name : %s, Column Value : %s }", providerID, columnName, columnValue),
 		service.logger.Logs.ServiceProviderLogFile)
 
+	prevSPWallet, err := service.spWalletRepo.Find(providerID)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding service provider wallet before updating "+
+			"{ Provider ID : %s }, %s", providerID, err.Error()))
+
+		return errors.New("unable to update service provider wallet")
+	}
+
 	spWallet := entity.SPWallet{ProviderID: providerID}
-	err := service.spWalletRepo.UpdateValue(&spWallet, columnName, columnValue)
+	err = service.spWalletRepo.UpdateValue(&spWallet, columnName, columnValue)
 	if err != nil {
 		/* ---------------------------- Logging ---------------------------- */
 		service.logger.LogToErrorFile(fmt.Sprintf("Error: For single service provider wallet value updating "+
@@ -118,6 +144,16 @@ func (service *Service) UpdateSPWalletSingleValue(providerID, columnName string,
 		return errors.New("unable to update service provider wallet")
 	}
 
+	// Recording the change in the audit trail
+	updatedSPWallet, err := service.spWalletRepo.Find(providerID)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding service provider wallet after updating "+
+			"{ Provider ID : %s }, %s", providerID, err.Error()))
+	} else {
+		service.recordAudit(entity.AuditActionUpdate, providerID, prevSPWallet, updatedSPWallet)
+	}
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished single service provider wallet value updating process, "+
 		"SP Wallet => %s", spWallet.ToString()), service.logger.Logs.ServiceProviderLogFile)
@@ -140,6 +176,9 @@ func (service *Service) DeleteSPWallet(providerID string) (*entity.SPWallet, err
 		return nil, errors.New("unable to delete service provider")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionDelete, providerID, spWallet, nil)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished service provider wallet deleting process, Deleted SP Wallet => %s",
 		spWallet.ToString()), service.logger.Logs.ServiceProviderLogFile)
@@ -0,0 +1,127 @@
+package cancellation
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/log"
+	"github.com/Benyam-S/onemembership/money"
+	"github.com/Benyam-S/onemembership/subscription"
+	"github.com/Benyam-S/onemembership/subscriptionplan"
+	"github.com/Benyam-S/onemembership/transaction"
+)
+
+// Result is a type that defines the outcome of a subscription cancellation
+type Result struct {
+	Subscription     *entity.Subscription
+	RevokedChatIDs   []int64                // Chats the subscriber should be removed from, only set for immediate cancellations
+	RevokedChatLinks []*entity.UserChatLink // Chat links that have been revoked, only set for immediate cancellations
+	Refund           *entity.SubscriptionTransaction
+}
+
+// Service is a type that defines a subscription cancellation service
+type Service struct {
+	subscriptionService     subscription.IService
+	subscriptionPlanService subscriptionplan.IService
+	transactionService      transaction.IService
+	logger                  *log.Logger
+}
+
+// NewCancellationService is a function that returns a new subscription cancellation service
+func NewCancellationService(subscriptionService subscription.IService, subscriptionPlanService subscriptionplan.IService,
+	transactionService transaction.IService, subscriptionLogger *log.Logger) *Service {
+	return &Service{subscriptionService: subscriptionService, subscriptionPlanService: subscriptionPlanService,
+		transactionService: transactionService, logger: subscriptionLogger}
+}
+
+// CancelSubscription is a method that cancels a subscription using the given cancellation mode.
+// When refund is requested the prorated value of the remaining time is refunded through a reversing transaction,
+// only immediate cancellations can be refunded since end of term cancellations keep their access.
+// If the refund fails the cancellation is kept and the error is returned along with the result,
+// so the refund can be retried through the transaction service.
+func (service *Service) CancelSubscription(subscriptionID, mode string, refund bool) (*Result, error) {
+
+	if refund && mode != entity.CancellationImmediate {
+		return nil, errors.New("only immediate cancellations can be refunded")
+	}
+
+	currentSubscription, err := service.subscriptionService.FindSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The remaining value has to be calculated before the subscription ends
+	refundAmount := service.subscriptionService.RemainingValue(currentSubscription, time.Now())
+
+	cancelledSubscription, revokedChatLinks, err := service.subscriptionService.CancelSubscription(subscriptionID, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Subscription: cancelledSubscription, RevokedChatLinks: revokedChatLinks}
+	if mode == entity.CancellationImmediate {
+		result.RevokedChatIDs = service.resolveChatIDs(cancelledSubscription, revokedChatLinks)
+	}
+
+	if !refund || cancelledSubscription.TransactionID == "" {
+		return result, nil
+	}
+
+	subscriptionTransaction, err := service.transactionService.FindSubscriptionTransaction(
+		cancelledSubscription.TransactionID)
+	if err != nil {
+		return result, err
+	}
+
+	// The remaining value is held in the plan's currency so it is converted with the rate the payment was made at
+	if subscriptionTransaction.OriginalCurrency != "" &&
+		subscriptionTransaction.OriginalCurrency != subscriptionTransaction.CurrencyType {
+		refundAmount = refundAmount.Convert(subscriptionTransaction.ExchangeRate)
+	}
+
+	// The provider can't refund more than it has received for the subscription
+	refundAmount = money.Min(refundAmount, subscriptionTransaction.ReceivedAmount)
+	if refundAmount <= 0 {
+		return result, nil
+	}
+
+	result.Refund, err = service.transactionService.RefundSubscriptionTransaction(subscriptionTransaction.ID,
+		refundAmount)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For refunding cancelled subscription "+
+			"{ Subscription ID : %s, Amount : %s }, %s", cancelledSubscription.ID, refundAmount, err.Error()))
+
+		return result, err
+	}
+
+	return result, nil
+}
+
+// resolveChatIDs is a method that returns the chats linked to the subscription plan
+// together with the chats of the revoked chat links
+func (service *Service) resolveChatIDs(cancelledSubscription *entity.Subscription,
+	revokedChatLinks []*entity.UserChatLink) []int64 {
+
+	chatIDs := make([]int64, 0)
+	addedChatIDs := make(map[int64]bool)
+
+	planChatLinks := service.subscriptionPlanService.FindMultiplePlanChatLinks(cancelledSubscription.SubscriptionPlanID)
+	for _, planChatLink := range planChatLinks {
+		if planChatLink.PlanID == cancelledSubscription.SubscriptionPlanID && !addedChatIDs[planChatLink.ChatID] {
+			addedChatIDs[planChatLink.ChatID] = true
+			chatIDs = append(chatIDs, planChatLink.ChatID)
+		}
+	}
+
+	for _, userChatLink := range revokedChatLinks {
+		if !addedChatIDs[userChatLink.ChatID] {
+			addedChatIDs[userChatLink.ChatID] = true
+			chatIDs = append(chatIDs, userChatLink.ChatID)
+		}
+	}
+
+	return chatIDs
+}
@@ -0,0 +1,200 @@
+package expiry
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/log"
+	"github.com/Benyam-S/onemembership/subscription"
+	"github.com/Benyam-S/onemembership/subscriptionplan"
+)
+
+// Clock is a type that defines a function that returns the current time, it can be replaced to drive the worker in tests
+type Clock func() time.Time
+
+// Event is a type that defines a subscription expiry event emitted by the expiry worker
+type Event struct {
+	Stage         string // Holds one of the expiry stage constants
+	Subscription  *entity.Subscription
+	ChatIDs       []int64                // Chats the subscriber should be removed from, only set for the removed stage
+	UserChatLinks []*entity.UserChatLink // Invite links generated for the subscriber, only set for the removed stage
+}
+
+// Handler is a type that defines a function that handles an expiry event,
+// if it returns an error the event will be emitted again on the next scan
+type Handler func(event *Event) error
+
+// Worker is a type that defines a background worker that acts on expiring subscriptions
+type Worker struct {
+	mu                      sync.Mutex
+	subscriptionService     subscription.IService
+	subscriptionPlanService subscriptionplan.IService
+	handler                 Handler
+	noticePeriod            time.Duration // How long before expiration the expiring soon event is emitted
+	gracePeriod             time.Duration // How long after expiration the subscriber is kept in the chats
+	now                     Clock
+	unsavedStages           map[string]string // Handled stages that couldn't be stored, keyed by subscription id
+	logger                  *log.Logger
+}
+
+// NewExpiryWorker is a function that returns a new subscription expiry worker, if clock is nil time.Now is used
+func NewExpiryWorker(subscriptionService subscription.IService, subscriptionPlanService subscriptionplan.IService,
+	handler Handler, noticePeriod, gracePeriod time.Duration, clock Clock, subscriptionLogger *log.Logger) *Worker {
+
+	if clock == nil {
+		clock = time.Now
+	}
+
+	return &Worker{subscriptionService: subscriptionService, subscriptionPlanService: subscriptionPlanService,
+		handler: handler, noticePeriod: noticePeriod, gracePeriod: gracePeriod, now: clock,
+		unsavedStages: make(map[string]string), logger: subscriptionLogger}
+}
+
+// Start is a method that scans for expiring subscriptions every interval until the stop channel is closed
+func (worker *Worker) Start(interval time.Duration, stop <-chan struct{}) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		worker.Scan()
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan is a method that performs a single pass over the expiring subscriptions and emits the due events.
+// The reached stage is stored with the subscription so a restarted worker doesn't emit the same event twice,
+// a stage that couldn't be stored is stored again on the next scan without emitting its event again.
+func (worker *Worker) Scan() {
+
+	worker.mu.Lock()
+	defer worker.mu.Unlock()
+
+	now := worker.now()
+
+	/* ---------------------------- Logging ---------------------------- */
+	worker.logger.Log(fmt.Sprintf("Started subscription expiry scanning process { Now : %s }", now),
+		worker.logger.Logs.SubscriptionLogFile)
+
+	subscriptions := worker.subscriptionService.FindExpiringSubscriptions(now.Add(worker.noticePeriod))
+	for _, subscription := range subscriptions {
+
+		stage := worker.dueStage(subscription, now)
+		if stage == "" || stage == subscription.ExpiryStage {
+			continue
+		}
+
+		// An event whose stage couldn't be stored has already been handled, so only storing the stage again
+		if worker.unsavedStages[subscription.ID] != stage {
+			event := &Event{Stage: stage, Subscription: subscription}
+			if stage == entity.ExpiryStageRemoved {
+				event.ChatIDs, event.UserChatLinks = worker.resolveChats(subscription)
+			}
+
+			if err := worker.handler(event); err != nil {
+				/* ---------------------------- Logging ---------------------------- */
+				worker.logger.LogToErrorFile(fmt.Sprintf("Error: For handling subscription expiry event "+
+					"{ Subscription ID : %s, Stage : %s }, %s", subscription.ID, stage, err.Error()))
+				continue
+			}
+		}
+
+		if err := worker.saveStage(subscription, stage); err != nil {
+			/* ---------------------------- Logging ---------------------------- */
+			worker.logger.LogToErrorFile(fmt.Sprintf("Error: For storing subscription expiry stage "+
+				"{ Subscription ID : %s, Stage : %s }, %s", subscription.ID, stage, err.Error()))
+
+			worker.unsavedStages[subscription.ID] = stage
+			continue
+		}
+
+		delete(worker.unsavedStages, subscription.ID)
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	worker.logger.Log(fmt.Sprintf("Finished subscription expiry scanning process { Scanned : %d }", len(subscriptions)),
+		worker.logger.Logs.SubscriptionLogFile)
+}
+
+// saveStage is a method that stores the expiry stage a subscription has reached,
+// once the subscriber has been removed from the chats the subscription becomes part of the history
+func (worker *Worker) saveStage(subscription *entity.Subscription, stage string) error {
+
+	prevStage := subscription.ExpiryStage
+	subscription.ExpiryStage = stage
+
+	var err error
+	if stage == entity.ExpiryStageRemoved {
+		status := entity.SubscriptionStatusExpired
+		if subscription.CancellationMode == entity.CancellationEndOfTerm {
+			status = entity.SubscriptionStatusCancelled
+		}
+
+		err = worker.subscriptionService.UpdateSubscriptionStatus(subscription, status)
+	} else {
+		err = worker.subscriptionService.UpdateSubscription(subscription)
+	}
+
+	if err != nil {
+		subscription.ExpiryStage = prevStage
+		return err
+	}
+
+	return nil
+}
+
+// dueStage is a method that returns the expiry stage a subscription should be in at the given time
+func (worker *Worker) dueStage(subscription *entity.Subscription, now time.Time) string {
+
+	switch {
+	case !now.Before(subscription.ExpiresAt.Add(worker.gracePeriod)):
+		return entity.ExpiryStageRemoved
+	case !now.Before(subscription.ExpiresAt):
+		return entity.ExpiryStageExpired
+	case !now.Before(subscription.ExpiresAt.Add(-worker.noticePeriod)):
+		// Stages only move forward, so a subscription that has already expired isn't marked as expiring again
+		if subscription.ExpiryStage == "" {
+			return entity.ExpiryStageExpiringSoon
+		}
+	}
+
+	return ""
+}
+
+// resolveChats is a method that returns the chats linked to the subscription plan
+// together with the chat links that have been generated for the subscriber
+func (worker *Worker) resolveChats(subscription *entity.Subscription) ([]int64, []*entity.UserChatLink) {
+
+	chatIDs := make([]int64, 0)
+	userChatLinks := make([]*entity.UserChatLink, 0)
+	addedChatIDs := make(map[int64]bool)
+
+	planChatLinks := worker.subscriptionPlanService.FindMultiplePlanChatLinks(subscription.SubscriptionPlanID)
+	for _, planChatLink := range planChatLinks {
+		if planChatLink.PlanID == subscription.SubscriptionPlanID && !addedChatIDs[planChatLink.ChatID] {
+			addedChatIDs[planChatLink.ChatID] = true
+			chatIDs = append(chatIDs, planChatLink.ChatID)
+		}
+	}
+
+	for _, userChatLink := range worker.subscriptionPlanService.FindMultipleUserChatLinks(subscription.SubscriberID) {
+		if userChatLink.UserID != subscription.SubscriberID || userChatLink.PlanID != subscription.SubscriptionPlanID {
+			continue
+		}
+
+		userChatLinks = append(userChatLinks, userChatLink)
+		if !addedChatIDs[userChatLink.ChatID] {
+			addedChatIDs[userChatLink.ChatID] = true
+			chatIDs = append(chatIDs, userChatLink.ChatID)
+		}
+	}
+
+	return chatIDs, userChatLinks
+}
@@ -0,0 +1,258 @@
+package expiry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/log"
+	"github.com/Benyam-S/onemembership/subscription"
+	"github.com/Benyam-S/onemembership/subscriptionplan"
+)
+
+// fakeSubscriptionService is a type that defines an in-memory subscription service,
+// the methods the worker doesn't use are left to the embedded interface
+type fakeSubscriptionService struct {
+	subscription.IService
+	subscriptions map[string]entity.Subscription
+	failUpdates   bool
+}
+
+func (service *fakeSubscriptionService) FindExpiringSubscriptions(moment time.Time) []*entity.Subscription {
+
+	subscriptions := make([]*entity.Subscription, 0)
+	for _, storedSubscription := range service.subscriptions {
+		subscription := storedSubscription
+		if subscription.Status == entity.SubscriptionStatusActive && !subscription.ExpiresAt.After(moment) &&
+			subscription.ExpiryStage != entity.ExpiryStageRemoved {
+			subscriptions = append(subscriptions, &subscription)
+		}
+	}
+	return subscriptions
+}
+
+func (service *fakeSubscriptionService) UpdateSubscription(subscription *entity.Subscription) error {
+	if service.failUpdates {
+		return errors.New("unable to update subscription")
+	}
+
+	service.subscriptions[subscription.ID] = *subscription
+	return nil
+}
+
+func (service *fakeSubscriptionService) UpdateSubscriptionStatus(subscription *entity.Subscription, status string) error {
+	prevStatus := subscription.Status
+	subscription.Status = status
+
+	if err := service.UpdateSubscription(subscription); err != nil {
+		subscription.Status = prevStatus
+		return err
+	}
+	return nil
+}
+
+// fakeSubscriptionPlanService is a type that defines a subscription plan service holding fixed chat links
+type fakeSubscriptionPlanService struct {
+	subscriptionplan.IService
+	planChatLinks []*entity.PlanChatLink
+	userChatLinks []*entity.UserChatLink
+}
+
+func (service *fakeSubscriptionPlanService) FindMultiplePlanChatLinks(identifier interface{}) []*entity.PlanChatLink {
+	return service.planChatLinks
+}
+
+func (service *fakeSubscriptionPlanService) FindMultipleUserChatLinks(identifier interface{}) []*entity.UserChatLink {
+	return service.userChatLinks
+}
+
+// fakeClock is a type that defines a clock that only moves when it is told to
+type fakeClock struct {
+	now time.Time
+}
+
+func (clock *fakeClock) Now() time.Time {
+	return clock.now
+}
+
+const (
+	noticePeriod = 3 * 24 * time.Hour
+	gracePeriod  = 24 * time.Hour
+)
+
+var expiresAt = time.Date(2021, time.June, 10, 12, 0, 0, 0, time.UTC)
+
+// newTestWorker is a function that returns an expiry worker over the given services that records the emitted events
+func newTestWorker(subscriptionService *fakeSubscriptionService, clock *fakeClock,
+	events *[]*Event, handlerErr *error) *Worker {
+
+	subscriptionPlanService := &fakeSubscriptionPlanService{
+		planChatLinks: []*entity.PlanChatLink{{PlanID: "PLAN-1", ChatID: 100}, {PlanID: "PLAN-1", ChatID: 200}},
+		userChatLinks: []*entity.UserChatLink{{UserID: "USER-1", PlanID: "PLAN-1", ChatID: 100}},
+	}
+
+	handler := func(event *Event) error {
+		if handlerErr != nil && *handlerErr != nil {
+			return *handlerErr
+		}
+
+		*events = append(*events, event)
+		return nil
+	}
+
+	return NewExpiryWorker(subscriptionService, subscriptionPlanService, handler, noticePeriod, gracePeriod,
+		clock.Now, log.NewLogger(&log.LogContainer{}, log.None))
+}
+
+func newTestSubscriptionService(cancellationMode string) *fakeSubscriptionService {
+	return &fakeSubscriptionService{subscriptions: map[string]entity.Subscription{
+		"SUB-1": {ID: "SUB-1", SubscriberID: "USER-1", SubscriptionPlanID: "PLAN-1", ExpiresAt: expiresAt,
+			Status: entity.SubscriptionStatusActive, CancellationMode: cancellationMode},
+	}}
+}
+
+// stages is a function that returns the stages of the given events in order
+func stages(events []*Event) []string {
+	emittedStages := make([]string, 0)
+	for _, event := range events {
+		emittedStages = append(emittedStages, event.Stage)
+	}
+	return emittedStages
+}
+
+func equalStages(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for index := range a {
+		if a[index] != b[index] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestScanEmitsEachStageOnce(t *testing.T) {
+
+	subscriptionService := newTestSubscriptionService("")
+	clock := &fakeClock{now: expiresAt.Add(-4 * 24 * time.Hour)}
+	var events []*Event
+	worker := newTestWorker(subscriptionService, clock, &events, nil)
+
+	moments := []time.Time{
+		expiresAt.Add(-4 * 24 * time.Hour), // Before the notice period
+		expiresAt.Add(-2 * 24 * time.Hour), // Expiring soon
+		expiresAt.Add(-24 * time.Hour),     // Still expiring soon
+		expiresAt.Add(time.Hour),           // Expired
+		expiresAt.Add(2 * time.Hour),       // Within the grace period
+		expiresAt.Add(gracePeriod),         // Removed
+		expiresAt.Add(2 * gracePeriod),     // Already removed
+	}
+	for _, moment := range moments {
+		clock.now = moment
+		worker.Scan()
+	}
+
+	expected := []string{entity.ExpiryStageExpiringSoon, entity.ExpiryStageExpired, entity.ExpiryStageRemoved}
+	if !equalStages(stages(events), expected) {
+		t.Fatalf("emitted stages %v, expected %v", stages(events), expected)
+	}
+
+	removedEvent := events[2]
+	if len(removedEvent.ChatIDs) != 2 || len(removedEvent.UserChatLinks) != 1 {
+		t.Fatalf("unexpected removed event chats %v and links %v", removedEvent.ChatIDs, removedEvent.UserChatLinks)
+	}
+
+	stored := subscriptionService.subscriptions["SUB-1"]
+	if stored.ExpiryStage != entity.ExpiryStageRemoved || stored.Status != entity.SubscriptionStatusExpired {
+		t.Fatalf("unexpected stored subscription stage %q and status %q", stored.ExpiryStage, stored.Status)
+	}
+}
+
+func TestScanSkipsToTheDueStage(t *testing.T) {
+
+	subscriptionService := newTestSubscriptionService(entity.CancellationEndOfTerm)
+	clock := &fakeClock{now: expiresAt.Add(2 * gracePeriod)}
+	var events []*Event
+	worker := newTestWorker(subscriptionService, clock, &events, nil)
+
+	worker.Scan()
+
+	expected := []string{entity.ExpiryStageRemoved}
+	if !equalStages(stages(events), expected) {
+		t.Fatalf("emitted stages %v, expected %v", stages(events), expected)
+	}
+
+	if status := subscriptionService.subscriptions["SUB-1"].Status; status != entity.SubscriptionStatusCancelled {
+		t.Fatalf("cancelled subscription has been stored with status %q", status)
+	}
+}
+
+func TestRestartedWorkerDoesNotEmitAgain(t *testing.T) {
+
+	subscriptionService := newTestSubscriptionService("")
+	clock := &fakeClock{now: expiresAt.Add(time.Hour)}
+	var events []*Event
+
+	newTestWorker(subscriptionService, clock, &events, nil).Scan()
+	newTestWorker(subscriptionService, clock, &events, nil).Scan()
+
+	expected := []string{entity.ExpiryStageExpired}
+	if !equalStages(stages(events), expected) {
+		t.Fatalf("emitted stages %v, expected %v", stages(events), expected)
+	}
+}
+
+func TestFailedHandlerIsRetried(t *testing.T) {
+
+	subscriptionService := newTestSubscriptionService("")
+	clock := &fakeClock{now: expiresAt.Add(gracePeriod)}
+	var events []*Event
+	handlerErr := errors.New("unable to remove subscriber")
+	worker := newTestWorker(subscriptionService, clock, &events, &handlerErr)
+
+	worker.Scan()
+	if len(events) != 0 || subscriptionService.subscriptions["SUB-1"].ExpiryStage != "" {
+		t.Fatal("stage has been stored although the handler failed")
+	}
+
+	handlerErr = nil
+	worker.Scan()
+
+	expected := []string{entity.ExpiryStageRemoved}
+	if !equalStages(stages(events), expected) {
+		t.Fatalf("emitted stages %v, expected %v", stages(events), expected)
+	}
+}
+
+func TestUnsavedStageIsNotEmittedAgain(t *testing.T) {
+
+	subscriptionService := newTestSubscriptionService("")
+	subscriptionService.failUpdates = true
+	clock := &fakeClock{now: expiresAt.Add(gracePeriod)}
+	var events []*Event
+	worker := newTestWorker(subscriptionService, clock, &events, nil)
+
+	worker.Scan()
+	worker.Scan()
+
+	stored := subscriptionService.subscriptions["SUB-1"]
+	if stored.ExpiryStage != "" || stored.Status != entity.SubscriptionStatusActive {
+		t.Fatal("subscription has been changed although the update failed")
+	}
+
+	subscriptionService.failUpdates = false
+	worker.Scan()
+	worker.Scan()
+
+	expected := []string{entity.ExpiryStageRemoved}
+	if !equalStages(stages(events), expected) {
+		t.Fatalf("emitted stages %v, expected %v", stages(events), expected)
+	}
+
+	stored = subscriptionService.subscriptions["SUB-1"]
+	if stored.ExpiryStage != entity.ExpiryStageRemoved || stored.Status != entity.SubscriptionStatusExpired {
+		t.Fatalf("unexpected stored subscription stage %q and status %q", stored.ExpiryStage, stored.Status)
+	}
+}
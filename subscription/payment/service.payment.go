@@ -0,0 +1,51 @@
+package payment
+
+import (
+	"fmt"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/log"
+	"github.com/Benyam-S/onemembership/subscription"
+	"github.com/Benyam-S/onemembership/transaction"
+)
+
+// Service is a type that defines a service that grants subscriptions for the payments made through the payment gateways
+type Service struct {
+	subscriptionService subscription.IService
+	transactionService  transaction.IService
+	logger              *log.Logger
+}
+
+// NewPaymentService is a function that returns a new subscription payment service
+func NewPaymentService(subscriptionService subscription.IService, transactionService transaction.IService,
+	subscriptionLogger *log.Logger) *Service {
+	return &Service{subscriptionService: subscriptionService, transactionService: transactionService,
+		logger: subscriptionLogger}
+}
+
+// HandlePaymentNotification is a method that applies a payment notification to the subscription transaction it belongs to,
+// the subscription is activated, renewed or moved to the new plan once the payment has been completed.
+// A completed payment whose activation fails is activated later by the reconciliation worker.
+func (service *Service) HandlePaymentNotification(gatewayID int64,
+	payload []byte) (*entity.SubscriptionTransaction, *entity.Subscription, error) {
+
+	subscriptionTransaction, err := service.transactionService.HandlePaymentNotification(gatewayID, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if subscriptionTransaction.Status != entity.TransactionStatusComplete {
+		return subscriptionTransaction, nil, nil
+	}
+
+	newSubscription, err := service.subscriptionService.ActivateSubscription(subscriptionTransaction)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For activating notified payment "+
+			"{ Subscription Transaction ID : %s }, %s", subscriptionTransaction.ID, err.Error()))
+
+		return subscriptionTransaction, nil, err
+	}
+
+	return subscriptionTransaction, newSubscription, nil
+}
@@ -0,0 +1,237 @@
+package planchange
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/log"
+	"github.com/Benyam-S/onemembership/money"
+	"github.com/Benyam-S/onemembership/subscription"
+	"github.com/Benyam-S/onemembership/subscriptionplan"
+	"github.com/Benyam-S/onemembership/transaction"
+)
+
+// Result is a type that defines the outcome of a subscription plan change
+type Result struct {
+	Subscription            *entity.Subscription // The new subscription, only set once the plan change has been activated
+	SubscriptionTransaction *entity.SubscriptionTransaction
+	PayURL                  string                 // Only set when the subscriber has to pay the difference
+	GrantedChatIDs          []int64                // Chats of the new plan the subscriber needs invite links for
+	RevokedChatIDs          []int64                // Chats of the current plan that aren't part of the new plan
+	RevokedChatLinks        []*entity.UserChatLink // Chat links generated for the subscriber to the revoked chats
+}
+
+// Service is a type that defines a subscription plan change service
+type Service struct {
+	subscriptionService     subscription.IService
+	subscriptionPlanService subscriptionplan.IService
+	transactionService      transaction.IService
+	logger                  *log.Logger
+}
+
+// NewPlanChangeService is a function that returns a new subscription plan change service
+func NewPlanChangeService(subscriptionService subscription.IService, subscriptionPlanService subscriptionplan.IService,
+	transactionService transaction.IService, subscriptionLogger *log.Logger) *Service {
+	return &Service{subscriptionService: subscriptionService, subscriptionPlanService: subscriptionPlanService,
+		transactionService: transactionService, logger: subscriptionLogger}
+}
+
+// ChangePlan is a method that moves an active subscription to another subscription plan of the same project.
+// The difference between the new plan price and the prorated value of the remaining time is charged through
+// a new subscription transaction, when the remaining value covers the new plan the surplus is credited as
+// additional time and the new subscription is activated right away.
+// The idempotency key, such as the bot update id, makes a repeated request return the same payment.
+func (service *Service) ChangePlan(idempotencyKey, subscriptionID, newPlanID string) (*Result, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started subscription plan change process { Subscription ID : %s, New Plan ID : %s }",
+		subscriptionID, newPlanID), service.logger.Logs.SubscriptionLogFile)
+
+	currentSubscription, err := service.subscriptionService.FindSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if currentSubscription.Status != entity.SubscriptionStatusActive || !currentSubscription.ExpiresAt.After(now) {
+		return nil, errors.New("subscription is not active")
+	}
+
+	newPlan, err := service.subscriptionPlanService.FindSubscriptionPlan(newPlanID)
+	if err != nil {
+		return nil, err
+	}
+
+	if newPlan.ProjectID != currentSubscription.ProjectID || newPlan.Status != entity.PlanStatusComplete {
+		return nil, errors.New("subscription plan is not available for this subscription")
+	}
+
+	if newPlan.ID == currentSubscription.SubscriptionPlanID {
+		return nil, errors.New("subscription is already on the given plan")
+	}
+
+	// Only one plan change can be in progress, so a repeated request returns the payment of the pending plan change
+	pendingTransaction, err := service.pendingPlanChange(currentSubscription)
+	if err != nil {
+		return nil, err
+	}
+
+	if pendingTransaction != nil {
+		if idempotencyKey == "" || string(pendingTransaction.IdempotencyKey) != idempotencyKey ||
+			pendingTransaction.PlanID != newPlan.ID {
+			return nil, errors.New("subscription already has a plan change awaiting payment")
+		}
+
+		result := &Result{SubscriptionTransaction: pendingTransaction, PayURL: pendingTransaction.PayURL}
+		result.GrantedChatIDs, result.RevokedChatIDs, result.RevokedChatLinks =
+			service.compareChats(currentSubscription, newPlan.ID)
+
+		return result, nil
+	}
+
+	// Prorating is only possible between plans of the same currency
+	remainingValue := service.subscriptionService.RemainingValue(currentSubscription, now)
+	difference, err := money.New(newPlan.Price, newPlan.Currency).
+		Sub(money.New(remainingValue, currentSubscription.SubscriptionPlanCurrency))
+	if err != nil {
+		return nil, errors.New("subscription plans with different currencies can not be prorated")
+	}
+
+	if _, err := service.subscriptionService.FindActiveSubscription(currentSubscription.SubscriberID,
+		newPlan.ID); err == nil {
+		return nil, errors.New("subscriber already has an active subscription to the given plan")
+	}
+
+	result := new(Result)
+	result.GrantedChatIDs, result.RevokedChatIDs, result.RevokedChatLinks =
+		service.compareChats(currentSubscription, newPlan.ID)
+
+	if difference.Amount > 0 {
+		subscriptionTransaction, payURL, err := service.transactionService.InitiateSubscriptionTransaction(
+			transaction.DefaultGatewayID, idempotencyKey, currentSubscription.SubscriberID, newPlan.ID,
+			currentSubscription.ProjectName, newPlan.Name, newPlan.Currency, entity.InitiatedFromPlanChange, "", "",
+			difference.Amount)
+		if err != nil {
+			return nil, err
+		}
+
+		// The new subscription is activated once the payment has been completed,
+		// crediting the remaining value calculated at checkout
+		subscriptionTransaction.CreditedAmount = remainingValue
+		err = service.subscriptionService.StartPlanChange(currentSubscription.ID, subscriptionTransaction)
+		if err != nil {
+			return nil, err
+		}
+		currentSubscription.PlanChangeTransactionID = subscriptionTransaction.ID
+
+		result.SubscriptionTransaction = subscriptionTransaction
+		result.PayURL = payURL
+
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.Log(fmt.Sprintf("Finished subscription plan change process, awaiting payment "+
+			"{ Subscription ID : %s, Subscription Transaction ID : %s }", currentSubscription.ID,
+			subscriptionTransaction.ID), service.logger.Logs.SubscriptionLogFile)
+
+		return result, nil
+	}
+
+	// Nothing has to be paid, so the credit is recorded through a completed credit transaction without an amount
+	subscriptionTransaction := service.transactionService.ConstructCreditSubscriptionTransaction(
+		currentSubscription.SubscriberID, newPlan.ID, currentSubscription.ProjectName, newPlan.Name, newPlan.Currency,
+		entity.InitiatedFromPlanChangeCredit)
+	subscriptionTransaction.CreditedAmount = remainingValue
+
+	err = service.subscriptionService.StartPlanChange(currentSubscription.ID, subscriptionTransaction)
+	if err != nil {
+		return nil, err
+	}
+	currentSubscription.PlanChangeTransactionID = subscriptionTransaction.ID
+
+	newSubscription, err := service.subscriptionService.ActivateSubscription(subscriptionTransaction)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Subscription = newSubscription
+	result.SubscriptionTransaction = subscriptionTransaction
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished subscription plan change process, Subscription => %s",
+		newSubscription.ToString()), service.logger.Logs.SubscriptionLogFile)
+
+	return result, nil
+}
+
+// pendingPlanChange is a method that returns the plan change transaction of the subscription that is still awaiting payment,
+// a plan change that has been paid but hasn't been activated yet blocks any other plan change
+func (service *Service) pendingPlanChange(currentSubscription *entity.Subscription) (*entity.SubscriptionTransaction, error) {
+
+	if currentSubscription.PlanChangeTransactionID == "" {
+		return nil, nil
+	}
+
+	subscriptionTransaction, err := service.transactionService.FindSubscriptionTransaction(
+		currentSubscription.PlanChangeTransactionID)
+	if err != nil {
+		return nil, nil
+	}
+
+	switch subscriptionTransaction.Status {
+	case entity.TransactionStatusPending:
+		return subscriptionTransaction, nil
+	case entity.TransactionStatusComplete:
+		if _, err := service.subscriptionService.FindActivatedSubscription(subscriptionTransaction.ID); err != nil {
+			return nil, errors.New("subscription already has a plan change awaiting activation")
+		}
+	}
+
+	return nil, nil
+}
+
+// compareChats is a method that returns the chats the subscriber gains and loses by moving to the new plan,
+// together with the subscriber's chat links to the lost chats
+func (service *Service) compareChats(currentSubscription *entity.Subscription,
+	newPlanID string) ([]int64, []int64, []*entity.UserChatLink) {
+
+	currentChatIDs := make(map[int64]bool)
+	newChatIDs := make(map[int64]bool)
+	grantedChatIDs := make([]int64, 0)
+	revokedChatIDs := make([]int64, 0)
+	revokedChatLinks := make([]*entity.UserChatLink, 0)
+
+	for _, planChatLink := range service.subscriptionPlanService.FindMultiplePlanChatLinks(
+		currentSubscription.SubscriptionPlanID) {
+		if planChatLink.PlanID == currentSubscription.SubscriptionPlanID {
+			currentChatIDs[planChatLink.ChatID] = true
+		}
+	}
+
+	for _, planChatLink := range service.subscriptionPlanService.FindMultiplePlanChatLinks(newPlanID) {
+		if planChatLink.PlanID != newPlanID || newChatIDs[planChatLink.ChatID] {
+			continue
+		}
+
+		newChatIDs[planChatLink.ChatID] = true
+		if !currentChatIDs[planChatLink.ChatID] {
+			grantedChatIDs = append(grantedChatIDs, planChatLink.ChatID)
+		}
+	}
+
+	for chatID := range currentChatIDs {
+		if !newChatIDs[chatID] {
+			revokedChatIDs = append(revokedChatIDs, chatID)
+		}
+	}
+
+	for _, userChatLink := range service.subscriptionPlanService.FindMultipleUserChatLinks(
+		currentSubscription.SubscriberID) {
+		if userChatLink.UserID == currentSubscription.SubscriberID &&
+			userChatLink.PlanID == currentSubscription.SubscriptionPlanID && !newChatIDs[userChatLink.ChatID] {
+			revokedChatLinks = append(revokedChatLinks, userChatLink)
+		}
+	}
+
+	return grantedChatIDs, revokedChatIDs, revokedChatLinks
+}
@@ -0,0 +1,182 @@
+package renewal
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/log"
+	"github.com/Benyam-S/onemembership/preference"
+	"github.com/Benyam-S/onemembership/subscription"
+	"github.com/Benyam-S/onemembership/transaction"
+)
+
+// EventRenewalRequested is a constant that states a renewal transaction has been created and its pay link should be sent
+const EventRenewalRequested = "Renewal_Requested"
+
+// EventRenewalLapsed is a constant that states a subscription has run out of renewal attempts
+const EventRenewalLapsed = "Renewal_Lapsed"
+
+// Clock is a type that defines a function that returns the current time, it can be replaced to drive the worker in tests
+type Clock func() time.Time
+
+// Event is a type that defines a renewal event emitted by the renewal worker
+type Event struct {
+	Type                    string
+	Subscription            *entity.Subscription
+	SubscriptionTransaction *entity.SubscriptionTransaction // Only set for the renewal requested event
+	PayURL                  string                          // Only set for the renewal requested event
+}
+
+// Handler is a type that defines a function that handles a renewal event, such as sending the pay link to the subscriber
+type Handler func(event *Event) error
+
+// Worker is a type that defines a background worker that renews recurring subscriptions
+type Worker struct {
+	mu                  sync.Mutex
+	subscriptionService subscription.IService
+	transactionService  transaction.IService
+	preferenceService   preference.IService
+	handler             Handler
+	leadPeriod          time.Duration // How long before expiration the first renewal attempt is made
+	retryInterval       time.Duration // How long to wait for a payment before making another attempt
+	maxAttempts         int64         // Number of attempts made before the subscription lapses
+	now                 Clock
+	logger              *log.Logger
+}
+
+// NewRenewalWorker is a function that returns a new subscription renewal worker, if clock is nil time.Now is used
+func NewRenewalWorker(subscriptionService subscription.IService, transactionService transaction.IService,
+	preferenceService preference.IService, handler Handler, leadPeriod, retryInterval time.Duration,
+	maxAttempts int64, clock Clock, subscriptionLogger *log.Logger) *Worker {
+
+	if clock == nil {
+		clock = time.Now
+	}
+
+	return &Worker{subscriptionService: subscriptionService, transactionService: transactionService,
+		preferenceService: preferenceService, handler: handler, leadPeriod: leadPeriod, retryInterval: retryInterval,
+		maxAttempts: maxAttempts, now: clock, logger: subscriptionLogger}
+}
+
+// Start is a method that scans for renewable subscriptions every interval until the stop channel is closed
+func (worker *Worker) Start(interval time.Duration, stop <-chan struct{}) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		worker.Scan()
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan is a method that performs a single pass over the renewable subscriptions.
+// The renewal state is stored with the subscription so a restarted worker continues where it stopped.
+func (worker *Worker) Scan() {
+
+	worker.mu.Lock()
+	defer worker.mu.Unlock()
+
+	now := worker.now()
+
+	/* ---------------------------- Logging ---------------------------- */
+	worker.logger.Log(fmt.Sprintf("Started subscription renewal scanning process { Now : %s }", now),
+		worker.logger.Logs.SubscriptionLogFile)
+
+	subscriptions := worker.subscriptionService.FindRenewableSubscriptions(now.Add(worker.leadPeriod))
+	for _, subscription := range subscriptions {
+
+		clientPreference, err := worker.preferenceService.FindClientPreference(subscription.SubscriberID)
+		if err == nil && clientPreference.RenewalOptOut {
+			continue
+		}
+
+		if subscription.RenewalStatus == entity.RenewalStatusPending && subscription.RenewalTransactionID != "" {
+			renewalTransaction, err := worker.transactionService.FindSubscriptionTransaction(subscription.RenewalTransactionID)
+
+			// The payment has been completed but the subscription hasn't been renewed, so renewing it here
+			if err == nil && renewalTransaction.Status == entity.TransactionStatusComplete {
+				worker.subscriptionService.ActivateSubscription(renewalTransaction)
+				continue
+			}
+		}
+
+		if subscription.RenewalAttempts > 0 && now.Before(subscription.RenewalAttemptedAt.Add(worker.retryInterval)) {
+			continue
+		}
+
+		if subscription.RenewalAttempts >= worker.maxAttempts {
+			worker.lapse(subscription)
+			continue
+		}
+
+		worker.attempt(subscription, now)
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	worker.logger.Log(fmt.Sprintf("Finished subscription renewal scanning process { Scanned : %d }", len(subscriptions)),
+		worker.logger.Logs.SubscriptionLogFile)
+}
+
+// attempt is a method that creates a new pending renewal transaction for the subscription and emits its pay link
+func (worker *Worker) attempt(subscription *entity.Subscription, now time.Time) {
+
+	// Keying the attempt makes a repeated attempt, such as after a failed update, return the same renewal transaction.
+	// The transaction holds the renewed subscription, so paying the pay link of an earlier attempt still renews it
+	idempotencyKey := fmt.Sprintf("renewal:%s:%d", subscription.ID, subscription.RenewalAttempts+1)
+	renewalTransaction, payURL, err := worker.transactionService.InitiateSubscriptionTransaction(
+		transaction.DefaultGatewayID, idempotencyKey, subscription.SubscriberID, subscription.SubscriptionPlanID,
+		subscription.ProjectName, subscription.SubscriptionPlanName, subscription.SubscriptionPlanCurrency,
+		entity.InitiatedFromRenewal, subscription.CouponID, subscription.ID, subscription.SubscriptionPlanPrice)
+
+	// A failed attempt still counts towards the dunning limit
+	subscription.RenewalAttempts++
+	subscription.RenewalAttemptedAt = now
+	subscription.RenewalStatus = entity.RenewalStatusPending
+	subscription.RenewalTransactionID = ""
+
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		worker.logger.LogToErrorFile(fmt.Sprintf("Error: For creating renewal transaction "+
+			"{ Subscription ID : %s, Attempt : %d }, %s", subscription.ID, subscription.RenewalAttempts, err.Error()))
+
+		worker.subscriptionService.UpdateSubscription(subscription)
+		return
+	}
+
+	subscription.RenewalTransactionID = renewalTransaction.ID
+	if err := worker.subscriptionService.UpdateSubscription(subscription); err != nil {
+		return
+	}
+
+	event := &Event{Type: EventRenewalRequested, Subscription: subscription,
+		SubscriptionTransaction: renewalTransaction, PayURL: payURL}
+	if err := worker.handler(event); err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		worker.logger.LogToErrorFile(fmt.Sprintf("Error: For handling renewal event "+
+			"{ Subscription ID : %s, Type : %s }, %s", subscription.ID, event.Type, err.Error()))
+	}
+}
+
+// lapse is a method that stops any further renewal attempt for the subscription and emits the lapsed event
+func (worker *Worker) lapse(subscription *entity.Subscription) {
+
+	subscription.RenewalStatus = entity.RenewalStatusLapsed
+	if err := worker.subscriptionService.UpdateSubscription(subscription); err != nil {
+		return
+	}
+
+	event := &Event{Type: EventRenewalLapsed, Subscription: subscription}
+	if err := worker.handler(event); err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		worker.logger.LogToErrorFile(fmt.Sprintf("Error: For handling renewal event "+
+			"{ Subscription ID : %s, Type : %s }, %s", subscription.ID, event.Type, err.Error()))
+	}
+}
@@ -1,14 +1,34 @@
 package subscription
 
-import "github.com/Benyam-S/onemembership/entity"
+import (
+	"errors"
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+)
+
+// ErrPlanChangeUnfinished is an error returned when a subscription's earlier plan change is still awaiting payment or activation
+var ErrPlanChangeUnfinished = errors.New("subscription already has an unfinished plan change")
 
 // ISubscriptionRepository is an interface that defines all the repository methods of a subscription struct
 type ISubscriptionRepository interface {
 	Construct(subscriberID, subscriptionPlanID string) (*entity.Subscription, error)
 	Create(newSubscription *entity.Subscription) error
+	Activate(newSubscription *entity.Subscription, subscriptionTransaction *entity.SubscriptionTransaction) error
+	StartTrial(newSubscription *entity.Subscription) error
+	StartPlanChange(subscriptionID string, planChangeTransaction *entity.SubscriptionTransaction) error
+	HasClaimedTrial(subscription *entity.Subscription) bool
 	Find(id string) (*entity.Subscription, error)
 	FindMultiple(identifier string) []*entity.Subscription
+	FindActive(subscriberID, subscriptionPlanID string) (*entity.Subscription, error)
+	History(subscriberID string, pageNum int64) ([]*entity.Subscription, int64)
+	FindExpiring(moment time.Time) []*entity.Subscription
+	FindRenewable(moment time.Time) []*entity.Subscription
+	FindRenewing(transactionID string) (*entity.Subscription, error)
+	FindChangingPlan(transactionID string) (*entity.Subscription, error)
+	FindActivated(transactionID string) (*entity.Subscription, error)
 	Update(subscription *entity.Subscription) error
+	Cancel(subscription *entity.Subscription) ([]*entity.UserChatLink, error)
 	Delete(id string) (*entity.Subscription, error)
 	DeleteMultiple(identifier string) []*entity.Subscription
 }
@@ -16,6 +36,7 @@ type ISubscriptionRepository interface {
 // ISPSubscriptionRepository is an interface that defines all the repository methods of a service provider subscription struct
 type ISPSubscriptionRepository interface {
 	Create(newSubscription *entity.SPSubscription) error
+	Activate(spSubscriptionTransaction *entity.SPSubscriptionTransaction) (*entity.SPSubscription, error)
 	Find(providerID string) (*entity.SPSubscription, error)
 	FindMultiple(planID string) []*entity.SPSubscription
 	Update(subscription *entity.SPSubscription) error
@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"errors"
+	"time"
+
 	"github.com/Benyam-S/onemembership/entity"
 	"github.com/Benyam-S/onemembership/subscription"
 	"github.com/jinzhu/gorm"
@@ -27,6 +30,73 @@ func (repo *SPSubscriptionRepository) Create(newSubscription *entity.SPSubscript
 	return nil
 }
 
+// Activate is a method that creates or extends the subscription of the service provider that has paid
+// the given completed transaction. The subscription is extended from its expiration date when it is still active,
+// and each transaction can only be used once.
+func (repo *SPSubscriptionRepository) Activate(
+	spSubscriptionTransaction *entity.SPSubscriptionTransaction) (*entity.SPSubscription, error) {
+
+	spSubscription := new(entity.SPSubscription)
+	err := repo.conn.Transaction(func(tx *gorm.DB) error {
+
+		lockedTransaction := new(entity.SPSubscriptionTransaction)
+		err := tx.Set("gorm:query_option", "FOR UPDATE").Model(lockedTransaction).
+			Where("id = ?", spSubscriptionTransaction.ID).First(lockedTransaction).Error
+		if err != nil {
+			return err
+		}
+
+		if lockedTransaction.Status != entity.TransactionStatusComplete {
+			return errors.New("service provider subscription transaction hasn't been completed")
+		}
+
+		spSubscriptionPlan := new(entity.SPSubscriptionPlan)
+		err = tx.Model(spSubscriptionPlan).Where("id = ?", lockedTransaction.PlanID).First(spSubscriptionPlan).Error
+		if err != nil {
+			return err
+		}
+
+		activeFrom := time.Now()
+		err = tx.Set("gorm:query_option", "FOR UPDATE").Model(spSubscription).
+			Where("provider_id = ?", lockedTransaction.ProviderID).First(spSubscription).Error
+		isNew := gorm.IsRecordNotFoundError(err)
+		if err != nil && !isNew {
+			return err
+		}
+
+		if !isNew && spSubscription.TransactionID == lockedTransaction.ID {
+			return errors.New("service provider subscription transaction has already been used for activation")
+		}
+
+		// Extending from the current expiration date so paying ahead of time doesn't shorten the subscription
+		if !isNew && spSubscription.ExpiresAt.After(activeFrom) {
+			activeFrom = spSubscription.ExpiresAt
+		}
+
+		spSubscription.ProviderID = lockedTransaction.ProviderID
+		spSubscription.SubscriptionPlanID = spSubscriptionPlan.ID
+		spSubscription.SubscriptionPlanName = spSubscriptionPlan.Name
+		spSubscription.SubscriptionPlanDuration = spSubscriptionPlan.Duration
+		spSubscription.SubscriptionPlanPrice = spSubscriptionPlan.Price
+		spSubscription.SubscriptionPlanCurrency = spSubscriptionPlan.Currency
+		spSubscription.TransactionID = lockedTransaction.ID
+
+		// Subscription plan duration is stored in days
+		spSubscription.ExpiresAt = activeFrom.AddDate(0, 0, int(spSubscriptionPlan.Duration))
+
+		if isNew {
+			return tx.Create(spSubscription).Error
+		}
+		return tx.Save(spSubscription).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return spSubscription, nil
+}
+
 // Find is a method that finds a certain service provider subscription from the database using an providerID,
 // also Find() uses only provider_id as a key for selection
 func (repo *SPSubscriptionRepository) Find(providerID string) (*entity.SPSubscription, error) {
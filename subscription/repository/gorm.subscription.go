@@ -1,9 +1,13 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"time"
 
 	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/money"
 	"github.com/Benyam-S/onemembership/subscription"
 	"github.com/Benyam-S/onemembership/tools"
 	"github.com/jinzhu/gorm"
@@ -57,7 +61,7 @@ func (repo *SubscriptionRepository) Construct(subscriberID, subscriptionPlanID s
 		SubscriptionPlanName        string
 		SubscriptionPlanBenfits     string
 		SubscriptionPlanDuration    int64
-		SubscriptionPlanPrice       float64
+		SubscriptionPlanPrice       money.Amount
 		SubscriptionPlanIsRecurring bool
 		SubscriptionPlanCurrency    string
 	}
@@ -117,6 +121,208 @@ func (repo *SubscriptionRepository) Create(newSubscription *entity.Subscription)
 	return nil
 }
 
+// Activate is a method that adds a new active subscription for a completed subscription transaction, supersedes
+// the subscriber's previous active subscription to the same plan in a single database transaction, the payment
+// has already been credited to the provider's wallet and posted to the ledger when the transaction was completed
+func (repo *SubscriptionRepository) Activate(newSubscription *entity.Subscription,
+	subscriptionTransaction *entity.SubscriptionTransaction) error {
+
+	return repo.conn.Transaction(func(tx *gorm.DB) error {
+
+		lockedTransaction, err := lockCompletedTransaction(tx, subscriptionTransaction.ID)
+		if err != nil {
+			return err
+		}
+
+		if !tools.IsUnique("transaction_id", lockedTransaction.ID, "subscriptions", tx) {
+			return errors.New("subscription transaction has already been used for activation")
+		}
+
+		totalNumOfSubscriptions := tools.CountMembers("subscriptions", tx)
+		newSubscription.ID = fmt.Sprintf("SUB-%s%d", tools.RandomStringGN(7), totalNumOfSubscriptions+1)
+
+		for !tools.IsUnique("id", newSubscription.ID, "subscriptions", tx) {
+			totalNumOfSubscriptions++
+			newSubscription.ID = fmt.Sprintf("SUB-%s%d", tools.RandomStringGN(7), totalNumOfSubscriptions+1)
+		}
+
+		// A plan change moves the subscriber's chat links over to the new plan
+		changingSubscription := new(entity.Subscription)
+		err = tx.Model(changingSubscription).Where("plan_change_transaction_id = ?", lockedTransaction.ID).
+			First(changingSubscription).Error
+		if err == nil {
+			err = switchUserChatLinks(tx, newSubscription.SubscriberID, changingSubscription.SubscriptionPlanID,
+				newSubscription.SubscriptionPlanID)
+			if err != nil {
+				return err
+			}
+		}
+
+		// The previous subscriptions are kept as history, so only one subscription per plan stays active
+		err = tx.Exec("UPDATE subscriptions SET status = ?, updated_at = ? WHERE ((subscriber_id = ? && "+
+			"subscription_plan_id = ?) || plan_change_transaction_id = ?) && status = ?", entity.SubscriptionStatusSuperseded,
+			time.Now(), newSubscription.SubscriberID, newSubscription.SubscriptionPlanID, lockedTransaction.ID,
+			entity.SubscriptionStatusActive).Error
+		if err != nil {
+			return err
+		}
+
+		newSubscription.TransactionID = lockedTransaction.ID
+		newSubscription.Status = entity.SubscriptionStatusActive
+		return tx.Create(newSubscription).Error
+	})
+}
+
+// StartTrial is a method that adds a new free trial subscription to the database if the subscriber hasn't claimed
+// a trial of the project yet, the project is locked so concurrent requests can't claim more than one trial
+func (repo *SubscriptionRepository) StartTrial(newSubscription *entity.Subscription) error {
+
+	return repo.conn.Transaction(func(tx *gorm.DB) error {
+
+		lockedProject := new(entity.Project)
+		err := tx.Set("gorm:query_option", "FOR UPDATE").Model(lockedProject).
+			Where("id = ?", newSubscription.ProjectID).First(lockedProject).Error
+		if err != nil {
+			return err
+		}
+
+		if hasClaimedTrial(tx, newSubscription) {
+			return errors.New("free trial has already been claimed for the project")
+		}
+
+		totalNumOfSubscriptions := tools.CountMembers("subscriptions", tx)
+		newSubscription.ID = fmt.Sprintf("SUB-%s%d", tools.RandomStringGN(7), totalNumOfSubscriptions+1)
+
+		for !tools.IsUnique("id", newSubscription.ID, "subscriptions", tx) {
+			totalNumOfSubscriptions++
+			newSubscription.ID = fmt.Sprintf("SUB-%s%d", tools.RandomStringGN(7), totalNumOfSubscriptions+1)
+		}
+
+		newSubscription.TransactionID = ""
+		newSubscription.IsTrial = true
+		newSubscription.Status = entity.SubscriptionStatusActive
+
+		return tx.Create(newSubscription).Error
+	})
+}
+
+// HasClaimedTrial is a method that checks whether the subscriber of a subscription has already claimed
+// a free trial of the subscription's project
+func (repo *SubscriptionRepository) HasClaimedTrial(subscription *entity.Subscription) bool {
+	return hasClaimedTrial(repo.conn, subscription)
+}
+
+// hasClaimedTrial is a function that checks whether a subscriber has already claimed a free trial of a project.
+// Trials claimed by deleted accounts sharing the subscriber's phone number or username are counted as well,
+// so a trial can't be claimed again by deleting and re-creating the account
+func hasClaimedTrial(tx *gorm.DB, subscription *entity.Subscription) bool {
+
+	var count struct{ Total int64 }
+	tx.Raw("SELECT COUNT(*) AS total FROM subscriptions S WHERE S.project_id = ? && S.is_trial = true && "+
+		"(S.subscriber_id = ? || EXISTS (SELECT 1 FROM deleted_users D WHERE "+
+		"(D.user_id = CONCAT(D.id, '_', S.subscriber_id) || "+
+		"(D.phone_number != '' && D.phone_number = S.subscriber_phone_number) || "+
+		"(D.username != '' && D.username = S.subscriber_user_name)) && "+
+		"((D.phone_number != '' && D.phone_number = ?) || (D.username != '' && D.username = ?))))",
+		subscription.ProjectID, subscription.SubscriberID, subscription.SubscriberPhoneNumber,
+		subscription.SubscriberUserName).Scan(&count)
+
+	return count.Total > 0
+}
+
+// StartPlanChange is a method that records the transaction of a plan change on the subscription together with the
+// remaining value the transaction has been credited with. The subscription is locked, so only one plan change can be
+// started while an earlier one is still awaiting payment or activation. A transaction without an id, such as a credit
+// transaction, is added in the same database transaction.
+func (repo *SubscriptionRepository) StartPlanChange(subscriptionID string,
+	planChangeTransaction *entity.SubscriptionTransaction) error {
+
+	return repo.conn.Transaction(func(tx *gorm.DB) error {
+
+		lockedSubscription := new(entity.Subscription)
+		err := tx.Set("gorm:query_option", "FOR UPDATE").Model(lockedSubscription).
+			Where("id = ?", subscriptionID).First(lockedSubscription).Error
+		if err != nil {
+			return err
+		}
+
+		if lockedSubscription.Status != entity.SubscriptionStatusActive {
+			return errors.New("subscription is not active")
+		}
+
+		if lockedSubscription.PlanChangeTransactionID != "" &&
+			lockedSubscription.PlanChangeTransactionID != planChangeTransaction.ID {
+
+			var unfinished int64
+			err = tx.Model(entity.SubscriptionTransaction{}).Where("id = ? && (status = ? || (status = ? && "+
+				"NOT EXISTS (SELECT 1 FROM subscriptions S WHERE S.transaction_id = subscription_transactions.id)))",
+				lockedSubscription.PlanChangeTransactionID, entity.TransactionStatusPending,
+				entity.TransactionStatusComplete).Count(&unfinished).Error
+			if err != nil {
+				return err
+			}
+
+			if unfinished > 0 {
+				return subscription.ErrPlanChangeUnfinished
+			}
+		}
+
+		if planChangeTransaction.ID == "" {
+			totalNumOfSubscriptionTransactions := tools.CountMembers("subscription_transactions", tx)
+			planChangeTransaction.ID = fmt.Sprintf("SBT-%s%d", tools.RandomStringGN(7),
+				totalNumOfSubscriptionTransactions+1)
+
+			for !tools.IsUnique("id", planChangeTransaction.ID, "subscription_transactions", tx) {
+				totalNumOfSubscriptionTransactions++
+				planChangeTransaction.ID = fmt.Sprintf("SBT-%s%d", tools.RandomStringGN(7),
+					totalNumOfSubscriptionTransactions+1)
+			}
+
+			err = tx.Create(planChangeTransaction).Error
+		} else {
+			err = tx.Exec("UPDATE subscription_transactions SET credited_amount = ?, updated_at = ? WHERE id = ?",
+				planChangeTransaction.CreditedAmount, time.Now(), planChangeTransaction.ID).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		return tx.Exec("UPDATE subscriptions SET plan_change_transaction_id = ?, updated_at = ? WHERE id = ?",
+			planChangeTransaction.ID, time.Now(), subscriptionID).Error
+	})
+}
+
+// lockCompletedTransaction is a function that locks a subscription transaction for the rest of the database transaction,
+// so concurrent uses of the same subscription transaction are serialized
+func lockCompletedTransaction(tx *gorm.DB, transactionID string) (*entity.SubscriptionTransaction, error) {
+
+	lockedTransaction := new(entity.SubscriptionTransaction)
+	err := tx.Set("gorm:query_option", "FOR UPDATE").Model(lockedTransaction).
+		Where("id = ?", transactionID).First(lockedTransaction).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if lockedTransaction.Status != entity.TransactionStatusComplete {
+		return nil, errors.New("subscription transaction hasn't been completed")
+	}
+
+	return lockedTransaction, nil
+}
+
+// switchUserChatLinks is a function that moves a user's chat links from one plan to another,
+// links to chats that aren't part of the new plan are removed
+func switchUserChatLinks(tx *gorm.DB, userID, fromPlanID, toPlanID string) error {
+
+	err := tx.Exec("UPDATE user_chat_links SET plan_id = ? WHERE user_id = ? && plan_id = ? && "+
+		"chat_id IN (SELECT chat_id FROM plan_chat_links WHERE plan_id = ?)", toPlanID, userID, fromPlanID, toPlanID).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Exec("DELETE FROM user_chat_links WHERE user_id = ? && plan_id = ?", userID, fromPlanID).Error
+}
+
 // Find is a method that finds a certain subscription from the database using an subscription id,
 // also Find() uses only id as a key for selection
 func (repo *SubscriptionRepository) Find(id string) (*entity.Subscription, error) {
@@ -146,6 +352,113 @@ func (repo *SubscriptionRepository) FindMultiple(identifier string) []*entity.Su
 	return subscriptions
 }
 
+// FindActive is a method that finds the subscription that is currently active for the given subscriber and plan,
+// also FindActive() uses subscriber_id and subscription_plan_id as a key for selection
+func (repo *SubscriptionRepository) FindActive(subscriberID, subscriptionPlanID string) (*entity.Subscription, error) {
+
+	subscription := new(entity.Subscription)
+	err := repo.conn.Model(subscription).Where("subscriber_id = ? && subscription_plan_id = ? && status = ?",
+		subscriberID, subscriptionPlanID, entity.SubscriptionStatusActive).Order("expires_at DESC").
+		First(subscription).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// History is a method that returns set of subscriptions a subscriber has ever had limited to the page number,
+// with the most recent subscription first
+func (repo *SubscriptionRepository) History(subscriberID string, pageNum int64) ([]*entity.Subscription, int64) {
+
+	var subscriptions []*entity.Subscription
+	var count float64
+
+	repo.conn.Raw("SELECT * FROM subscriptions WHERE subscriber_id = ? ORDER BY created_at DESC LIMIT ?, 20",
+		subscriberID, pageNum*20).Scan(&subscriptions)
+	repo.conn.Raw("SELECT COUNT(*) FROM subscriptions WHERE subscriber_id = ?", subscriberID).Count(&count)
+
+	var pageCount int64 = int64(math.Ceil(count / 20.0))
+	return subscriptions, pageCount
+}
+
+// FindExpiring is a method that finds active subscriptions that expire before the given moment
+// and whose subscribers haven't been removed from the subscribed chats yet
+func (repo *SubscriptionRepository) FindExpiring(moment time.Time) []*entity.Subscription {
+
+	var subscriptions []*entity.Subscription
+	err := repo.conn.Model(entity.Subscription{}).Where("status = ? && expires_at <= ? && "+
+		"(expiry_stage IS NULL || expiry_stage != ?)", entity.SubscriptionStatusActive, moment, entity.ExpiryStageRemoved).
+		Order("expires_at ASC").Find(&subscriptions).Error
+
+	if err != nil {
+		return []*entity.Subscription{}
+	}
+	return subscriptions
+}
+
+// FindRenewable is a method that finds active recurring subscriptions that expire before the given moment
+// and can still be renewed, meaning they haven't lapsed or been cancelled and their subscribers haven't been removed from the chats
+func (repo *SubscriptionRepository) FindRenewable(moment time.Time) []*entity.Subscription {
+
+	var subscriptions []*entity.Subscription
+	err := repo.conn.Model(entity.Subscription{}).Where("status = ? && subscription_plan_is_recurring = ? && "+
+		"expires_at <= ? && (renewal_status IS NULL || renewal_status != ?) && (expiry_stage IS NULL || expiry_stage != ?) && "+
+		"(cancellation_mode IS NULL || cancellation_mode != ?)", entity.SubscriptionStatusActive, true, moment,
+		entity.RenewalStatusLapsed, entity.ExpiryStageRemoved, entity.CancellationEndOfTerm).
+		Order("expires_at ASC").Find(&subscriptions).Error
+
+	if err != nil {
+		return []*entity.Subscription{}
+	}
+	return subscriptions
+}
+
+// FindRenewing is a method that finds the subscription a renewal transaction has been created for.
+// Each renewal attempt replaces the renewal_transaction_id of the subscription, so the subscription is matched
+// through the renewed subscription id the transactions of every attempt hold
+func (repo *SubscriptionRepository) FindRenewing(transactionID string) (*entity.Subscription, error) {
+
+	subscription := new(entity.Subscription)
+	err := repo.conn.Model(subscription).Where("id = (SELECT renewed_subscription_id FROM subscription_transactions "+
+		"WHERE id = ? && initiated_from = ?)", transactionID, entity.InitiatedFromRenewal).First(subscription).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// FindChangingPlan is a method that finds the subscription a plan change transaction has been created for,
+// also FindChangingPlan() uses only plan_change_transaction_id as a key for selection
+func (repo *SubscriptionRepository) FindChangingPlan(transactionID string) (*entity.Subscription, error) {
+
+	subscription := new(entity.Subscription)
+	err := repo.conn.Model(subscription).Where("plan_change_transaction_id = ?", transactionID).First(subscription).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// FindActivated is a method that finds the subscription a subscription transaction has activated,
+// also FindActivated() uses only transaction_id as a key for selection
+func (repo *SubscriptionRepository) FindActivated(transactionID string) (*entity.Subscription, error) {
+
+	subscription := new(entity.Subscription)
+	err := repo.conn.Model(subscription).Where("transaction_id = ?", transactionID).First(subscription).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
 // Update is a method that updates a certain subscription entries in the database
 func (repo *SubscriptionRepository) Update(subscription *entity.Subscription) error {
 
@@ -167,6 +480,50 @@ func (repo *SubscriptionRepository) Update(subscription *entity.Subscription) er
 	return nil
 }
 
+// Cancel is a method that saves a cancelled subscription, when the subscription has been cancelled immediately
+// the subscriber's chat links to the subscription plan are removed in the same database transaction and returned
+func (repo *SubscriptionRepository) Cancel(subscription *entity.Subscription) ([]*entity.UserChatLink, error) {
+
+	var revokedChatLinks []*entity.UserChatLink
+	err := repo.conn.Transaction(func(tx *gorm.DB) error {
+
+		prevSubscription := new(entity.Subscription)
+		err := tx.Set("gorm:query_option", "FOR UPDATE").Model(prevSubscription).
+			Where("id = ? && status = ?", subscription.ID, entity.SubscriptionStatusActive).First(prevSubscription).Error
+		if err != nil {
+			return errors.New("subscription is not active")
+		}
+
+		/* --------------------------- can change layer if needed --------------------------- */
+		subscription.CreatedAt = prevSubscription.CreatedAt
+		/* -------------------------------------- end --------------------------------------- */
+
+		err = tx.Save(subscription).Error
+		if err != nil {
+			return err
+		}
+
+		if subscription.CancellationMode != entity.CancellationImmediate {
+			return nil
+		}
+
+		err = tx.Model(entity.UserChatLink{}).Where("user_id = ? && plan_id = ?", subscription.SubscriberID,
+			subscription.SubscriptionPlanID).Find(&revokedChatLinks).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Exec("DELETE FROM user_chat_links WHERE user_id = ? && plan_id = ?", subscription.SubscriberID,
+			subscription.SubscriptionPlanID).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return revokedChatLinks, nil
+}
+
 // Delete is a method that deletes a certain subscription from the database using an subscription id.
 // In Delete() id is only used as an key
 func (repo *SubscriptionRepository) Delete(id string) (*entity.Subscription, error) {
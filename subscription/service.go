@@ -1,18 +1,35 @@
 package subscription
 
-import "github.com/Benyam-S/onemembership/entity"
+import (
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/money"
+)
 
 // IService is an interface that defines all the service methods of a subscription struct
 type IService interface {
 	ConstructSubscription(subscriberID, subscriptionPlanID string) (*entity.Subscription, error)
 	AddSubscription(newSubscription *entity.Subscription) error
+	ActivateSubscription(subscriptionTransaction *entity.SubscriptionTransaction) (*entity.Subscription, error)
+	StartTrialSubscription(subscriberID, subscriptionPlanID string, trialDays int64) (*entity.Subscription, error)
+	StartPlanChange(subscriptionID string, planChangeTransaction *entity.SubscriptionTransaction) error
 	FindSubscription(id string) (*entity.Subscription, error)
 	FindMultipleSubscriptions(identifier string) []*entity.Subscription
+	FindActiveSubscription(subscriberID, subscriptionPlanID string) (*entity.Subscription, error)
+	FindActivatedSubscription(transactionID string) (*entity.Subscription, error)
+	SubscriptionHistory(subscriberID string, pageNum int64) ([]*entity.Subscription, int64)
+	FindExpiringSubscriptions(moment time.Time) []*entity.Subscription
+	FindRenewableSubscriptions(moment time.Time) []*entity.Subscription
+	RemainingValue(subscription *entity.Subscription, moment time.Time) money.Amount
 	UpdateSubscription(subscription *entity.Subscription) error
+	UpdateSubscriptionStatus(subscription *entity.Subscription, status string) error
+	CancelSubscription(id, mode string) (*entity.Subscription, []*entity.UserChatLink, error)
 	DeleteSubscription(id string) (*entity.Subscription, error)
 	DeleteMultipleSubscriptions(identifier string) []*entity.Subscription
 
 	AddSPSubscription(newSubscription *entity.SPSubscription) error
+	ActivateSPSubscription(spSubscriptionTransaction *entity.SPSubscriptionTransaction) (*entity.SPSubscription, error)
 	FindSPSubscription(providerID string) (*entity.SPSubscription, error)
 	FindMultipleSPSubscriptions(planID string) []*entity.SPSubscription
 	UpdateSPSubscription(subscription *entity.SPSubscription) error
@@ -30,6 +30,35 @@ func (service *Service) AddSPSubscription(newSubscription *entity.SPSubscription
 	return nil
 }
 
+// ActivateSPSubscription is a method that creates or extends the subscription of a service provider
+// using a completed service provider subscription transaction
+func (service *Service) ActivateSPSubscription(
+	spSubscriptionTransaction *entity.SPSubscriptionTransaction) (*entity.SPSubscription, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started service provider subscription activation process, "+
+		"SP Subscription Transaction => %s", spSubscriptionTransaction.ToString()), service.logger.Logs.SubscriptionLogFile)
+
+	if spSubscriptionTransaction.Status != entity.TransactionStatusComplete {
+		return nil, errors.New("subscription transaction hasn't been completed")
+	}
+
+	subscription, err := service.spSubscriptionRepo.Activate(spSubscriptionTransaction)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For activating service provider subscription "+
+			"{ SP Subscription Transaction ID : %s }, %s", spSubscriptionTransaction.ID, err.Error()))
+
+		return nil, errors.New("unable to activate subscription")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished service provider subscription activation process, SP Subscription => %s",
+		subscription.ToString()), service.logger.Logs.SubscriptionLogFile)
+
+	return subscription, nil
+}
+
 // FindSPSubscription is a method that find and return a service provider subscription that matches the providerID value
 func (service *Service) FindSPSubscription(providerID string) (*entity.SPSubscription, error) {
 	/* ---------------------------- Logging ---------------------------- */
@@ -4,12 +4,33 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"time"
 
 	"github.com/Benyam-S/onemembership/entity"
 	"github.com/Benyam-S/onemembership/log"
+	"github.com/Benyam-S/onemembership/money"
 	"github.com/Benyam-S/onemembership/subscription"
 )
 
+// subscriptionStatusTransitions is a map that holds the statuses a subscription can move to from each status,
+// statuses that aren't listed are final
+var subscriptionStatusTransitions = map[string][]string{
+	entity.SubscriptionStatusActive: {entity.SubscriptionStatusExpired, entity.SubscriptionStatusCancelled,
+		entity.SubscriptionStatusSuperseded},
+}
+
+// isValidStatusTransition is a function that checks whether a subscription can move from one status to another
+func isValidStatusTransition(prevStatus, status string) bool {
+
+	for _, nextStatus := range subscriptionStatusTransitions[prevStatus] {
+		if nextStatus == status {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Service is a type that defines a subscription service
 type Service struct {
 	subscriptionRepo   subscription.ISubscriptionRepository
@@ -70,6 +91,185 @@ func (service *Service) AddSubscription(newSubscription *entity.Subscription) er
 	return nil
 }
 
+// ActivateSubscription is a method that grants a subscription for a completed subscription transaction.
+// It constructs the subscription snapshot and sets its expiration date atomically,
+// any previously active subscription to the same plan is superseded and kept as history.
+func (service *Service) ActivateSubscription(subscriptionTransaction *entity.SubscriptionTransaction) (*entity.Subscription, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started subscription activation process, Subscription Transaction => %s",
+		subscriptionTransaction.ToString()), service.logger.Logs.SubscriptionLogFile)
+
+	if subscriptionTransaction.Status != entity.TransactionStatusComplete {
+		return nil, errors.New("subscription transaction hasn't been completed")
+	}
+
+	var newSubscription *entity.Subscription
+
+	// Renewal transactions carry over the snapshot of the subscription they have been created for,
+	// since the subscriber has been charged the snapshot price
+	renewingSubscription, err := service.subscriptionRepo.FindRenewing(subscriptionTransaction.ID)
+	if err == nil {
+		newSubscription = renewedSubscription(renewingSubscription)
+	} else {
+		newSubscription, err = service.ConstructSubscription(subscriptionTransaction.UserID, subscriptionTransaction.PlanID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Keeping the coupon the transaction has been discounted with, so the following renewals can carry over its discount
+	newSubscription.CouponID = subscriptionTransaction.CouponID
+
+	_, err = service.subscriptionRepo.FindChangingPlan(subscriptionTransaction.ID)
+	if err == nil {
+		newSubscription.ExpiresAt = service.planChangeExpiry(newSubscription, subscriptionTransaction, time.Now())
+	} else {
+		// Extending from the active subscription's expiration date so paying ahead of time doesn't shorten the subscription
+		activeFrom := time.Now()
+		activeSubscription, err := service.subscriptionRepo.FindActive(newSubscription.SubscriberID,
+			newSubscription.SubscriptionPlanID)
+		if err == nil && activeSubscription.ExpiresAt.After(activeFrom) {
+			activeFrom = activeSubscription.ExpiresAt
+		}
+
+		// Subscription plan duration is stored in days
+		newSubscription.ExpiresAt = activeFrom.AddDate(0, 0, int(newSubscription.SubscriptionPlanDuration))
+	}
+
+	err = service.subscriptionRepo.Activate(newSubscription, subscriptionTransaction)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For activating subscription "+
+			"{ Subscription Transaction ID : %s }, %s", subscriptionTransaction.ID, err.Error()))
+
+		return nil, errors.New("unable to activate subscription")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished subscription activation process, Subscription => %s",
+		newSubscription.ToString()), service.logger.Logs.SubscriptionLogFile)
+
+	return newSubscription, nil
+}
+
+// StartTrialSubscription is a method that grants a free trial subscription without a subscription transaction,
+// a subscriber can only claim one trial per project. Trials of recurring plans are converted to paid subscriptions
+// by the renewal engine once they expire.
+func (service *Service) StartTrialSubscription(subscriberID, subscriptionPlanID string,
+	trialDays int64) (*entity.Subscription, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started trial subscription process { Subscriber ID : %s, "+
+		"Subscription Plan ID : %s, Trial Days : %d }", subscriberID, subscriptionPlanID, trialDays),
+		service.logger.Logs.SubscriptionLogFile)
+
+	if trialDays <= 0 {
+		return nil, errors.New("subscription plan doesn't offer a free trial")
+	}
+
+	newSubscription, err := service.ConstructSubscription(subscriberID, subscriptionPlanID)
+	if err != nil {
+		return nil, err
+	}
+
+	if service.subscriptionRepo.HasClaimedTrial(newSubscription) {
+		return nil, errors.New("free trial has already been claimed for this project")
+	}
+
+	newSubscription.ExpiresAt = time.Now().AddDate(0, 0, int(trialDays))
+	err = service.subscriptionRepo.StartTrial(newSubscription)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For starting trial subscription { Subscriber ID : %s, "+
+			"Subscription Plan ID : %s }, %s", subscriberID, subscriptionPlanID, err.Error()))
+
+		return nil, errors.New("unable to start free trial")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished trial subscription process, Subscription => %s",
+		newSubscription.ToString()), service.logger.Logs.SubscriptionLogFile)
+
+	return newSubscription, nil
+}
+
+// StartPlanChange is a method that records the transaction of a plan change on the subscription being changed,
+// a subscription can only have one plan change awaiting payment or activation at a time
+func (service *Service) StartPlanChange(subscriptionID string, planChangeTransaction *entity.SubscriptionTransaction) error {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started plan change starting process { Subscription ID : %s }, "+
+		"Subscription Transaction => %s", subscriptionID, planChangeTransaction.ToString()),
+		service.logger.Logs.SubscriptionLogFile)
+
+	err := service.subscriptionRepo.StartPlanChange(subscriptionID, planChangeTransaction)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For starting plan change { Subscription ID : %s }, "+
+			"Subscription Transaction => %s, %s", subscriptionID, planChangeTransaction.ToString(), err.Error()))
+
+		if err == subscription.ErrPlanChangeUnfinished {
+			return err
+		}
+
+		return errors.New("unable to start plan change")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished plan change starting process { Subscription ID : %s, "+
+		"Subscription Transaction ID : %s }", subscriptionID, planChangeTransaction.ID),
+		service.logger.Logs.SubscriptionLogFile)
+
+	return nil
+}
+
+// planChangeExpiry is a method that returns the expiration date of a subscription activated by a plan change.
+// The remaining value the changing subscription has been credited with at checkout together with the paid amount
+// is converted to time on the new plan, so an upgrade gets a full term while a downgrade is credited with additional time.
+func (service *Service) planChangeExpiry(newSubscription *entity.Subscription,
+	subscriptionTransaction *entity.SubscriptionTransaction, moment time.Time) time.Time {
+
+	if newSubscription.SubscriptionPlanPrice <= 0 || newSubscription.SubscriptionPlanDuration <= 0 {
+		return moment.AddDate(0, 0, int(newSubscription.SubscriptionPlanDuration))
+	}
+
+	// A payment converted at checkout is credited with the amount it had in the plan's currency
+	credit := money.New(subscriptionTransaction.ReceivedAmount, subscriptionTransaction.CurrencyType)
+	if subscriptionTransaction.OriginalCurrency != "" {
+		credit = money.New(subscriptionTransaction.OriginalAmount, subscriptionTransaction.OriginalCurrency)
+	}
+
+	// The credited value is held in the currency of the new plan, like the payment before its conversion
+	if total, err := credit.Add(money.New(subscriptionTransaction.CreditedAmount,
+		newSubscription.SubscriptionPlanCurrency)); err == nil {
+		credit = total
+	}
+
+	ratio := credit.Amount.Float64() / newSubscription.SubscriptionPlanPrice.Float64()
+	days := ratio * float64(newSubscription.SubscriptionPlanDuration)
+	return moment.Add(time.Duration(days * 24 * float64(time.Hour)))
+}
+
+// renewedSubscription is a function that returns a new subscription holding the same snapshot as the given subscription
+func renewedSubscription(subscription *entity.Subscription) *entity.Subscription {
+
+	newSubscription := *subscription
+	newSubscription.ID = ""
+	newSubscription.TransactionID = ""
+	newSubscription.IsTrial = false
+	newSubscription.Status = ""
+	newSubscription.ExpiryStage = ""
+	newSubscription.RenewalStatus = ""
+	newSubscription.RenewalTransactionID = ""
+	newSubscription.RenewalAttempts = 0
+	newSubscription.RenewalAttemptedAt = time.Time{}
+	newSubscription.CreatedAt = time.Time{}
+	newSubscription.UpdatedAt = time.Time{}
+
+	return &newSubscription
+}
+
 // FindSubscription is a method that find and return a subscription that matches the id value
 func (service *Service) FindSubscription(id string) (*entity.Subscription, error) {
 	/* ---------------------------- Logging ---------------------------- */
@@ -102,8 +302,113 @@ func (service *Service) FindMultipleSubscriptions(identifier string) []*entity.S
 	return service.subscriptionRepo.FindMultiple(identifier)
 }
 
-// UpdateSubscription is a method that updates a subscription in the system
+// FindActiveSubscription is a method that find and return the subscription that is currently active
+// for the given subscriber and subscription plan
+func (service *Service) FindActiveSubscription(subscriberID, subscriptionPlanID string) (*entity.Subscription, error) {
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Active subscription finding process { Subscriber ID : %s, Subscription Plan ID : %s }",
+		subscriberID, subscriptionPlanID), service.logger.Logs.SubscriptionLogFile)
+
+	empty1, _ := regexp.MatchString(`^\s*$`, subscriberID)
+	empty2, _ := regexp.MatchString(`^\s*$`, subscriptionPlanID)
+	if empty1 || empty2 {
+		return nil, errors.New("no active subscription found")
+	}
+
+	subscription, err := service.subscriptionRepo.FindActive(subscriberID, subscriptionPlanID)
+	if err != nil {
+		return nil, errors.New("no active subscription found")
+	}
+	return subscription, nil
+}
+
+// FindActivatedSubscription is a method that find and return the subscription a subscription transaction has activated
+func (service *Service) FindActivatedSubscription(transactionID string) (*entity.Subscription, error) {
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Activated subscription finding process { Transaction ID : %s }", transactionID),
+		service.logger.Logs.SubscriptionLogFile)
+
+	empty, _ := regexp.MatchString(`^\s*$`, transactionID)
+	if empty {
+		return nil, errors.New("no subscription found")
+	}
+
+	subscription, err := service.subscriptionRepo.FindActivated(transactionID)
+	if err != nil {
+		return nil, errors.New("no subscription found")
+	}
+	return subscription, nil
+}
+
+// SubscriptionHistory is a method that returns all the subscriptions a subscriber has ever had with pagination
+func (service *Service) SubscriptionHistory(subscriberID string, pageNum int64) ([]*entity.Subscription, int64) {
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Subscription history finding process { Subscriber ID : %s, Page : %d }",
+		subscriberID, pageNum), service.logger.Logs.SubscriptionLogFile)
+
+	empty, _ := regexp.MatchString(`^\s*$`, subscriberID)
+	if empty || pageNum < 0 {
+		return []*entity.Subscription{}, 0
+	}
+
+	return service.subscriptionRepo.History(subscriberID, pageNum)
+}
+
+// FindExpiringSubscriptions is a method that find and return subscriptions that expire before the given moment
+// and still have members in the subscribed chats
+func (service *Service) FindExpiringSubscriptions(moment time.Time) []*entity.Subscription {
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Expiring subscriptions finding process { Moment : %s }", moment),
+		service.logger.Logs.SubscriptionLogFile)
+
+	return service.subscriptionRepo.FindExpiring(moment)
+}
+
+// FindRenewableSubscriptions is a method that find and return recurring subscriptions that expire before the given moment
+// and can still be renewed
+func (service *Service) FindRenewableSubscriptions(moment time.Time) []*entity.Subscription {
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Renewable subscriptions finding process { Moment : %s }", moment),
+		service.logger.Logs.SubscriptionLogFile)
+
+	return service.subscriptionRepo.FindRenewable(moment)
+}
+
+// RemainingValue is a method that returns the prorated value of the time a subscription has left at the given moment,
+// calculated from the subscription plan price and duration of the subscription snapshot
+func (service *Service) RemainingValue(subscription *entity.Subscription, moment time.Time) money.Amount {
+
+	// Nothing has been paid for a free trial
+	if subscription.IsTrial || !subscription.ExpiresAt.After(moment) || subscription.SubscriptionPlanDuration <= 0 {
+		return 0
+	}
+
+	// Subscription plan duration is stored in days
+	remainingSeconds := int64(subscription.ExpiresAt.Sub(moment) / time.Second)
+	durationSeconds := subscription.SubscriptionPlanDuration * 24 * 60 * 60
+
+	return subscription.SubscriptionPlanPrice.Prorate(remainingSeconds, durationSeconds)
+}
+
+// UpdateSubscription is a method that updates a subscription in the system,
+// the status of a subscription can only be changed through UpdateSubscriptionStatus
 func (service *Service) UpdateSubscription(subscription *entity.Subscription) error {
+
+	prevSubscription, err := service.subscriptionRepo.Find(subscription.ID)
+	if err == nil && prevSubscription.Status != subscription.Status {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For updating subscription { Subscription ID : %s, "+
+			"From : %s, To : %s }, status can only be changed through a status transition", subscription.ID,
+			prevSubscription.Status, subscription.Status))
+
+		return errors.New("subscription status can only be changed through a status transition")
+	}
+
+	return service.updateSubscription(subscription)
+}
+
+// updateSubscription is a method that saves a subscription without checking its status
+func (service *Service) updateSubscription(subscription *entity.Subscription) error {
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Started subscription updating process, Subscription => %s",
 		subscription.ToString()), service.logger.Logs.SubscriptionLogFile)
@@ -124,6 +429,97 @@ func (service *Service) UpdateSubscription(subscription *entity.Subscription) er
 	return nil
 }
 
+// UpdateSubscriptionStatus is a method that moves a subscription to the given status,
+// only the transitions allowed by subscriptionStatusTransitions can be made
+func (service *Service) UpdateSubscriptionStatus(subscription *entity.Subscription, status string) error {
+
+	prevStatus := subscription.Status
+	fromStatus := prevStatus
+	if fromStatus == "" {
+		fromStatus = entity.SubscriptionStatusActive
+	}
+
+	if !isValidStatusTransition(fromStatus, status) {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For changing subscription status "+
+			"{ Subscription ID : %s, From : %s, To : %s }, invalid status transition", subscription.ID, fromStatus, status))
+
+		return errors.New("invalid subscription status transition")
+	}
+
+	// The stored status is checked as well, so a subscription that has moved on since it has been read isn't changed
+	storedSubscription, err := service.subscriptionRepo.Find(subscription.ID)
+	if err == nil && storedSubscription.Status != subscription.Status {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For changing subscription status "+
+			"{ Subscription ID : %s, From : %s, To : %s }, status has already been changed to %s", subscription.ID,
+			fromStatus, status, storedSubscription.Status))
+
+		return errors.New("invalid subscription status transition")
+	}
+
+	subscription.Status = status
+	err = service.updateSubscription(subscription)
+	if err != nil {
+		subscription.Status = prevStatus
+		return err
+	}
+
+	return nil
+}
+
+// CancelSubscription is a method that cancels an active subscription either immediately or at the end of its term.
+// An immediate cancellation ends the subscription right away and returns the subscriber's revoked chat links,
+// while an end of term cancellation stops the renewals and lets the subscription end once it expires.
+func (service *Service) CancelSubscription(id, mode string) (*entity.Subscription, []*entity.UserChatLink, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started subscription cancelling process { Subscription ID : %s, Mode : %s }",
+		id, mode), service.logger.Logs.SubscriptionLogFile)
+
+	if mode != entity.CancellationImmediate && mode != entity.CancellationEndOfTerm {
+		return nil, nil, errors.New("invalid cancellation mode")
+	}
+
+	subscription, err := service.FindSubscription(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if subscription.Status != entity.SubscriptionStatusActive ||
+		subscription.CancellationMode == entity.CancellationImmediate {
+		return nil, nil, errors.New("subscription is not active")
+	}
+
+	if subscription.CancellationMode == entity.CancellationEndOfTerm && mode == entity.CancellationEndOfTerm {
+		return nil, nil, errors.New("subscription has already been cancelled")
+	}
+
+	subscription.CancellationMode = mode
+	subscription.CancelledAt = time.Now()
+	if mode == entity.CancellationImmediate {
+		if !isValidStatusTransition(subscription.Status, entity.SubscriptionStatusCancelled) {
+			return nil, nil, errors.New("invalid subscription status transition")
+		}
+		subscription.Status = entity.SubscriptionStatusCancelled
+	}
+
+	revokedChatLinks, err := service.subscriptionRepo.Cancel(subscription)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For cancelling subscription "+
+			"{ Subscription ID : %s, Mode : %s }, %s", id, mode, err.Error()))
+
+		return nil, nil, errors.New("unable to cancel subscription")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished subscription cancelling process, Subscription => %s",
+		subscription.ToString()), service.logger.Logs.SubscriptionLogFile)
+
+	return subscription, revokedChatLinks, nil
+}
+
 // DeleteSubscription is a method that deletes a subscription from the system using an id
 func (service *Service) DeleteSubscription(id string) (*entity.Subscription, error) {
 	/* ---------------------------- Logging ---------------------------- */
@@ -0,0 +1,93 @@
+package spcheckout
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/log"
+	"github.com/Benyam-S/onemembership/serviceprovider"
+	"github.com/Benyam-S/onemembership/subscription"
+	"github.com/Benyam-S/onemembership/subscriptionplan"
+	"github.com/Benyam-S/onemembership/transaction"
+)
+
+// Service is a type that defines a checkout service through which service providers pay for the platform
+type Service struct {
+	subscriptionService     subscription.IService
+	subscriptionPlanService subscriptionplan.IService
+	serviceProviderService  serviceprovider.IService
+	transactionService      transaction.IService
+	receiverName            string // The name payments are made to, shown to the service provider by the payment gateway
+	logger                  *log.Logger
+}
+
+// NewSPCheckoutService is a function that returns a new service provider checkout service
+func NewSPCheckoutService(subscriptionService subscription.IService, subscriptionPlanService subscriptionplan.IService,
+	serviceProviderService serviceprovider.IService, transactionService transaction.IService, receiverName string,
+	subscriptionLogger *log.Logger) *Service {
+	return &Service{subscriptionService: subscriptionService, subscriptionPlanService: subscriptionPlanService,
+		serviceProviderService: serviceProviderService, transactionService: transactionService,
+		receiverName: receiverName, logger: subscriptionLogger}
+}
+
+// Checkout is a method that creates a pending service provider subscription transaction for the given
+// platform subscription plan and returns it together with the url the service provider should pay through.
+// The idempotency key, such as the web request id, makes a repeated request return the same payment.
+func (service *Service) Checkout(gatewayID int64, idempotencyKey, providerID,
+	spSubscriptionPlanID string) (*entity.SPSubscriptionTransaction, string, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started service provider checkout process { Provider ID : %s, Plan ID : %s }",
+		providerID, spSubscriptionPlanID), service.logger.Logs.SubscriptionLogFile)
+
+	serviceProvider, err := service.serviceProviderService.FindServiceProvider(providerID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	spSubscriptionPlan, err := service.subscriptionPlanService.FindSPSubscriptionPlan(spSubscriptionPlanID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if spSubscriptionPlan.Price <= 0 {
+		return nil, "", errors.New("subscription plan doesn't require a payment")
+	}
+
+	spSubscriptionTransaction, payURL, err := service.transactionService.InitiateSPSubscriptionTransaction(gatewayID,
+		idempotencyKey, serviceProvider.ID, spSubscriptionPlan.ID, service.receiverName, spSubscriptionPlan.Name,
+		spSubscriptionPlan.Currency, spSubscriptionPlan.Price)
+	if err != nil {
+		return nil, "", err
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished service provider checkout process, awaiting payment "+
+		"{ Provider ID : %s, SP Subscription Transaction ID : %s }", serviceProvider.ID, spSubscriptionTransaction.ID),
+		service.logger.Logs.SubscriptionLogFile)
+
+	return spSubscriptionTransaction, payURL, nil
+}
+
+// HandlePaymentNotification is a method that applies a payment notification to the service provider subscription
+// transaction it belongs to, the service provider's subscription is created or extended once the payment has been completed
+func (service *Service) HandlePaymentNotification(gatewayID int64,
+	payload []byte) (*entity.SPSubscriptionTransaction, *entity.SPSubscription, error) {
+
+	spSubscriptionTransaction, err := service.transactionService.HandleSPPaymentNotification(gatewayID, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if spSubscriptionTransaction.Status != entity.TransactionStatusComplete {
+		return spSubscriptionTransaction, nil, nil
+	}
+
+	spSubscription, err := service.subscriptionService.ActivateSPSubscription(spSubscriptionTransaction)
+	if err != nil {
+		return spSubscriptionTransaction, nil, err
+	}
+
+	return spSubscriptionTransaction, spSubscription, nil
+}
@@ -0,0 +1,78 @@
+package trial
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/log"
+	"github.com/Benyam-S/onemembership/subscription"
+	"github.com/Benyam-S/onemembership/subscriptionplan"
+)
+
+// Result is a type that defines the outcome of a started free trial
+type Result struct {
+	Subscription   *entity.Subscription
+	GrantedChatIDs []int64 // Chats of the plan the subscriber needs invite links for
+}
+
+// Service is a type that defines a free trial service
+type Service struct {
+	subscriptionService     subscription.IService
+	subscriptionPlanService subscriptionplan.IService
+	logger                  *log.Logger
+}
+
+// NewTrialService is a function that returns a new free trial service
+func NewTrialService(subscriptionService subscription.IService, subscriptionPlanService subscriptionplan.IService,
+	subscriptionLogger *log.Logger) *Service {
+	return &Service{subscriptionService: subscriptionService, subscriptionPlanService: subscriptionPlanService,
+		logger: subscriptionLogger}
+}
+
+// StartTrial is a method that starts the free trial of a subscription plan for a subscriber and returns the chats
+// of the plan the subscriber should be granted access to, the same as a paid subscription to the plan
+func (service *Service) StartTrial(subscriberID, subscriptionPlanID string) (*Result, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started free trial process { Subscriber ID : %s, Subscription Plan ID : %s }",
+		subscriberID, subscriptionPlanID), service.logger.Logs.SubscriptionLogFile)
+
+	subscriptionPlan, err := service.subscriptionPlanService.FindSubscriptionPlan(subscriptionPlanID)
+	if err != nil {
+		return nil, err
+	}
+
+	if subscriptionPlan.Status != entity.PlanStatusComplete {
+		return nil, errors.New("subscription plan is not available")
+	}
+
+	if subscriptionPlan.TrialDays <= 0 {
+		return nil, errors.New("subscription plan doesn't offer a free trial")
+	}
+
+	if _, err := service.subscriptionService.FindActiveSubscription(subscriberID, subscriptionPlan.ID); err == nil {
+		return nil, errors.New("subscriber already has an active subscription to the given plan")
+	}
+
+	newSubscription, err := service.subscriptionService.StartTrialSubscription(subscriberID, subscriptionPlan.ID,
+		subscriptionPlan.TrialDays)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Subscription: newSubscription, GrantedChatIDs: make([]int64, 0)}
+	grantedChatIDs := make(map[int64]bool)
+	for _, planChatLink := range service.subscriptionPlanService.FindMultiplePlanChatLinks(subscriptionPlan.ID) {
+		if planChatLink.PlanID == subscriptionPlan.ID && !grantedChatIDs[planChatLink.ChatID] {
+			grantedChatIDs[planChatLink.ChatID] = true
+			result.GrantedChatIDs = append(result.GrantedChatIDs, planChatLink.ChatID)
+		}
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished free trial process { Subscription ID : %s, Granted Chats : %d }",
+		newSubscription.ID, len(result.GrantedChatIDs)), service.logger.Logs.SubscriptionLogFile)
+
+	return result, nil
+}
@@ -4,6 +4,8 @@ import "github.com/Benyam-S/onemembership/entity"
 
 // IService is an interface that defines all the service methods of a subscription plan struct
 type IService interface {
+	WithActor(actor string) IService
+
 	AddSubscriptionPlan(newSubscriptionPlan *entity.SubscriptionPlan) error
 	ValidateSubscriptionPlan(subscriptionPlan *entity.SubscriptionPlan) entity.ErrMap
 	FindSubscriptionPlan(id string) (*entity.SubscriptionPlan, error)
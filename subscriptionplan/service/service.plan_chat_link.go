@@ -84,6 +84,10 @@ func (service *Service) DeletePlanChatLink(planID string, chatID int64) (*entity
 		return nil, errors.New("unable to delete subscription plan to chat link")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionDelete, fmt.Sprintf("%s:%d", planID, chatID),
+		planChatLink, nil)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf(
 		"Finished subscription plan to chat link deleting process, Deleted Plan Chat Link => %s",
@@ -98,5 +102,13 @@ func (service *Service) DeleteMultiplePlanChatLinks(identifier interface{}) []*e
 		"Multiple subscription plan to chat link deleting { Plan Chat Link Identifier : %s }",
 		identifier), service.logger.Logs.SubscriptionPlanLogFile)
 
-	return service.planChatLinkRepo.DeleteMultiple(identifier)
+	planChatLinks := service.planChatLinkRepo.DeleteMultiple(identifier)
+
+	// Recording the changes in the audit trail
+	for _, planChatLink := range planChatLinks {
+		service.recordAudit(entity.AuditActionDelete,
+			fmt.Sprintf("%s:%d", planChatLink.PlanID, planChatLink.ChatID), planChatLink, nil)
+	}
+
+	return planChatLinks
 }
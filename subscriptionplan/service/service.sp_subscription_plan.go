@@ -3,7 +3,6 @@ package service
 import (
 	"errors"
 	"fmt"
-	"math"
 	"regexp"
 	"strings"
 
@@ -52,9 +51,6 @@ func (service *Service) ValidateSPSubscriptionPlan(subscriptionPlan *entity.SPSu
 
 	if subscriptionPlan.Price < 0 {
 		errMap["price"] = errors.New(`invalid subscription plan price used`)
-	} else {
-		// Formatting the price
-		subscriptionPlan.Price = math.Round(subscriptionPlan.Price*100) / 100
 	}
 
 	var isValidCurrencyType bool
@@ -102,7 +98,16 @@ func (service *Service) UpdateSPSubscriptionPlan(subscriptionPlan *entity.SPSubs
 	service.logger.Log(fmt.Sprintf("Started service provider subscription plan updating process, SP Subscription Plan => %s",
 		subscriptionPlan.ToString()), service.logger.Logs.SubscriptionPlanLogFile)
 
-	err := service.spSubscriptionPlanRepo.Update(subscriptionPlan)
+	prevSubscriptionPlan, err := service.spSubscriptionPlanRepo.Find(subscriptionPlan.ID)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding service provider subscription plan before updating "+
+			"{ SP Subscription Plan ID : %s }, %s", subscriptionPlan.ID, err.Error()))
+
+		return errors.New("unable to update subscription plan")
+	}
+
+	err = service.spSubscriptionPlanRepo.Update(subscriptionPlan)
 	if err != nil {
 		/* ---------------------------- Logging ---------------------------- */
 		service.logger.LogToErrorFile(fmt.Sprintf("Error: For updating SP Subscription Plan => %s, %s",
@@ -111,6 +116,10 @@ func (service *Service) UpdateSPSubscriptionPlan(subscriptionPlan *entity.SPSubs
 		return errors.New("unable to update subscription plan")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionUpdate, subscriptionPlan.ID, prevSubscriptionPlan,
+		subscriptionPlan)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished service provider subscription plan updating process, SP Subscription Plan => %s",
 		subscriptionPlan.ToString()), service.logger.Logs.SubscriptionPlanLogFile)
@@ -133,6 +142,9 @@ func (service *Service) DeleteSPSubscriptionPlan(id string) (*entity.SPSubscript
 		return nil, errors.New("unable to delete subscription plan")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionDelete, id, subscriptionPlan, nil)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished service provider subscription plan deleting process, "+
 		"Deleted SP Subscription Plan => %s", subscriptionPlan.ToString()), service.logger.Logs.SubscriptionPlanLogFile)
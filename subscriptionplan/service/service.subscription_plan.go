@@ -3,10 +3,10 @@ package service
 import (
 	"errors"
 	"fmt"
-	"math"
 	"regexp"
 	"strings"
 
+	"github.com/Benyam-S/onemembership/audit"
 	"github.com/Benyam-S/onemembership/common"
 	"github.com/Benyam-S/onemembership/entity"
 	"github.com/Benyam-S/onemembership/log"
@@ -20,6 +20,8 @@ type Service struct {
 	planChatLinkRepo       subscriptionplan.IPlanChatLinkRepository
 	userChatLinkRepo       subscriptionplan.IUserChatLinkRepository
 	cmService              common.IService
+	auditService           audit.IService
+	actor                  string // The actor the changes are recorded on behalf of in the audit trail
 	logger                 *log.Logger
 }
 
@@ -28,10 +30,30 @@ func NewSubscriptionPlanService(subscriptionPlanRepository subscriptionplan.ISub
 	spSubscriptionPlanRepository subscriptionplan.ISPSubscriptionPlanRepository,
 	planChatLinkRepository subscriptionplan.IPlanChatLinkRepository,
 	userChatLinkRepository subscriptionplan.IUserChatLinkRepository, commonService common.IService,
-	subscriptionPlanLogger *log.Logger) subscriptionplan.IService {
+	auditService audit.IService, subscriptionPlanLogger *log.Logger) subscriptionplan.IService {
 	return &Service{subscriptionPlanRepo: subscriptionPlanRepository, spSubscriptionPlanRepo: spSubscriptionPlanRepository,
 		userChatLinkRepo: userChatLinkRepository, planChatLinkRepo: planChatLinkRepository,
-		cmService: commonService, logger: subscriptionPlanLogger}
+		cmService: commonService, auditService: auditService, actor: audit.SystemActor,
+		logger: subscriptionPlanLogger}
+}
+
+// WithActor is a method that returns a copy of the subscription plan service that records its changes in the audit trail
+// on behalf of the given actor, such as the admin or the service provider that has made the request
+func (service *Service) WithActor(actor string) subscriptionplan.IService {
+	serviceCopy := *service
+	serviceCopy.actor = actor
+	return &serviceCopy
+}
+
+// recordAudit is a method that appends an action performed on an entity to the audit trail on behalf of the actor,
+// the action has already taken place so a failure is only logged
+func (service *Service) recordAudit(action, entityID string, before, after interface{}) {
+	err := service.auditService.Record(service.actor, action, entityID, before, after)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For recording audit { Actor : %s, Action : %s, "+
+			"Entity ID : %s }, %s", service.actor, action, entityID, err.Error()))
+	}
 }
 
 // AddSubscriptionPlan is a method that adds a new subscription plan to the system
@@ -41,6 +63,11 @@ func (service *Service) AddSubscriptionPlan(newSubscriptionPlan *entity.Subscrip
 	service.logger.Log(fmt.Sprintf("Started subscription plan adding process, Subscription Plan => %s",
 		newSubscriptionPlan.ToString()), service.logger.Logs.SubscriptionPlanLogFile)
 
+	// Only service providers with an active platform subscription can add new subscription plans
+	if !service.cmService.ProjectHasActiveSPSubscription(newSubscriptionPlan.ProjectID) {
+		return errors.New("service provider doesn't have an active subscription")
+	}
+
 	err := service.subscriptionPlanRepo.Create(newSubscriptionPlan)
 	if err != nil {
 		/* ---------------------------- Logging ---------------------------- */
@@ -88,11 +115,12 @@ func (service *Service) ValidateSubscriptionPlan(subscriptionPlan *entity.Subscr
 		errMap["duration"] = errors.New(`invalid subscription plan duration used`)
 	}
 
+	if subscriptionPlan.TrialDays < 0 || subscriptionPlan.TrialDays > 1000000 {
+		errMap["trial_days"] = errors.New(`invalid subscription plan trial period used`)
+	}
+
 	if subscriptionPlan.Price < 0 {
 		errMap["price"] = errors.New(`invalid subscription plan price used`)
-	} else {
-		// Formatting the price
-		subscriptionPlan.Price = math.Round(subscriptionPlan.Price*100) / 100
 	}
 
 	var validCurrencyType bool
@@ -155,7 +183,16 @@ func (service *Service) UpdateSubscriptionPlan(subscriptionPlan *entity.Subscrip
 	service.logger.Log(fmt.Sprintf("Started subscription plan updating process, Subscription Plan => %s",
 		subscriptionPlan.ToString()), service.logger.Logs.SubscriptionPlanLogFile)
 
-	err := service.subscriptionPlanRepo.Update(subscriptionPlan)
+	prevSubscriptionPlan, err := service.subscriptionPlanRepo.Find(subscriptionPlan.ID)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding subscription plan before updating "+
+			"{ Subscription Plan ID : %s }, %s", subscriptionPlan.ID, err.Error()))
+
+		return errors.New("unable to update subscription plan")
+	}
+
+	err = service.subscriptionPlanRepo.Update(subscriptionPlan)
 	if err != nil {
 		/* ---------------------------- Logging ---------------------------- */
 		service.logger.LogToErrorFile(fmt.Sprintf("Error: For updating Subscription Plan => %s, %s",
@@ -164,6 +201,10 @@ func (service *Service) UpdateSubscriptionPlan(subscriptionPlan *entity.Subscrip
 		return errors.New("unable to update subscription plan")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionUpdate, subscriptionPlan.ID, prevSubscriptionPlan,
+		subscriptionPlan)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished subscription plan updating process, Subscription Plan => %s",
 		subscriptionPlan.ToString()), service.logger.Logs.SubscriptionPlanLogFile)
@@ -186,6 +227,9 @@ func (service *Service) DeleteSubscriptionPlan(id string) (*entity.SubscriptionP
 		return nil, errors.New("unable to delete subscription plan")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionDelete, id, subscriptionPlan, nil)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished subscription plan deleting process, Deleted Subscription Plan => %s",
 		subscriptionPlan.ToString()), service.logger.Logs.SubscriptionPlanLogFile)
@@ -198,5 +242,12 @@ func (service *Service) DeleteMultipleSubscriptionPlans(projectID string) []*ent
 	service.logger.Log(fmt.Sprintf("Multiple subscription plan deleting { Project ID : %s }",
 		projectID), service.logger.Logs.SubscriptionPlanLogFile)
 
-	return service.subscriptionPlanRepo.DeleteMultiple(projectID)
+	subscriptionPlans := service.subscriptionPlanRepo.DeleteMultiple(projectID)
+
+	// Recording the changes in the audit trail
+	for _, subscriptionPlan := range subscriptionPlans {
+		service.recordAudit(entity.AuditActionDelete, subscriptionPlan.ID, subscriptionPlan, nil)
+	}
+
+	return subscriptionPlans
 }
@@ -84,6 +84,10 @@ func (service *Service) DeleteUserChatLink(userID, planID string, chatID int64)
 		return nil, errors.New("unable to delete user to chat link")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionDelete,
+		fmt.Sprintf("%s:%s:%d", userID, planID, chatID), spChatLink, nil)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf(
 		"Finished user to chat link deleting process, Deleted User Chat Link => %s",
@@ -98,5 +102,13 @@ func (service *Service) DeleteMultipleUserChatLinks(identifier interface{}) []*e
 		"Multiple user to chat link deleting { User Chat Link Identifier : %s }",
 		identifier), service.logger.Logs.SubscriptionPlanLogFile)
 
-	return service.userChatLinkRepo.DeleteMultiple(identifier)
+	userChatLinks := service.userChatLinkRepo.DeleteMultiple(identifier)
+
+	// Recording the changes in the audit trail
+	for _, userChatLink := range userChatLinks {
+		service.recordAudit(entity.AuditActionDelete,
+			fmt.Sprintf("%s:%s:%d", userChatLink.UserID, userChatLink.PlanID, userChatLink.ChatID), userChatLink, nil)
+	}
+
+	return userChatLinks
 }
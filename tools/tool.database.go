@@ -1,6 +1,8 @@
 package tools
 
 import (
+	"strings"
+
 	"github.com/jinzhu/gorm"
 )
 
@@ -17,3 +19,9 @@ func IsUnique(columnName string, columnValue interface{}, tableName string, db *
 	db.Table(tableName).Where(columnName+"=?", columnValue).Count(&totalCount)
 	return 0 >= totalCount
 }
+
+// IsDuplicateKey is a method that determines whether a database error has been caused by a value that already exists
+// in a unique index, MySQL reports it as error 1062
+func IsDuplicateKey(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "Error 1062:")
+}
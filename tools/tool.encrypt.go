@@ -1,11 +1,14 @@
 package tools
 
 import (
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
+	"math/big"
 )
 
 // GenerateKeyPair generates a new key pair
@@ -115,3 +118,46 @@ func DecryptWithPrivateKey(ciphertext []byte, priv *rsa.PrivateKey) ([]byte, err
 
 	return plaintext, nil
 }
+
+// EncryptWithPrivateKey encrypts data with private key using PKCS #1 v1.5 type 1 padding,
+// so the result can only be recovered by the holder of the matching public key
+func EncryptWithPrivateKey(msg []byte, priv *rsa.PrivateKey) ([]byte, error) {
+	ciphertext, err := rsa.SignPKCS1v15(nil, priv, crypto.Hash(0), msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return ciphertext, nil
+}
+
+// DecryptWithPublicKey decrypts data that has been encrypted with the matching private key,
+// it returns an error if the padding doesn't match which indicates the data has been tampered with
+func DecryptWithPublicKey(ciphertext []byte, pub *rsa.PublicKey) ([]byte, error) {
+	keySize := pub.Size()
+	if len(ciphertext) != keySize {
+		return nil, errors.New("invalid cipher text length")
+	}
+
+	c := new(big.Int).SetBytes(ciphertext)
+	if c.Cmp(pub.N) >= 0 {
+		return nil, errors.New("invalid cipher text")
+	}
+
+	m := new(big.Int).Exp(c, big.NewInt(int64(pub.E)), pub.N)
+	em := m.FillBytes(make([]byte, keySize))
+
+	// Checking the 0x00 || 0x01 || PS || 0x00 || M padding where PS is at least 8 bytes of 0xff
+	if em[0] != 0x00 || em[1] != 0x01 {
+		return nil, errors.New("invalid padding")
+	}
+
+	index := 2
+	for ; index < keySize && em[index] == 0xff; index++ {
+	}
+
+	if index == keySize || em[index] != 0x00 || index-2 < 8 {
+		return nil, errors.New("invalid padding")
+	}
+
+	return em[index+1:], nil
+}
@@ -0,0 +1,146 @@
+package transaction
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/money"
+)
+
+// DefaultGatewayID is a constant that selects the default payment gateway of a gateway registry
+const DefaultGatewayID int64 = 0
+
+// ErrUnsupportedOperation is an error returned by a payment provider that doesn't support the requested operation
+var ErrUnsupportedOperation = errors.New("operation is not supported by the payment provider")
+
+// ErrPaymentMismatch is an error returned when the payment reported by a payment provider doesn't match the transaction
+var ErrPaymentMismatch = errors.New("payment doesn't match the transaction")
+
+// ErrPaymentProcessed is an error returned when the payment of a transaction that has already been settled is reported again
+var ErrPaymentProcessed = errors.New("payment result has already been processed")
+
+// PaymentRequest is a type that defines the details a payment provider needs to initiate a payment
+type PaymentRequest struct {
+	Nonce          string
+	OutTradeNo     string
+	ReceiverName   string
+	Subject        string
+	TotalAmount    money.Amount
+	CurrencyType   string
+	TimeoutExpress int64 // The number of minutes the payment stays open
+}
+
+// PaymentResult is a type that defines the state of a payment as reported by a payment provider
+type PaymentResult struct {
+	OutTradeNo  string
+	TradeNo     string
+	TotalAmount money.Amount
+	Status      string // Holds one of the transaction status constants
+}
+
+// PaymentProvider is an interface that defines all the methods a payment gateway driver should implement
+type PaymentProvider interface {
+	AppID() string
+	Currency() string
+	TransactionFee() money.Amount
+	Initiate(request *PaymentRequest) (string, error)
+	VerifyCallback(payload []byte) (*PaymentResult, error)
+	QueryStatus(outTradeNo string) (*PaymentResult, error)
+	Refund(outTradeNo, tradeNo string, amount money.Amount) error
+}
+
+// GatewayRegistry is a type that holds the payment provider of each payment gateway
+type GatewayRegistry struct {
+	mu               sync.RWMutex
+	providers        map[int64]PaymentProvider
+	defaultGatewayID int64
+}
+
+// NewGatewayRegistry is a function that returns a new gateway registry where each payment gateway is registered
+// with the driver that has the same name, the first registered gateway becomes the default gateway
+func NewGatewayRegistry(paymentGateways []*entity.PaymentGateway, drivers map[string]PaymentProvider) *GatewayRegistry {
+
+	registry := &GatewayRegistry{providers: make(map[int64]PaymentProvider)}
+	for _, paymentGateway := range paymentGateways {
+		if driver, ok := drivers[paymentGateway.Name]; ok {
+			registry.Register(paymentGateway, driver)
+		}
+	}
+
+	return registry
+}
+
+// Register is a method that registers a payment provider for the given payment gateway
+func (registry *GatewayRegistry) Register(paymentGateway *entity.PaymentGateway, provider PaymentProvider) {
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if len(registry.providers) == 0 {
+		registry.defaultGatewayID = paymentGateway.ID
+	}
+
+	registry.providers[paymentGateway.ID] = provider
+}
+
+// SetDefault is a method that sets the payment gateway used when the default gateway is requested
+func (registry *GatewayRegistry) SetDefault(gatewayID int64) error {
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if _, ok := registry.providers[gatewayID]; !ok {
+		return errors.New("no payment provider registered for the payment gateway")
+	}
+
+	registry.defaultGatewayID = gatewayID
+	return nil
+}
+
+// Find is a method that returns the payment provider registered for the given payment gateway
+func (registry *GatewayRegistry) Find(gatewayID int64) (PaymentProvider, error) {
+
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	if gatewayID == DefaultGatewayID {
+		gatewayID = registry.defaultGatewayID
+	}
+
+	provider, ok := registry.providers[gatewayID]
+	if !ok {
+		return nil, errors.New("no payment provider registered for the payment gateway")
+	}
+
+	return provider, nil
+}
+
+// Resolve is a method that returns the id of the payment gateway a request for the given gateway is made through,
+// the default gateway id is replaced with the id of the gateway currently set as the default
+func (registry *GatewayRegistry) Resolve(gatewayID int64) int64 {
+
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	if gatewayID == DefaultGatewayID {
+		return registry.defaultGatewayID
+	}
+
+	return gatewayID
+}
+
+// FindByAppID is a method that returns the payment provider a transaction has been made through using its app id
+func (registry *GatewayRegistry) FindByAppID(appID string) (PaymentProvider, error) {
+
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	for _, provider := range registry.providers {
+		if provider.AppID() == appID {
+			return provider, nil
+		}
+	}
+
+	return nil, errors.New("no payment provider registered for the app id")
+}
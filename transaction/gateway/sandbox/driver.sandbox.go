@@ -0,0 +1,177 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/money"
+	"github.com/Benyam-S/onemembership/tools"
+	"github.com/Benyam-S/onemembership/transaction"
+)
+
+// Payment is a type that defines a payment kept by the sandbox driver
+type Payment struct {
+	Request  *transaction.PaymentRequest
+	Result   *transaction.PaymentResult
+	Refunded money.Amount
+}
+
+// Driver is a type that defines an in-memory payment provider that settles payments on demand,
+// so payment flows can be run without reaching a real payment gateway
+type Driver struct {
+	mu             sync.Mutex
+	appID          string
+	currencyType   string
+	transactionFee money.Amount
+	payURL         string
+	payments       map[string]*Payment
+}
+
+// NewSandboxDriver is a function that returns a new sandbox payment provider,
+// the pay url of each payment is the given base url followed by the out trade number
+func NewSandboxDriver(appID, currencyType string, transactionFee money.Amount, payURL string) *Driver {
+	return &Driver{appID: appID, currencyType: currencyType, transactionFee: transactionFee, payURL: payURL,
+		payments: make(map[string]*Payment)}
+}
+
+// AppID is a method that returns the app id transactions made through the driver are recorded with
+func (driver *Driver) AppID() string {
+	return driver.appID
+}
+
+// Currency is a method that returns the currency payments made through the driver are settled in
+func (driver *Driver) Currency() string {
+	return driver.currencyType
+}
+
+// TransactionFee is a method that returns the fee the driver adds to each payment
+func (driver *Driver) TransactionFee() money.Amount {
+	return driver.transactionFee
+}
+
+// Initiate is a method that opens a new pending payment and returns its pay url
+func (driver *Driver) Initiate(request *transaction.PaymentRequest) (string, error) {
+
+	driver.mu.Lock()
+	defer driver.mu.Unlock()
+
+	if _, ok := driver.payments[request.OutTradeNo]; ok {
+		return "", errors.New("payment has already been initiated")
+	}
+
+	if !strings.EqualFold(request.CurrencyType, driver.currencyType) {
+		return "", errors.New("payment currency isn't supported by the driver")
+	}
+
+	driver.payments[request.OutTradeNo] = &Payment{Request: request,
+		Result: &transaction.PaymentResult{OutTradeNo: request.OutTradeNo, TotalAmount: request.TotalAmount,
+			Status: entity.TransactionStatusPending}}
+
+	return driver.payURL + request.OutTradeNo, nil
+}
+
+// Settle is a method that completes or fails a pending payment and returns the callback payload
+// the gateway would have sent, so it can be passed to the notification handler.
+// Dropping the payload simulates a payment the gateway never notified about.
+func (driver *Driver) Settle(outTradeNo string, completed bool) ([]byte, error) {
+
+	driver.mu.Lock()
+	defer driver.mu.Unlock()
+
+	payment, ok := driver.payments[outTradeNo]
+	if !ok {
+		return nil, errors.New("no payment found")
+	}
+
+	if payment.Result.Status != entity.TransactionStatusPending {
+		return nil, errors.New("payment has already been settled")
+	}
+
+	payment.Result.TradeNo = fmt.Sprintf("SANDBOX-%s", tools.RandomStringGN(12))
+	payment.Result.Status = entity.TransactionStatusFailed
+	if completed {
+		payment.Result.Status = entity.TransactionStatusComplete
+	}
+
+	return json.Marshal(payment.Result)
+}
+
+// Override is a method that replaces the state reported for a payment, so a gateway that reports
+// a different amount or status than the one that has been requested can be simulated
+func (driver *Driver) Override(outTradeNo string, paymentResult *transaction.PaymentResult) error {
+
+	driver.mu.Lock()
+	defer driver.mu.Unlock()
+
+	payment, ok := driver.payments[outTradeNo]
+	if !ok {
+		return errors.New("no payment found")
+	}
+
+	overriddenResult := *paymentResult
+	payment.Result = &overriddenResult
+	return nil
+}
+
+// VerifyCallback is a method that reads a callback payload produced by Settle,
+// only payloads that match a settled payment are accepted
+func (driver *Driver) VerifyCallback(payload []byte) (*transaction.PaymentResult, error) {
+
+	paymentResult := new(transaction.PaymentResult)
+	err := json.Unmarshal(payload, paymentResult)
+	if err != nil {
+		return nil, errors.New("invalid notification payload")
+	}
+
+	driver.mu.Lock()
+	defer driver.mu.Unlock()
+
+	payment, ok := driver.payments[paymentResult.OutTradeNo]
+	if !ok || *payment.Result != *paymentResult {
+		return nil, errors.New("unable to verify notification")
+	}
+
+	return paymentResult, nil
+}
+
+// QueryStatus is a method that returns the current state of a payment
+func (driver *Driver) QueryStatus(outTradeNo string) (*transaction.PaymentResult, error) {
+
+	driver.mu.Lock()
+	defer driver.mu.Unlock()
+
+	payment, ok := driver.payments[outTradeNo]
+	if !ok {
+		return nil, errors.New("no payment found")
+	}
+
+	paymentResult := *payment.Result
+	return &paymentResult, nil
+}
+
+// Refund is a method that pays back part or all of a completed payment
+func (driver *Driver) Refund(outTradeNo, tradeNo string, amount money.Amount) error {
+
+	driver.mu.Lock()
+	defer driver.mu.Unlock()
+
+	payment, ok := driver.payments[outTradeNo]
+	if !ok || payment.Result.TradeNo != tradeNo {
+		return errors.New("no payment found")
+	}
+
+	if payment.Result.Status != entity.TransactionStatusComplete {
+		return errors.New("only completed payments can be refunded")
+	}
+
+	if payment.Refunded.Add(amount) > payment.Result.TotalAmount {
+		return errors.New("refund exceeds the paid amount")
+	}
+
+	payment.Refunded = payment.Refunded.Add(amount)
+	return nil
+}
@@ -0,0 +1,251 @@
+package telebirr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/money"
+	"github.com/Benyam-S/onemembership/tools"
+	"github.com/Benyam-S/onemembership/transaction"
+	"github.com/google/go-querystring/query"
+)
+
+// TradeStatusCompleted is a constant that holds the trade status telebirr sends when a payment has been completed
+const TradeStatusCompleted = 2
+
+// CurrencyType is a constant that holds the currency telebirr payments are made in
+const CurrencyType = "ETB"
+
+// Driver is a type that defines the telebirr payment provider
+type Driver struct {
+	account *transaction.TelebirrAPIAccount
+	client  *http.Client
+}
+
+// NewTelebirrDriver is a function that returns a new telebirr payment provider for the given api account
+func NewTelebirrDriver(account *transaction.TelebirrAPIAccount) transaction.PaymentProvider {
+	return &Driver{account: account, client: new(http.Client)}
+}
+
+// AppID is a method that returns the telebirr app id transactions made through the driver are recorded with
+func (driver *Driver) AppID() string {
+	return driver.account.AppID
+}
+
+// Currency is a method that returns the currency payments made through telebirr are settled in
+func (driver *Driver) Currency() string {
+	return CurrencyType
+}
+
+// TransactionFee is a method that returns the fee telebirr adds to each payment
+func (driver *Driver) TransactionFee() money.Amount {
+	return driver.account.TransactionFee
+}
+
+// Initiate is a method that requests a telebirr H5 web url the user should pay through
+func (driver *Driver) Initiate(request *transaction.PaymentRequest) (string, error) {
+
+	type TelebirrRequest struct {
+		AppID string `json:"appid"`
+		Sign  string `json:"sign"`
+		USSD  string `json:"ussd"`
+	}
+
+	type TelebirrRequestParameters struct {
+		AppID          string `json:"appId" url:"appId"`
+		AppKey         string `json:"-" url:"appKey"` // since it isn't included in the ussd value no need to change it to json format
+		Nonce          string `json:"nonce" url:"nonce"`
+		NotifyURL      string `json:"notifyUrl" url:"notifyUrl,omitempty"`
+		OutTradeNo     string `json:"outTradeNo" url:"outTradeNo"`
+		ReceiverName   string `json:"receiveName" url:"receiveName,omitempty"`
+		ReturnURL      string `json:"returnUrl" url:"returnUrl,omitempty"`
+		ShortCode      string `json:"shortCode" url:"shortCode"`
+		Subject        string `json:"subject" url:"subject,omitempty"`
+		TimeoutExpress string `json:"timeoutExpress" url:"timeoutExpress"`
+		Timestamp      string `json:"timestamp" url:"timestamp"`
+		TotalAmount    string `json:"totalAmount" url:"totalAmount"`
+	}
+
+	type TelebirrResponse struct {
+		Code    string            `json:"code"`
+		Message string            `json:"msg"`
+		Data    map[string]string `json:"data"`
+	}
+
+	if !strings.EqualFold(request.CurrencyType, CurrencyType) {
+		return "", errors.New("telebirr only accepts payments in " + CurrencyType)
+	}
+
+	telebirrRequestParameters := &TelebirrRequestParameters{
+		AppID:          driver.account.AppID,
+		AppKey:         driver.account.AppKey,
+		Nonce:          request.Nonce,
+		OutTradeNo:     request.OutTradeNo,
+		NotifyURL:      driver.account.NotifyURL,
+		ReceiverName:   request.ReceiverName,
+		ReturnURL:      driver.account.ReturnURL,
+		ShortCode:      driver.account.ShortCode,
+		Subject:        request.Subject,
+		TimeoutExpress: strconv.FormatInt(request.TimeoutExpress, 10),
+		Timestamp:      strconv.FormatInt(time.Now().Unix(), 10),
+		TotalAmount:    request.TotalAmount.String(),
+	}
+
+	telebirrParameterS, err := json.Marshal(telebirrRequestParameters)
+	if err != nil {
+		return "", err
+	}
+
+	publicKey, err := tools.BytesToPublicKey(driver.account.PublicKey, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var encryptedBytes []byte
+	source := string(telebirrParameterS)
+	for len(source) > 0 {
+		input := tools.Substr(source, 0, 117)
+		source = tools.Substr(source, 117, len(source))
+
+		encryptedChunkBytes, err := tools.EncryptWithPublicKey([]byte(input), publicKey)
+		if err != nil {
+			return "", err
+		}
+
+		encryptedBytes = append(encryptedBytes, encryptedChunkBytes...)
+	}
+	encryptedValue := base64.URLEncoding.EncodeToString(encryptedBytes)
+
+	urlValues, err := query.Values(telebirrRequestParameters)
+	if err != nil {
+		return "", errors.New("unable to construct valid request")
+	}
+
+	// Incase
+	parsedURLValue, err := url.PathUnescape(urlValues.Encode())
+	if err != nil {
+		return "", errors.New("unable to construct valid request")
+	}
+
+	hasher := sha256.New()
+	hasher.Write([]byte(parsedURLValue))
+	signedValue := base64.URLEncoding.EncodeToString(hasher.Sum(nil))
+
+	telebirrRequest := &TelebirrRequest{AppID: driver.account.AppID, USSD: encryptedValue, Sign: signedValue}
+
+	jsonOutput, _ := json.MarshalIndent(telebirrRequest, "", "\t")
+	output := bytes.NewBuffer(jsonOutput)
+	url := driver.account.AccessPoint + "toTradeWebPay"
+
+	httpRequest, err := http.NewRequest("POST", url, output)
+	if err != nil {
+		return "", err
+	}
+
+	response, err := driver.client.Do(httpRequest)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+
+	telebirrResponse := new(TelebirrResponse)
+	err = json.Unmarshal(responseBody, telebirrResponse)
+	if err != nil {
+		return "", err
+	}
+
+	// Means error has occurred
+	if telebirrResponse.Code != "0" {
+		return "", errors.New("unable to generate web url")
+	}
+
+	return telebirrResponse.Data["toPayUrl"], nil
+}
+
+// VerifyCallback is a method that decrypts a telebirr payment notification using the telebirr public key,
+// a notification that can't be decrypted with the key hasn't been sent by telebirr
+func (driver *Driver) VerifyCallback(payload []byte) (*transaction.PaymentResult, error) {
+
+	type TelebirrNotification struct {
+		MSISDN        string `json:"msisdn"`
+		OutTradeNo    string `json:"outTradeNo"`
+		TotalAmount   string `json:"totalAmount"`
+		TradeDate     int64  `json:"tradeDate"`
+		TradeNo       string `json:"tradeNo"`
+		TradeStatus   int64  `json:"tradeStatus"`
+		TransactionNo string `json:"transactionNo"`
+	}
+
+	encryptedBytes, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(payload)))
+	if err != nil {
+		return nil, errors.New("invalid notification payload")
+	}
+
+	publicKey, err := tools.BytesToPublicKey(driver.account.PublicKey, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Telebirr encrypts the notification in chunks where each chunk has the same size as the key
+	chunkSize := publicKey.Size()
+	if len(encryptedBytes) == 0 || len(encryptedBytes)%chunkSize != 0 {
+		return nil, errors.New("invalid notification payload")
+	}
+
+	var decryptedBytes []byte
+	for len(encryptedBytes) > 0 {
+		decryptedChunkBytes, err := tools.DecryptWithPublicKey(encryptedBytes[:chunkSize], publicKey)
+		if err != nil {
+			return nil, errors.New("unable to verify notification")
+		}
+
+		decryptedBytes = append(decryptedBytes, decryptedChunkBytes...)
+		encryptedBytes = encryptedBytes[chunkSize:]
+	}
+
+	telebirrNotification := new(TelebirrNotification)
+	err = json.Unmarshal(decryptedBytes, telebirrNotification)
+	if err != nil {
+		return nil, errors.New("invalid notification payload")
+	}
+
+	totalAmount, err := money.Parse(telebirrNotification.TotalAmount)
+	if err != nil {
+		return nil, errors.New("invalid notification payload")
+	}
+
+	paymentResult := &transaction.PaymentResult{OutTradeNo: telebirrNotification.OutTradeNo,
+		TradeNo: telebirrNotification.TradeNo, TotalAmount: totalAmount, Status: entity.TransactionStatusFailed}
+	if telebirrNotification.TradeStatus == TradeStatusCompleted {
+		paymentResult.Status = entity.TransactionStatusComplete
+	}
+
+	return paymentResult, nil
+}
+
+// QueryStatus is a method that queries the state of a payment, the telebirr H5 api doesn't provide an order query
+// so the state of a payment is only known through its notification
+func (driver *Driver) QueryStatus(outTradeNo string) (*transaction.PaymentResult, error) {
+	return nil, transaction.ErrUnsupportedOperation
+}
+
+// Refund is a method that pays back a payment, the telebirr H5 api doesn't provide refunds
+// so telebirr refunds have to be paid back manually
+func (driver *Driver) Refund(outTradeNo, tradeNo string, amount money.Amount) error {
+	return transaction.ErrUnsupportedOperation
+}
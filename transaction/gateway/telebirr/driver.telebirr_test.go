@@ -0,0 +1,96 @@
+package telebirr_test
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/money"
+	"github.com/Benyam-S/onemembership/transaction"
+	"github.com/Benyam-S/onemembership/transaction/gateway/telebirr"
+	"github.com/Benyam-S/onemembership/transaction/internal/transactiontest"
+)
+
+func TestTelebirrDriver(t *testing.T) {
+
+	const appID = "telebirr-test-app"
+
+	server := transactiontest.NewTelebirrServer(t, appID)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	driver := telebirr.NewTelebirrDriver(&transaction.TelebirrAPIAccount{AccessPoint: httpServer.URL + "/",
+		AppID: appID, AppKey: "telebirr-test-key", ShortCode: "500000", PublicKey: server.PublicKey()})
+
+	t.Run("web pay request", func(t *testing.T) {
+
+		payURL, err := driver.Initiate(&transaction.PaymentRequest{Nonce: "N_ST-1", OutTradeNo: "T_ST-1",
+			ReceiverName: "Project", Subject: "Plan", TotalAmount: money.Amount(10000),
+			CurrencyType: telebirr.CurrencyType, TimeoutExpress: 60})
+		if err != nil {
+			t.Fatalf("initiating payment: %v", err)
+		}
+
+		if !strings.HasSuffix(payURL, "T_ST-1") {
+			t.Fatalf("unexpected pay url %q", payURL)
+		}
+
+		request := server.Request("T_ST-1")
+		if request["totalAmount"] != "100.00" || request["nonce"] != "N_ST-1" {
+			t.Fatalf("unexpected web pay request %v", request)
+		}
+	})
+
+	t.Run("valid notification", func(t *testing.T) {
+
+		payload := server.Notify("T_ST-1", "TB-1", "100.00", telebirr.TradeStatusCompleted)
+
+		paymentResult, err := driver.VerifyCallback(payload)
+		if err != nil {
+			t.Fatalf("verifying valid notification: %v", err)
+		}
+
+		if *paymentResult != (transaction.PaymentResult{OutTradeNo: "T_ST-1", TradeNo: "TB-1",
+			TotalAmount: money.Amount(10000), Status: entity.TransactionStatusComplete}) {
+			t.Fatalf("unexpected payment result %+v", paymentResult)
+		}
+	})
+
+	t.Run("failed notification", func(t *testing.T) {
+
+		payload := server.Notify("T_ST-1", "TB-1", "100.00", telebirr.TradeStatusCompleted+1)
+
+		paymentResult, err := driver.VerifyCallback(payload)
+		if err != nil || paymentResult.Status != entity.TransactionStatusFailed {
+			t.Fatalf("unexpected payment result %+v, %v", paymentResult, err)
+		}
+	})
+
+	t.Run("tampered ciphertext", func(t *testing.T) {
+
+		payload := server.Notify("T_ST-2", "TB-2", "100.00", telebirr.TradeStatusCompleted)
+
+		encryptedBytes, err := base64.StdEncoding.DecodeString(string(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		encryptedBytes[len(encryptedBytes)/2] ^= 0x01
+		tamperedPayload := []byte(base64.StdEncoding.EncodeToString(encryptedBytes))
+
+		if _, err := driver.VerifyCallback(tamperedPayload); err == nil {
+			t.Fatal("tampered notification has been accepted")
+		}
+	})
+
+	t.Run("foreign key", func(t *testing.T) {
+
+		impostor := transactiontest.NewTelebirrServer(t, appID)
+		payload := impostor.Notify("T_ST-3", "TB-3", "100.00", telebirr.TradeStatusCompleted)
+
+		if _, err := driver.VerifyCallback(payload); err == nil {
+			t.Fatal("notification signed with another key has been accepted")
+		}
+	})
+}
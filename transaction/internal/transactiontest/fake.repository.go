@@ -0,0 +1,197 @@
+// Package transactiontest provides in-memory fakes of the repositories and services the transaction service
+// depends on, so payment flows can be tested without a database or a real payment gateway
+package transactiontest
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/transaction"
+)
+
+// Clock is a type that defines a clock that only moves when it is told to
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock is a function that returns a new clock stopped at the given moment
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now is a method that returns the moment the clock has been stopped at
+func (clock *Clock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	return clock.now
+}
+
+// Advance is a method that moves the clock forward by the given duration
+func (clock *Clock) Advance(duration time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	clock.now = clock.now.Add(duration)
+}
+
+// SubscriptionTransactionRepository is a type that defines an in-memory subscription transaction repository,
+// the methods the tests don't reach are left to the embedded interface
+type SubscriptionTransactionRepository struct {
+	transaction.ISubscriptionTransactionRepository
+	mu            sync.Mutex
+	clock         *Clock
+	subscriptions *SubscriptionService
+	transactions  map[string]entity.SubscriptionTransaction
+}
+
+// NewSubscriptionTransactionRepository is a function that returns a new empty subscription transaction repository,
+// the subscription service is used for finding the transactions that haven't activated a subscription
+func NewSubscriptionTransactionRepository(clock *Clock,
+	subscriptions *SubscriptionService) *SubscriptionTransactionRepository {
+
+	return &SubscriptionTransactionRepository{clock: clock, subscriptions: subscriptions,
+		transactions: make(map[string]entity.SubscriptionTransaction)}
+}
+
+// Put is a method that stores the subscription transaction as it is, replacing any previous state
+func (repo *SubscriptionTransactionRepository) Put(subscriptionTransaction entity.SubscriptionTransaction) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.transactions[subscriptionTransaction.ID] = subscriptionTransaction
+}
+
+// Find is a method that finds a stored subscription transaction using its id or out trade number
+func (repo *SubscriptionTransactionRepository) Find(identifier string) (*entity.SubscriptionTransaction, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, storedTransaction := range repo.transactions {
+		subscriptionTransaction := storedTransaction
+		if subscriptionTransaction.ID == identifier || subscriptionTransaction.OutTradeNo == identifier {
+			return &subscriptionTransaction, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+// FindStale is a method that finds the pending subscription transactions whose timeout has passed before the given moment
+func (repo *SubscriptionTransactionRepository) FindStale(moment time.Time) []*entity.SubscriptionTransaction {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	subscriptionTransactions := make([]*entity.SubscriptionTransaction, 0)
+	for _, storedTransaction := range repo.transactions {
+		subscriptionTransaction := storedTransaction
+		timeout := time.Duration(subscriptionTransaction.TimeoutExpress) * time.Minute
+		if subscriptionTransaction.Status == entity.TransactionStatusPending &&
+			!subscriptionTransaction.CreatedAt.Add(timeout).After(moment) {
+			subscriptionTransactions = append(subscriptionTransactions, &subscriptionTransaction)
+		}
+	}
+	return subscriptionTransactions
+}
+
+// FindUnactivated is a method that finds the completed subscription transactions last updated within the given period
+// that haven't activated a subscription
+func (repo *SubscriptionTransactionRepository) FindUnactivated(from, to time.Time) []*entity.SubscriptionTransaction {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	subscriptionTransactions := make([]*entity.SubscriptionTransaction, 0)
+	for _, storedTransaction := range repo.transactions {
+		subscriptionTransaction := storedTransaction
+		if subscriptionTransaction.Status == entity.TransactionStatusComplete &&
+			!subscriptionTransaction.UpdatedAt.Before(from) && !subscriptionTransaction.UpdatedAt.After(to) &&
+			(repo.subscriptions == nil || !repo.subscriptions.IsActivated(subscriptionTransaction.ID)) {
+			subscriptionTransactions = append(subscriptionTransactions, &subscriptionTransaction)
+		}
+	}
+	return subscriptionTransactions
+}
+
+// Update is a method that replaces a stored subscription transaction
+func (repo *SubscriptionTransactionRepository) Update(subscriptionTransaction *entity.SubscriptionTransaction) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.transactions[subscriptionTransaction.ID]; !ok {
+		return errors.New("record not found")
+	}
+
+	subscriptionTransaction.UpdatedAt = repo.clock.Now()
+	repo.transactions[subscriptionTransaction.ID] = *subscriptionTransaction
+	return nil
+}
+
+// Settle is a method that settles a pending subscription transaction or completes an expired one,
+// a trade number can only settle a single transaction
+func (repo *SubscriptionTransactionRepository) Settle(id, tradeNo, status string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	subscriptionTransaction, ok := repo.transactions[id]
+	settleable := subscriptionTransaction.Status == entity.TransactionStatusPending ||
+		(subscriptionTransaction.Status == entity.TransactionStatusExpired && status == entity.TransactionStatusComplete)
+	if !ok || !settleable {
+		return transaction.ErrPaymentProcessed
+	}
+
+	if !repo.isUniqueTradeNo(tradeNo) {
+		return transaction.ErrPaymentMismatch
+	}
+
+	subscriptionTransaction.TradeNo = entity.NullableString(tradeNo)
+	subscriptionTransaction.Status = status
+	subscriptionTransaction.UpdatedAt = repo.clock.Now()
+	repo.transactions[id] = subscriptionTransaction
+	return nil
+}
+
+// Expire is a method that expires a pending subscription transaction
+func (repo *SubscriptionTransactionRepository) Expire(id string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	subscriptionTransaction, ok := repo.transactions[id]
+	if !ok || subscriptionTransaction.Status != entity.TransactionStatusPending {
+		return transaction.ErrPaymentProcessed
+	}
+
+	subscriptionTransaction.Status = entity.TransactionStatusExpired
+	subscriptionTransaction.UpdatedAt = repo.clock.Now()
+	repo.transactions[id] = subscriptionTransaction
+	return nil
+}
+
+// IsUniqueTradeNo is a method that checks whether no stored subscription transaction holds the trade number
+func (repo *SubscriptionTransactionRepository) IsUniqueTradeNo(tradeNo interface{}) bool {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return repo.isUniqueTradeNo(tradeNo)
+}
+
+// isUniqueTradeNo is a method that checks the trade number uniqueness while the repository is locked
+func (repo *SubscriptionTransactionRepository) isUniqueTradeNo(tradeNo interface{}) bool {
+	for _, subscriptionTransaction := range repo.transactions {
+		if subscriptionTransaction.TradeNo != "" && string(subscriptionTransaction.TradeNo) == tradeNo {
+			return false
+		}
+	}
+	return true
+}
+
+// SPSubscriptionTransactionRepository is a type that defines a repository without any service provider transaction
+type SPSubscriptionTransactionRepository struct {
+	transaction.ISPSubscriptionTransactionRepository
+}
+
+// FindStale is a method that returns no service provider subscription transaction
+func (repo *SPSubscriptionTransactionRepository) FindStale(moment time.Time) []*entity.SPSubscriptionTransaction {
+	return []*entity.SPSubscriptionTransaction{}
+}
@@ -0,0 +1,91 @@
+package transactiontest
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/Benyam-S/onemembership/audit"
+	"github.com/Benyam-S/onemembership/common"
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/subscription"
+)
+
+// CommonService is a type that defines a common service whose uniqueness checks run against the fake repository
+type CommonService struct {
+	common.IService
+	Repo *SubscriptionTransactionRepository
+}
+
+// IsUnique is a method that checks whether the trade number hasn't been stored yet, other columns are always unique
+func (service *CommonService) IsUnique(columnName string, columnValue interface{}, tableName string) bool {
+	if columnName == "trade_no" {
+		return service.Repo.IsUniqueTradeNo(columnValue)
+	}
+	return true
+}
+
+// AuditService is a type that defines an audit service that discards the recorded changes
+type AuditService struct {
+	audit.IService
+}
+
+// Record is a method that discards the recorded change
+func (service *AuditService) Record(actor, action, entityID string, before, after interface{}) error {
+	return nil
+}
+
+// SubscriptionService is a type that defines a subscription service that keeps the activated subscriptions in memory
+type SubscriptionService struct {
+	subscription.IService
+	mu             sync.Mutex
+	activated      map[string]*entity.Subscription // Keyed by the id of the transaction that has activated the subscription
+	FailActivation bool
+}
+
+// NewSubscriptionService is a function that returns a new subscription service without any activated subscription
+func NewSubscriptionService() *SubscriptionService {
+	return &SubscriptionService{activated: make(map[string]*entity.Subscription)}
+}
+
+// ActivateSubscription is a method that activates a subscription for a completed subscription transaction only once
+func (service *SubscriptionService) ActivateSubscription(
+	subscriptionTransaction *entity.SubscriptionTransaction) (*entity.Subscription, error) {
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	if subscriptionTransaction.Status != entity.TransactionStatusComplete {
+		return nil, errors.New("subscription transaction hasn't been completed")
+	}
+
+	if service.FailActivation {
+		return nil, errors.New("unable to activate subscription")
+	}
+
+	if service.activated[subscriptionTransaction.ID] != nil {
+		return nil, errors.New("subscription transaction has already been used for activation")
+	}
+
+	newSubscription := &entity.Subscription{ID: "SUB-" + subscriptionTransaction.ID,
+		SubscriberID: subscriptionTransaction.UserID, SubscriptionPlanID: subscriptionTransaction.PlanID,
+		TransactionID: subscriptionTransaction.ID, Status: entity.SubscriptionStatusActive}
+	service.activated[subscriptionTransaction.ID] = newSubscription
+
+	return newSubscription, nil
+}
+
+// IsActivated is a method that checks whether the subscription transaction has activated a subscription
+func (service *SubscriptionService) IsActivated(transactionID string) bool {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	return service.activated[transactionID] != nil
+}
+
+// NumOfActivated is a method that returns the number of subscriptions that have been activated
+func (service *SubscriptionService) NumOfActivated() int {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	return len(service.activated)
+}
@@ -0,0 +1,141 @@
+package transactiontest
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/Benyam-S/onemembership/tools"
+)
+
+// TelebirrServer is a type that defines a local telebirr server, it accepts H5 web pay requests encrypted
+// with its public key and produces payment notifications encrypted with its private key
+type TelebirrServer struct {
+	mu         sync.Mutex
+	t          testing.TB
+	privateKey *rsa.PrivateKey
+	appID      string
+	requests   map[string]map[string]string // The decrypted request parameters keyed by the out trade number
+}
+
+// NewTelebirrServer is a function that returns a new local telebirr server accepting requests of the given app id
+func NewTelebirrServer(t testing.TB, appID string) *TelebirrServer {
+
+	privateKey, err := tools.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &TelebirrServer{t: t, privateKey: privateKey, appID: appID, requests: make(map[string]map[string]string)}
+}
+
+// PublicKey is a method that returns the public key the server's requests are encrypted with
+func (server *TelebirrServer) PublicKey() []byte {
+
+	publicKey, err := tools.PublicKeyToBytes(&server.privateKey.PublicKey)
+	if err != nil {
+		server.t.Fatal(err)
+	}
+	return publicKey
+}
+
+// ServeHTTP is a method that handles the H5 web pay requests
+func (server *TelebirrServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	respond := func(code, message string, data map[string]string) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": code, "msg": message, "data": data})
+	}
+
+	if r.Method != http.MethodPost || r.URL.Path != "/toTradeWebPay" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var request struct {
+		AppID string `json:"appid"`
+		Sign  string `json:"sign"`
+		USSD  string `json:"ussd"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.AppID != server.appID {
+		respond("1", "invalid request", nil)
+		return
+	}
+
+	encryptedBytes, err := base64.URLEncoding.DecodeString(request.USSD)
+	if err != nil {
+		respond("1", "invalid ussd", nil)
+		return
+	}
+
+	var decryptedBytes []byte
+	chunkSize := server.privateKey.Size()
+	for len(encryptedBytes) > 0 {
+		if len(encryptedBytes) < chunkSize {
+			respond("1", "invalid ussd", nil)
+			return
+		}
+
+		decryptedChunkBytes, err := tools.DecryptWithPrivateKey(encryptedBytes[:chunkSize], server.privateKey)
+		if err != nil {
+			respond("1", "invalid ussd", nil)
+			return
+		}
+
+		decryptedBytes = append(decryptedBytes, decryptedChunkBytes...)
+		encryptedBytes = encryptedBytes[chunkSize:]
+	}
+
+	parameters := make(map[string]string)
+	if err := json.Unmarshal(decryptedBytes, &parameters); err != nil || parameters["appId"] != server.appID {
+		respond("1", "invalid ussd", nil)
+		return
+	}
+
+	server.mu.Lock()
+	server.requests[parameters["outTradeNo"]] = parameters
+	server.mu.Unlock()
+
+	respond("0", "success", map[string]string{"toPayUrl": "https://telebirr.test/pay/" + parameters["outTradeNo"]})
+}
+
+// Request is a method that returns the decrypted parameters of the web pay request made for the out trade number
+func (server *TelebirrServer) Request(outTradeNo string) map[string]string {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	return server.requests[outTradeNo]
+}
+
+// Notify is a method that returns the payment notification telebirr sends for a payment with the given trade status
+func (server *TelebirrServer) Notify(outTradeNo, tradeNo, totalAmount string, tradeStatus int64) []byte {
+
+	notification, err := json.Marshal(map[string]interface{}{"msisdn": "251900000000", "outTradeNo": outTradeNo,
+		"totalAmount": totalAmount, "tradeDate": 1600000000000, "tradeNo": tradeNo,
+		"tradeStatus": tradeStatus, "transactionNo": tradeNo})
+	if err != nil {
+		server.t.Fatal(err)
+	}
+
+	// Type 1 padding takes 11 bytes of each chunk
+	var encryptedBytes []byte
+	chunkSize := server.privateKey.Size() - 11
+	for len(notification) > 0 {
+		size := chunkSize
+		if len(notification) < size {
+			size = len(notification)
+		}
+
+		encryptedChunkBytes, err := tools.EncryptWithPrivateKey(notification[:size], server.privateKey)
+		if err != nil {
+			server.t.Fatal(err)
+		}
+
+		encryptedBytes = append(encryptedBytes, encryptedChunkBytes...)
+		notification = notification[size:]
+	}
+
+	return []byte(base64.StdEncoding.EncodeToString(encryptedBytes))
+}
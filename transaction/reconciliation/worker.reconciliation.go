@@ -0,0 +1,245 @@
+package reconciliation
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/log"
+	"github.com/Benyam-S/onemembership/subscription"
+	"github.com/Benyam-S/onemembership/transaction"
+)
+
+// EventTransactionCompleted is a constant that states a stale transaction has been paid and completed
+const EventTransactionCompleted = "Transaction_Completed"
+
+// EventTransactionFailed is a constant that states a stale transaction has been failed at the payment gateway
+const EventTransactionFailed = "Transaction_Failed"
+
+// EventTransactionExpired is a constant that states a stale transaction hasn't been paid before its timeout
+const EventTransactionExpired = "Transaction_Expired"
+
+// EventTransactionMismatch is a constant that states the payment gateway reported a payment that doesn't match
+// the transaction, or a paid transaction couldn't be activated
+const EventTransactionMismatch = "Transaction_Mismatch"
+
+// activationDelay is a constant that holds how long a completed transaction is left to its notification handler
+// before the worker activates it
+const activationDelay = 5 * time.Minute
+
+// activationLookback is a constant that holds how far back completed transactions are checked for a missing activation
+const activationLookback = 7 * 24 * time.Hour
+
+// Clock is a type that defines a function that returns the current time, it can be replaced to drive the worker in tests
+type Clock func() time.Time
+
+// Event is a type that defines a reconciliation event emitted by the reconciliation worker,
+// only one of the subscription transaction and the service provider subscription transaction is set
+type Event struct {
+	Type                      string
+	SubscriptionTransaction   *entity.SubscriptionTransaction
+	SPSubscriptionTransaction *entity.SPSubscriptionTransaction
+	Subscription              *entity.Subscription   // Only set when a completed subscription transaction has been activated
+	SPSubscription            *entity.SPSubscription // Only set when a completed SP subscription transaction has been activated
+	Err                       error                  // Only set for the mismatch event
+}
+
+// Handler is a type that defines a function that handles a reconciliation event, such as notifying the subscriber
+type Handler func(event *Event) error
+
+// Worker is a type that defines a background worker that settles pending transactions whose timeout has passed
+// and grants the subscriptions of completed payments that haven't been activated
+type Worker struct {
+	mu                  sync.Mutex
+	transactionService  transaction.IService
+	subscriptionService subscription.IService
+	handler             Handler
+	now                 Clock
+	logger              *log.Logger
+}
+
+// NewReconciliationWorker is a function that returns a new transaction reconciliation worker, if clock is nil time.Now is used
+func NewReconciliationWorker(transactionService transaction.IService, subscriptionService subscription.IService,
+	handler Handler, clock Clock, transactionLogger *log.Logger) *Worker {
+
+	if clock == nil {
+		clock = time.Now
+	}
+
+	return &Worker{transactionService: transactionService, subscriptionService: subscriptionService,
+		handler: handler, now: clock, logger: transactionLogger}
+}
+
+// Start is a method that scans for stale transactions every interval until the stop channel is closed
+func (worker *Worker) Start(interval time.Duration, stop <-chan struct{}) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		worker.Scan()
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan is a method that performs a single pass over the stale pending transactions and the completed subscription
+// transactions that haven't activated a subscription. Transactions that couldn't be settled, such as when the gateway
+// is unreachable, stay pending for the next scan, and transactions whose gateway can't be queried stay pending
+// for their notification. Mismatching transactions and completed transactions that couldn't be activated are reported
+// on every scan until they have been resolved.
+func (worker *Worker) Scan() {
+
+	worker.mu.Lock()
+	defer worker.mu.Unlock()
+
+	now := worker.now()
+
+	/* ---------------------------- Logging ---------------------------- */
+	worker.logger.Log(fmt.Sprintf("Started transaction reconciliation scanning process { Now : %s }", now),
+		worker.logger.Logs.TransactionLogFile)
+
+	subscriptionTransactions := worker.transactionService.FindStaleSubscriptionTransactions(now)
+	for _, subscriptionTransaction := range subscriptionTransactions {
+		worker.reconcileSubscriptionTransaction(subscriptionTransaction, now)
+	}
+
+	spSubscriptionTransactions := worker.transactionService.FindStaleSPSubscriptionTransactions(now)
+	for _, spSubscriptionTransaction := range spSubscriptionTransactions {
+		worker.reconcileSPSubscriptionTransaction(spSubscriptionTransaction, now)
+	}
+
+	// Payments that have been completed without granting the subscription, such as when the activation has failed
+	unactivatedTransactions := worker.transactionService.FindUnactivatedSubscriptionTransactions(
+		now.Add(-activationLookback), now.Add(-activationDelay))
+	for _, subscriptionTransaction := range unactivatedTransactions {
+		worker.activateSubscriptionTransaction(subscriptionTransaction)
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	worker.logger.Log(fmt.Sprintf("Finished transaction reconciliation scanning process { Scanned : %d }",
+		len(subscriptionTransactions)+len(spSubscriptionTransactions)+len(unactivatedTransactions)),
+		worker.logger.Logs.TransactionLogFile)
+}
+
+// reconcileSubscriptionTransaction is a method that settles a stale subscription transaction
+// and activates the subscription it has been paid for
+func (worker *Worker) reconcileSubscriptionTransaction(staleTransaction *entity.SubscriptionTransaction, now time.Time) {
+
+	subscriptionTransaction, err := worker.transactionService.ReconcileSubscriptionTransaction(staleTransaction.ID, now)
+	if err != nil {
+		if err == transaction.ErrPaymentMismatch {
+			worker.emit(&Event{Type: EventTransactionMismatch, SubscriptionTransaction: staleTransaction, Err: err})
+		}
+		return
+	}
+
+	event := &Event{Type: eventType(subscriptionTransaction.Status), SubscriptionTransaction: subscriptionTransaction}
+
+	// The payment has been completed without the gateway notifying it, so activating the subscription here
+	if subscriptionTransaction.Status == entity.TransactionStatusComplete {
+		event.Subscription, err = worker.subscriptionService.ActivateSubscription(subscriptionTransaction)
+		if err != nil {
+			event.Type = EventTransactionMismatch
+			event.Err = err
+		}
+	}
+
+	worker.emit(event)
+}
+
+// activateSubscriptionTransaction is a method that activates the subscription of a completed subscription transaction
+// that hasn't activated a subscription yet
+func (worker *Worker) activateSubscriptionTransaction(subscriptionTransaction *entity.SubscriptionTransaction) {
+
+	event := &Event{Type: EventTransactionCompleted, SubscriptionTransaction: subscriptionTransaction}
+
+	var err error
+	event.Subscription, err = worker.subscriptionService.ActivateSubscription(subscriptionTransaction)
+	if err != nil {
+		event.Type = EventTransactionMismatch
+		event.Err = err
+	}
+
+	worker.emit(event)
+}
+
+// reconcileSPSubscriptionTransaction is a method that settles a stale service provider subscription transaction
+// and creates or extends the service provider's subscription it has been paid for
+func (worker *Worker) reconcileSPSubscriptionTransaction(staleTransaction *entity.SPSubscriptionTransaction,
+	now time.Time) {
+
+	spSubscriptionTransaction, err := worker.transactionService.ReconcileSPSubscriptionTransaction(staleTransaction.ID, now)
+	if err != nil {
+		if err == transaction.ErrPaymentMismatch {
+			worker.emit(&Event{Type: EventTransactionMismatch, SPSubscriptionTransaction: staleTransaction, Err: err})
+		}
+		return
+	}
+
+	event := &Event{Type: eventType(spSubscriptionTransaction.Status),
+		SPSubscriptionTransaction: spSubscriptionTransaction}
+
+	if spSubscriptionTransaction.Status == entity.TransactionStatusComplete {
+		event.SPSubscription, err = worker.subscriptionService.ActivateSPSubscription(spSubscriptionTransaction)
+		if err != nil {
+			event.Type = EventTransactionMismatch
+			event.Err = err
+		}
+	}
+
+	worker.emit(event)
+}
+
+// emit is a method that passes the event to the handler and logs the handler's failure
+func (worker *Worker) emit(event *Event) {
+
+	if event.Type == EventTransactionMismatch {
+		/* ---------------------------- Logging ---------------------------- */
+		worker.logger.LogToErrorFile(fmt.Sprintf("Error: For reconciling transaction mismatch found "+
+			"{ Subscription Transaction ID : %s, SP Subscription Transaction ID : %s }, %s",
+			transactionID(event.SubscriptionTransaction), spTransactionID(event.SPSubscriptionTransaction),
+			event.Err.Error()))
+	}
+
+	if err := worker.handler(event); err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		worker.logger.LogToErrorFile(fmt.Sprintf("Error: For handling reconciliation event "+
+			"{ Subscription Transaction ID : %s, SP Subscription Transaction ID : %s, Type : %s }, %s",
+			transactionID(event.SubscriptionTransaction), spTransactionID(event.SPSubscriptionTransaction),
+			event.Type, err.Error()))
+	}
+}
+
+// eventType is a function that returns the event type of a settled transaction status
+func eventType(status string) string {
+	switch status {
+	case entity.TransactionStatusComplete:
+		return EventTransactionCompleted
+	case entity.TransactionStatusFailed:
+		return EventTransactionFailed
+	default:
+		return EventTransactionExpired
+	}
+}
+
+// transactionID is a function that returns the id of a subscription transaction that may not be set
+func transactionID(subscriptionTransaction *entity.SubscriptionTransaction) string {
+	if subscriptionTransaction == nil {
+		return ""
+	}
+	return subscriptionTransaction.ID
+}
+
+// spTransactionID is a function that returns the id of a service provider subscription transaction that may not be set
+func spTransactionID(spSubscriptionTransaction *entity.SPSubscriptionTransaction) string {
+	if spSubscriptionTransaction == nil {
+		return ""
+	}
+	return spSubscriptionTransaction.ID
+}
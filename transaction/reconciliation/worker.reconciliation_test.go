@@ -0,0 +1,329 @@
+package reconciliation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/log"
+	"github.com/Benyam-S/onemembership/money"
+	"github.com/Benyam-S/onemembership/transaction"
+	"github.com/Benyam-S/onemembership/transaction/gateway/sandbox"
+	"github.com/Benyam-S/onemembership/transaction/internal/transactiontest"
+	"github.com/Benyam-S/onemembership/transaction/service"
+)
+
+// unqueryableDriver is a type that defines a sandbox driver whose payments can only be settled through
+// their notification, like the telebirr H5 api
+type unqueryableDriver struct {
+	*sandbox.Driver
+}
+
+func (driver *unqueryableDriver) QueryStatus(outTradeNo string) (*transaction.PaymentResult, error) {
+	return nil, transaction.ErrUnsupportedOperation
+}
+
+// testEnvironment is a type that holds a reconciliation worker running over the sandbox drivers
+type testEnvironment struct {
+	t                   *testing.T
+	clock               *transactiontest.Clock
+	driver              *sandbox.Driver
+	unqueryableDriver   *sandbox.Driver
+	repo                *transactiontest.SubscriptionTransactionRepository
+	subscriptionService *transactiontest.SubscriptionService
+	transactionService  transaction.IService
+	worker              *Worker
+	events              []*Event
+}
+
+const (
+	sandboxAppID     = "sandbox-test-app"
+	unqueryableAppID = "unqueryable-test-app"
+)
+
+func newTestEnvironment(t *testing.T) *testEnvironment {
+
+	environment := &testEnvironment{t: t,
+		clock: transactiontest.NewClock(time.Date(2021, time.June, 10, 12, 0, 0, 0, time.UTC))}
+	environment.driver = sandbox.NewSandboxDriver(sandboxAppID, "ETB", money.Amount(200), "https://sandbox.test/pay/")
+	environment.unqueryableDriver = sandbox.NewSandboxDriver(unqueryableAppID, "ETB", money.Amount(200),
+		"https://unqueryable.test/pay/")
+	environment.subscriptionService = transactiontest.NewSubscriptionService()
+	environment.repo = transactiontest.NewSubscriptionTransactionRepository(environment.clock,
+		environment.subscriptionService)
+
+	gatewayRegistry := transaction.NewGatewayRegistry(
+		[]*entity.PaymentGateway{{ID: 1, Name: "Sandbox"}, {ID: 2, Name: "Unqueryable"}},
+		map[string]transaction.PaymentProvider{"Sandbox": environment.driver,
+			"Unqueryable": &unqueryableDriver{environment.unqueryableDriver}})
+
+	logger := log.NewLogger(&log.LogContainer{}, log.None)
+	environment.transactionService = service.NewTransactionService(nil, environment.repo,
+		&transactiontest.SPSubscriptionTransactionRepository{}, nil, gatewayRegistry, nil, nil, nil,
+		&transactiontest.CommonService{Repo: environment.repo}, &transactiontest.AuditService{}, logger)
+
+	handler := func(event *Event) error {
+		environment.events = append(environment.events, event)
+		return nil
+	}
+
+	environment.worker = NewReconciliationWorker(environment.transactionService, environment.subscriptionService,
+		handler, environment.clock.Now, logger)
+
+	return environment
+}
+
+// initiate is a method that opens a sandbox payment and stores its pending subscription transaction
+func (environment *testEnvironment) initiate(id string) entity.SubscriptionTransaction {
+	return environment.initiateThrough(environment.driver, id)
+}
+
+// initiateThrough is a method that opens a payment through the given sandbox driver
+// and stores its pending subscription transaction
+func (environment *testEnvironment) initiateThrough(driver *sandbox.Driver, id string) entity.SubscriptionTransaction {
+
+	subscriptionTransaction := entity.SubscriptionTransaction{ID: id, UserID: "USER-1", PlanID: "PLAN-1",
+		AppID: driver.AppID(), Nonce: "N_" + id, OutTradeNo: "T_" + id, ReceivedAmount: money.Amount(9800),
+		TransactionFee: money.Amount(200), CurrencyType: "ETB", TimeoutExpress: 60,
+		Status: entity.TransactionStatusPending, CreatedAt: environment.clock.Now(), UpdatedAt: environment.clock.Now()}
+
+	_, err := driver.Initiate(&transaction.PaymentRequest{Nonce: subscriptionTransaction.Nonce,
+		OutTradeNo: subscriptionTransaction.OutTradeNo, ReceiverName: "Project", Subject: "Plan",
+		TotalAmount:  subscriptionTransaction.ReceivedAmount.Add(subscriptionTransaction.TransactionFee),
+		CurrencyType: "ETB", TimeoutExpress: subscriptionTransaction.TimeoutExpress})
+	if err != nil {
+		environment.t.Fatal(err)
+	}
+
+	environment.repo.Put(subscriptionTransaction)
+	return subscriptionTransaction
+}
+
+// scan is a method that moves the clock forward and runs a single scan, returning the events it has emitted
+func (environment *testEnvironment) scan(after time.Duration) []*Event {
+	environment.clock.Advance(after)
+	environment.events = nil
+	environment.worker.Scan()
+	return environment.events
+}
+
+func (environment *testEnvironment) status(id string) string {
+	subscriptionTransaction, err := environment.repo.Find(id)
+	if err != nil {
+		environment.t.Fatal(err)
+	}
+	return subscriptionTransaction.Status
+}
+
+func TestScanCompletesPaidTransaction(t *testing.T) {
+
+	environment := newTestEnvironment(t)
+	subscriptionTransaction := environment.initiate("ST-1")
+
+	// The gateway never notifies the payment, so the payload is dropped
+	if _, err := environment.driver.Settle(subscriptionTransaction.OutTradeNo, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if events := environment.scan(30 * time.Minute); len(events) != 0 {
+		t.Fatalf("transaction has been reconciled before its timeout, events %v", events)
+	}
+
+	events := environment.scan(time.Hour)
+	if len(events) != 1 || events[0].Type != EventTransactionCompleted || events[0].Subscription == nil {
+		t.Fatalf("unexpected events %+v", events)
+	}
+
+	if environment.status("ST-1") != entity.TransactionStatusComplete ||
+		!environment.subscriptionService.IsActivated("ST-1") {
+		t.Fatal("paid transaction hasn't been completed and activated")
+	}
+
+	if events := environment.scan(time.Hour); len(events) != 0 {
+		t.Fatalf("completed transaction has been reconciled again, events %+v", events)
+	}
+}
+
+func TestScanFailsDeclinedTransaction(t *testing.T) {
+
+	environment := newTestEnvironment(t)
+	subscriptionTransaction := environment.initiate("ST-1")
+
+	if _, err := environment.driver.Settle(subscriptionTransaction.OutTradeNo, false); err != nil {
+		t.Fatal(err)
+	}
+
+	events := environment.scan(2 * time.Hour)
+	if len(events) != 1 || events[0].Type != EventTransactionFailed || events[0].Subscription != nil {
+		t.Fatalf("unexpected events %+v", events)
+	}
+
+	if environment.status("ST-1") != entity.TransactionStatusFailed || environment.subscriptionService.NumOfActivated() != 0 {
+		t.Fatal("declined transaction hasn't been failed")
+	}
+}
+
+func TestScanExpiresUnpaidTransaction(t *testing.T) {
+
+	environment := newTestEnvironment(t)
+	environment.initiate("ST-1")
+
+	events := environment.scan(2 * time.Hour)
+	if len(events) != 1 || events[0].Type != EventTransactionExpired {
+		t.Fatalf("unexpected events %+v", events)
+	}
+
+	if environment.status("ST-1") != entity.TransactionStatusExpired {
+		t.Fatal("unpaid transaction hasn't been expired")
+	}
+}
+
+func TestScanReportsMismatchingPayment(t *testing.T) {
+
+	environment := newTestEnvironment(t)
+	subscriptionTransaction := environment.initiate("ST-1")
+
+	// The gateway reports a smaller amount than the one that has been requested
+	err := environment.driver.Override(subscriptionTransaction.OutTradeNo, &transaction.PaymentResult{
+		OutTradeNo: subscriptionTransaction.OutTradeNo, TradeNo: "SANDBOX-1", TotalAmount: money.Amount(100),
+		Status: entity.TransactionStatusComplete})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for scan := 0; scan < 2; scan++ {
+		events := environment.scan(2 * time.Hour)
+		if len(events) != 1 || events[0].Type != EventTransactionMismatch || events[0].Err != transaction.ErrPaymentMismatch {
+			t.Fatalf("unexpected events %+v", events)
+		}
+	}
+
+	if environment.status("ST-1") != entity.TransactionStatusPending || environment.subscriptionService.NumOfActivated() != 0 {
+		t.Fatal("mismatching payment has been applied")
+	}
+}
+
+func TestScanActivatesCompletedTransaction(t *testing.T) {
+
+	environment := newTestEnvironment(t)
+	subscriptionTransaction := environment.initiate("ST-1")
+
+	if _, err := environment.driver.Settle(subscriptionTransaction.OutTradeNo, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// The payment is completed but its activation fails, so it is reported until the subscription has been granted
+	environment.subscriptionService.FailActivation = true
+	events := environment.scan(2 * time.Hour)
+	if len(events) != 1 || events[0].Type != EventTransactionMismatch {
+		t.Fatalf("unexpected events %+v", events)
+	}
+
+	if environment.status("ST-1") != entity.TransactionStatusComplete {
+		t.Fatal("paid transaction hasn't been completed")
+	}
+
+	events = environment.scan(activationDelay + time.Minute)
+	if len(events) != 1 || events[0].Type != EventTransactionMismatch {
+		t.Fatalf("unexpected events %+v", events)
+	}
+
+	environment.subscriptionService.FailActivation = false
+	events = environment.scan(time.Minute)
+	if len(events) != 1 || events[0].Type != EventTransactionCompleted || events[0].Subscription == nil {
+		t.Fatalf("unexpected events %+v", events)
+	}
+
+	if events := environment.scan(time.Hour); len(events) != 0 {
+		t.Fatalf("activated transaction has been activated again, events %+v", events)
+	}
+}
+
+func TestScanLeavesFreshCompletedTransactionToItsNotification(t *testing.T) {
+
+	environment := newTestEnvironment(t)
+	subscriptionTransaction := environment.initiate("ST-1")
+
+	subscriptionTransaction.Status = entity.TransactionStatusComplete
+	subscriptionTransaction.UpdatedAt = environment.clock.Now()
+	environment.repo.Put(subscriptionTransaction)
+
+	if events := environment.scan(time.Minute); len(events) != 0 {
+		t.Fatalf("transaction has been activated while its notification is being handled, events %+v", events)
+	}
+
+	events := environment.scan(activationDelay)
+	if len(events) != 1 || events[0].Type != EventTransactionCompleted {
+		t.Fatalf("unexpected events %+v", events)
+	}
+}
+
+func TestScanLeavesUnqueryableTransactionPending(t *testing.T) {
+
+	environment := newTestEnvironment(t)
+	subscriptionTransaction := environment.initiateThrough(environment.unqueryableDriver, "ST-1")
+
+	// Only the notification can tell whether the payment has been made, so the transaction isn't expired
+	if events := environment.scan(2 * time.Hour); len(events) != 0 {
+		t.Fatalf("unqueryable transaction has been reconciled, events %+v", events)
+	}
+
+	if environment.status("ST-1") != entity.TransactionStatusPending {
+		t.Fatal("unqueryable transaction has been expired")
+	}
+
+	payload, err := environment.unqueryableDriver.Settle(subscriptionTransaction.OutTradeNo, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := environment.transactionService.HandlePaymentNotification(2, payload); err != nil {
+		t.Fatalf("handling late notification: %v", err)
+	}
+
+	if environment.status("ST-1") != entity.TransactionStatusComplete {
+		t.Fatal("notified transaction hasn't been completed")
+	}
+}
+
+func TestNotificationCompletesExpiredTransaction(t *testing.T) {
+
+	environment := newTestEnvironment(t)
+	subscriptionTransaction := environment.initiate("ST-1")
+	declinedTransaction := environment.initiate("ST-2")
+
+	events := environment.scan(2 * time.Hour)
+	if len(events) != 2 || environment.status("ST-1") != entity.TransactionStatusExpired ||
+		environment.status("ST-2") != entity.TransactionStatusExpired {
+		t.Fatalf("unpaid transactions haven't been expired, events %+v", events)
+	}
+
+	// The payments have been made right before the timeout but notified after it
+	payload, err := environment.driver.Settle(subscriptionTransaction.OutTradeNo, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := environment.transactionService.HandlePaymentNotification(1, payload); err != nil {
+		t.Fatalf("handling late notification: %v", err)
+	}
+
+	declinedPayload, err := environment.driver.Settle(declinedTransaction.OutTradeNo, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := environment.transactionService.HandlePaymentNotification(1, declinedPayload); err == nil {
+		t.Fatal("late declined notification has been applied to an expired transaction")
+	}
+
+	if environment.status("ST-1") != entity.TransactionStatusComplete ||
+		environment.status("ST-2") != entity.TransactionStatusExpired {
+		t.Fatal("late notifications haven't been applied as expected")
+	}
+
+	events = environment.scan(activationDelay + time.Minute)
+	if len(events) != 1 || events[0].Type != EventTransactionCompleted || events[0].Subscription == nil {
+		t.Fatalf("unexpected events %+v", events)
+	}
+}
@@ -1,9 +1,15 @@
 package transaction
 
 import (
+	"errors"
+	"time"
+
 	"github.com/Benyam-S/onemembership/entity"
 )
 
+// ErrIdempotencyKeyUsed is an error returned when a transaction has already been created with the same idempotency key
+var ErrIdempotencyKeyUsed = errors.New("idempotency key has already been used")
+
 // IPaymentGatewayRepository is an interface that defines all the repository methods of a payment gateway struct
 type IPaymentGatewayRepository interface {
 	Create(newPaymentGateway *entity.PaymentGateway) error
@@ -16,8 +22,16 @@ type IPaymentGatewayRepository interface {
 // ISubscriptionTransactionRepository is an interface that defines all the repository methods of a subscription transaction struct
 type ISubscriptionTransactionRepository interface {
 	Create(newTransaction *entity.SubscriptionTransaction) error
+	CreateRefund(newRefund *entity.SubscriptionTransaction) error
+	UpdateRefundStatus(id, status string) error
 	Find(identifier string) (*entity.SubscriptionTransaction, error)
 	FindMultiple(identifier string) []*entity.SubscriptionTransaction
+	FindStale(moment time.Time) []*entity.SubscriptionTransaction
+	FindUnactivated(from, to time.Time) []*entity.SubscriptionTransaction
+	Search(filter *SearchFilter, pageNum int64) ([]*entity.SubscriptionTransaction, int64, []*SearchTotal)
+	FindByIdempotencyKey(userID, idempotencyKey string, moment time.Time) (*entity.SubscriptionTransaction, error)
+	Settle(id, tradeNo, status string) error
+	Expire(id string) error
 	Update(transaction *entity.SubscriptionTransaction) error
 	Delete(id string) (*entity.SubscriptionTransaction, error)
 	DeleteMultiple(identifier string) []*entity.SubscriptionTransaction
@@ -28,6 +42,11 @@ type ISPSubscriptionTransactionRepository interface {
 	Create(newTransaction *entity.SPSubscriptionTransaction) error
 	Find(identifier string) (*entity.SPSubscriptionTransaction, error)
 	FindMultiple(identifier string) []*entity.SPSubscriptionTransaction
+	FindStale(moment time.Time) []*entity.SPSubscriptionTransaction
+	Search(filter *SearchFilter, pageNum int64) ([]*entity.SPSubscriptionTransaction, int64, []*SearchTotal)
+	FindByIdempotencyKey(providerID, idempotencyKey string, moment time.Time) (*entity.SPSubscriptionTransaction, error)
+	Settle(id, tradeNo, status string) error
+	Expire(id string) error
 	Update(transaction *entity.SPSubscriptionTransaction) error
 	Delete(id string) (*entity.SPSubscriptionTransaction, error)
 	DeleteMultiple(identifier string) []*entity.SPSubscriptionTransaction
@@ -36,8 +55,11 @@ type ISPSubscriptionTransactionRepository interface {
 // ISPPayrollTransactionRepository is an interface that defines all the repository methods of a service provider payroll transaction struct
 type ISPPayrollTransactionRepository interface {
 	Create(newTransaction *entity.SPPayrollTransaction) error
+	CreateWithdrawal(newTransaction *entity.SPPayrollTransaction) error
+	UpdateStatus(id, status string) error
 	Find(id string) (*entity.SPPayrollTransaction, error)
 	FindMultiple(providerID string) []*entity.SPPayrollTransaction
+	Search(filter *SearchFilter, pageNum int64) ([]*entity.SPPayrollTransaction, int64, []*SearchTotal)
 	Update(transaction *entity.SPPayrollTransaction) error
 	Delete(id string) (*entity.SPPayrollTransaction, error)
 	DeleteMultiple(providerID string) []*entity.SPPayrollTransaction
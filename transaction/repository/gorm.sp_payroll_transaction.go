@@ -1,14 +1,27 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"strings"
+	"time"
 
 	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/ledger"
+	ledgerRepository "github.com/Benyam-S/onemembership/ledger/repository"
+	"github.com/Benyam-S/onemembership/money"
 	"github.com/Benyam-S/onemembership/tools"
 	"github.com/Benyam-S/onemembership/transaction"
 	"github.com/jinzhu/gorm"
 )
 
+// payrollStatusTransitions is a map that holds the statuses a payroll transaction can move to from each status
+var payrollStatusTransitions = map[string][]string{
+	entity.PayrollStatusPending:  {entity.PayrollStatusApproved, entity.PayrollStatusRejected},
+	entity.PayrollStatusApproved: {entity.PayrollStatusComplete, entity.PayrollStatusRejected},
+}
+
 // SPPayrollTransactionRepository is a type that defines a service provider payroll transaction repository type
 type SPPayrollTransactionRepository struct {
 	conn *gorm.DB
@@ -37,6 +50,152 @@ func (repo *SPPayrollTransactionRepository) Create(newPayrollTransaction *entity
 	return nil
 }
 
+// CreateWithdrawal is a method that adds a new pending payroll transaction for a withdrawal request.
+// It recalculates the provider's balance in the requested currency from the transactions, since the running amount
+// is only an estimate, moves the requested amount from the running amount to the pending amount of the currency
+// and posts the withdrawal to the ledger, all in a single database transaction.
+func (repo *SPPayrollTransactionRepository) CreateWithdrawal(newPayrollTransaction *entity.SPPayrollTransaction) error {
+
+	return repo.conn.Transaction(func(tx *gorm.DB) error {
+
+		spWallet := new(entity.SPWallet)
+		err := tx.Set("gorm:query_option", "FOR UPDATE").Model(spWallet).
+			Where("provider_id = ?", newPayrollTransaction.ProviderID).First(spWallet).Error
+		if err != nil {
+			return err
+		}
+
+		if spWallet.LinkedAccount == "" {
+			return errors.New("service provider wallet has no linked account")
+		}
+
+		balance, err := calculateBalance(tx, newPayrollTransaction.ProviderID, newPayrollTransaction.CurrencyType)
+		if err != nil {
+			return err
+		}
+
+		if newPayrollTransaction.PayedAmount > balance {
+			return errors.New("insufficient balance")
+		}
+
+		totalNumOfPayrollTransactions := tools.CountMembers("sp_payroll_transactions", tx)
+		newPayrollTransaction.ID = fmt.Sprintf("PRT-%s%d", tools.RandomStringGN(20), totalNumOfPayrollTransactions+1)
+
+		for !tools.IsUnique("id", newPayrollTransaction.ID, "sp_payroll_transactions", tx) {
+			totalNumOfPayrollTransactions++
+			newPayrollTransaction.ID = fmt.Sprintf("PRT-%s%d", tools.RandomStringGN(20), totalNumOfPayrollTransactions+1)
+		}
+
+		newPayrollTransaction.LinkedAccount = spWallet.LinkedAccount
+		newPayrollTransaction.LinkedAccountProvider = spWallet.LinkedAccountProvider
+		newPayrollTransaction.Status = entity.PayrollStatusPending
+		err = tx.Create(newPayrollTransaction).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Exec("UPDATE sp_wallet_balances SET running_amount = ?, pending_amount = pending_amount + ?, "+
+			"updated_at = ? WHERE provider_id = ? && currency_type = ?", balance.Sub(newPayrollTransaction.PayedAmount),
+			newPayrollTransaction.PayedAmount, time.Now(), newPayrollTransaction.ProviderID,
+			newPayrollTransaction.CurrencyType)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return errors.New("no service provider wallet balance found")
+		}
+
+		return ledgerRepository.NewJournalRepository(tx).Post(ledger.WithdrawalEntry(newPayrollTransaction))
+	})
+}
+
+// UpdateStatus is a method that moves a payroll transaction to the given status and adjusts the provider's wallet,
+// a rejected payroll gives the amount back to the running amount while a completed one removes it from the pending amount,
+// the change is posted to the ledger in the same database transaction
+func (repo *SPPayrollTransactionRepository) UpdateStatus(id, status string) error {
+
+	return repo.conn.Transaction(func(tx *gorm.DB) error {
+
+		payrollTransaction := new(entity.SPPayrollTransaction)
+		err := tx.Set("gorm:query_option", "FOR UPDATE").Model(payrollTransaction).Where("id = ?", id).
+			First(payrollTransaction).Error
+		if err != nil {
+			return err
+		}
+
+		allowed := false
+		for _, nextStatus := range payrollStatusTransitions[payrollTransaction.Status] {
+			if nextStatus == status {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			return errors.New("invalid payroll transaction status transition")
+		}
+
+		err = tx.Model(payrollTransaction).Updates(map[string]interface{}{"status": status, "updated_at": time.Now()}).Error
+		if err != nil {
+			return err
+		}
+
+		var result *gorm.DB
+		var entry *entity.JournalEntry
+		switch status {
+		case entity.PayrollStatusRejected:
+			entry = ledger.WithdrawalRejectionEntry(payrollTransaction)
+			result = tx.Exec("UPDATE sp_wallet_balances SET running_amount = running_amount + ?, "+
+				"pending_amount = pending_amount - ?, updated_at = ? WHERE provider_id = ? && currency_type = ?",
+				payrollTransaction.PayedAmount, payrollTransaction.PayedAmount, time.Now(), payrollTransaction.ProviderID,
+				payrollTransaction.CurrencyType)
+		case entity.PayrollStatusComplete:
+			entry = ledger.PayoutEntry(payrollTransaction)
+			result = tx.Exec("UPDATE sp_wallet_balances SET pending_amount = pending_amount - ?, updated_at = ? "+
+				"WHERE provider_id = ? && currency_type = ?", payrollTransaction.PayedAmount, time.Now(),
+				payrollTransaction.ProviderID, payrollTransaction.CurrencyType)
+		default:
+			return nil
+		}
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return errors.New("no service provider wallet balance found")
+		}
+
+		return ledgerRepository.NewJournalRepository(tx).Post(entry)
+	})
+}
+
+// calculateBalance is a function that calculates the amount a provider can withdraw in the given currency from the
+// completed subscription transactions, including their refunds, minus the payroll transactions that haven't been rejected
+func calculateBalance(tx *gorm.DB, providerID, currencyType string) (money.Amount, error) {
+
+	var earned struct{ Total money.Amount }
+	err := tx.Raw("SELECT COALESCE(SUM(A.received_amount), 0) AS total FROM subscription_transactions A "+
+		"INNER JOIN subscriptions B ON B.transaction_id IN (A.id, A.reversed_transaction_id) "+
+		"WHERE B.provider_id = ? && B.transaction_id != '' && A.currency_type = ? && A.status IN (?)", providerID,
+		currencyType, []string{entity.TransactionStatusComplete, entity.TransactionStatusRefundPending,
+			entity.TransactionStatusRefunded}).Scan(&earned).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var paid struct{ Total money.Amount }
+	err = tx.Raw("SELECT COALESCE(SUM(payed_amount), 0) AS total FROM sp_payroll_transactions "+
+		"WHERE provider_id = ? && currency_type = ? && status != ?", providerID, currencyType,
+		entity.PayrollStatusRejected).Scan(&paid).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return earned.Total.Sub(paid.Total), nil
+}
+
 // Find is a method that finds a certain service provider payroll transaction from the database using an transaction id,
 // also Find() uses only id as a key for selection
 func (repo *SPPayrollTransactionRepository) Find(id string) (*entity.SPPayrollTransaction, error) {
@@ -66,6 +225,30 @@ func (repo *SPPayrollTransactionRepository) FindMultiple(providerID string) []*e
 	return payrollTransactions
 }
 
+// Search is a method that searchs and returns set of service provider payroll transactions that match the filter
+// limited to the page number, along with the page count and the totals of all the matched transactions
+func (repo *SPPayrollTransactionRepository) Search(filter *transaction.SearchFilter,
+	pageNum int64) ([]*entity.SPPayrollTransaction, int64, []*transaction.SearchTotal) {
+
+	var payrollTransactions []*entity.SPPayrollTransaction
+	whereStmt, sqlValues := searchStatement(filter, "payed_amount")
+
+	if filter.ProviderID != "" {
+		whereStmt = append(whereStmt, " provider_id = ? ")
+		sqlValues = append(sqlValues, filter.ProviderID)
+	}
+
+	where := strings.Join(whereStmt, "&&")
+	totals, count := searchTotals(repo.conn, "sp_payroll_transactions", where, sqlValues, "payed_amount", "")
+
+	sqlValues = append(sqlValues, pageNum*20)
+	repo.conn.Raw("SELECT * FROM sp_payroll_transactions WHERE ("+where+") ORDER BY created_at DESC LIMIT ?, 20",
+		sqlValues...).Scan(&payrollTransactions)
+
+	var pageCount int64 = int64(math.Ceil(float64(count) / 20.0))
+	return payrollTransactions, pageCount, totals
+}
+
 // Update is a method that updates a certain service provider payroll transaction entries in the database
 func (repo *SPPayrollTransactionRepository) Update(payrollTransaction *entity.SPPayrollTransaction) error {
 
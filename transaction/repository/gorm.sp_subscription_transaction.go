@@ -2,8 +2,13 @@ package repository
 
 import (
 	"fmt"
+	"math"
+	"strings"
+	"time"
 
 	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/ledger"
+	ledgerRepository "github.com/Benyam-S/onemembership/ledger/repository"
 	"github.com/Benyam-S/onemembership/tools"
 	"github.com/Benyam-S/onemembership/transaction"
 	"github.com/jinzhu/gorm"
@@ -29,7 +34,12 @@ func (repo *SPSubscriptionTransactionRepository) Create(newSubscriptionTransacti
 		newSubscriptionTransaction.ID = fmt.Sprintf("SBT-%s%d", tools.RandomStringGN(20), totalNumOfSubscriptionTransactions+1)
 	}
 
+	// Concurrent requests repeating the same idempotency key are caught by the unique key
 	err := repo.conn.Create(newSubscriptionTransaction).Error
+	if tools.IsDuplicateKey(err) && newSubscriptionTransaction.IdempotencyKey != "" {
+		return transaction.ErrIdempotencyKeyUsed
+	}
+
 	if err != nil {
 		return err
 	}
@@ -64,6 +74,132 @@ func (repo *SPSubscriptionTransactionRepository) FindMultiple(identifier string)
 	return subscriptionTransactions
 }
 
+// FindStale is a method that finds pending service provider subscription transactions whose timeout has passed before the given moment,
+// the timeout is stored in minutes starting from the creation of the transaction
+func (repo *SPSubscriptionTransactionRepository) FindStale(moment time.Time) []*entity.SPSubscriptionTransaction {
+
+	var subscriptionTransactions []*entity.SPSubscriptionTransaction
+	err := repo.conn.Model(entity.SPSubscriptionTransaction{}).Where("status = ? && "+
+		"DATE_ADD(created_at, INTERVAL timeout_express MINUTE) <= ?", entity.TransactionStatusPending, moment).
+		Order("created_at ASC").Find(&subscriptionTransactions).Error
+
+	if err != nil {
+		return []*entity.SPSubscriptionTransaction{}
+	}
+	return subscriptionTransactions
+}
+
+// Search is a method that searchs and returns set of service provider subscription transactions that match the filter
+// limited to the page number, along with the page count and the totals of all the matched transactions
+func (repo *SPSubscriptionTransactionRepository) Search(filter *transaction.SearchFilter,
+	pageNum int64) ([]*entity.SPSubscriptionTransaction, int64, []*transaction.SearchTotal) {
+
+	var subscriptionTransactions []*entity.SPSubscriptionTransaction
+	whereStmt, sqlValues := searchStatement(filter, "received_amount")
+
+	if filter.AppID != "" {
+		whereStmt = append(whereStmt, " app_id = ? ")
+		sqlValues = append(sqlValues, filter.AppID)
+	}
+
+	if filter.PlanID != "" {
+		whereStmt = append(whereStmt, " plan_id = ? ")
+		sqlValues = append(sqlValues, filter.PlanID)
+	}
+
+	if filter.ProviderID != "" {
+		whereStmt = append(whereStmt, " provider_id = ? ")
+		sqlValues = append(sqlValues, filter.ProviderID)
+	}
+
+	where := strings.Join(whereStmt, "&&")
+	totals, count := searchTotals(repo.conn, "sp_subscription_transactions", where, sqlValues,
+		"received_amount", "transaction_fee")
+
+	sqlValues = append(sqlValues, pageNum*20)
+	repo.conn.Raw("SELECT * FROM sp_subscription_transactions WHERE ("+where+") ORDER BY created_at DESC LIMIT ?, 20",
+		sqlValues...).Scan(&subscriptionTransactions)
+
+	var pageCount int64 = int64(math.Ceil(float64(count) / 20.0))
+	return subscriptionTransactions, pageCount, totals
+}
+
+// FindByIdempotencyKey is a method that finds the latest service provider subscription transaction of a provider created with the given idempotency key,
+// only transactions that haven't timed out at the given moment are selected since their pay url is no longer valid
+func (repo *SPSubscriptionTransactionRepository) FindByIdempotencyKey(providerID, idempotencyKey string,
+	moment time.Time) (*entity.SPSubscriptionTransaction, error) {
+
+	subscriptionTransaction := new(entity.SPSubscriptionTransaction)
+	err := repo.conn.Model(subscriptionTransaction).Where("provider_id = ? && idempotency_key = ? && "+
+		"DATE_ADD(created_at, INTERVAL timeout_express MINUTE) > ?", providerID, idempotencyKey, moment).
+		Order("created_at DESC").First(subscriptionTransaction).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return subscriptionTransaction, nil
+}
+
+// Settle is a method that moves a pending service provider subscription transaction to the status of its settled payment
+// together with the payment's trade number. The transaction is only changed while it is still pending, so concurrent
+// deliveries of the same payment can't both settle it, except for an expired transaction that can still be completed
+// since its payment may have been made right before the timeout. A completed payment is posted to the ledger
+// in the same database transaction.
+func (repo *SPSubscriptionTransactionRepository) Settle(id, tradeNo, status string) error {
+
+	settleableStatuses := []string{entity.TransactionStatusPending}
+	if status == entity.TransactionStatusComplete {
+		settleableStatuses = append(settleableStatuses, entity.TransactionStatusExpired)
+	}
+
+	return repo.conn.Transaction(func(tx *gorm.DB) error {
+
+		result := tx.Exec("UPDATE sp_subscription_transactions SET trade_no = ?, status = ?, updated_at = ? "+
+			"WHERE id = ? && status IN (?)", entity.NullableString(tradeNo), status, time.Now(), id, settleableStatuses)
+		if tools.IsDuplicateKey(result.Error) {
+			return transaction.ErrPaymentMismatch
+		}
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return transaction.ErrPaymentProcessed
+		}
+
+		if status != entity.TransactionStatusComplete {
+			return nil
+		}
+
+		completedTransaction := new(entity.SPSubscriptionTransaction)
+		err := tx.Model(completedTransaction).Where("id = ?", id).First(completedTransaction).Error
+		if err != nil {
+			return err
+		}
+
+		return ledgerRepository.NewJournalRepository(tx).Post(ledger.SPSubscriptionPaymentEntry(completedTransaction))
+	})
+}
+
+// Expire is a method that expires a pending service provider subscription transaction whose payment hasn't been made
+// before its timeout, the transaction is only changed while it is still pending so a payment settled in the meantime
+// isn't overwritten
+func (repo *SPSubscriptionTransactionRepository) Expire(id string) error {
+
+	result := repo.conn.Exec("UPDATE sp_subscription_transactions SET status = ?, updated_at = ? "+
+		"WHERE id = ? && status = ?", entity.TransactionStatusExpired, time.Now(), id, entity.TransactionStatusPending)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return transaction.ErrPaymentProcessed
+	}
+
+	return nil
+}
+
 // Update is a method that updates a certain service provider subscription transaction entries in the database
 func (repo *SPSubscriptionTransactionRepository) Update(subscriptionTransaction *entity.SPSubscriptionTransaction) error {
 
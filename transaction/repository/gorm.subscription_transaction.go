@@ -1,9 +1,16 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"strings"
+	"time"
 
 	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/ledger"
+	ledgerRepository "github.com/Benyam-S/onemembership/ledger/repository"
+	"github.com/Benyam-S/onemembership/money"
 	"github.com/Benyam-S/onemembership/tools"
 	"github.com/Benyam-S/onemembership/transaction"
 	"github.com/jinzhu/gorm"
@@ -29,13 +36,210 @@ func (repo *SubscriptionTransactionRepository) Create(newSubscriptionTransaction
 		newSubscriptionTransaction.ID = fmt.Sprintf("SBT-%s%d", tools.RandomStringGN(7), totalNumOfSubscriptionTransactions+1)
 	}
 
+	// Concurrent requests repeating the same idempotency key are caught by the unique key
 	err := repo.conn.Create(newSubscriptionTransaction).Error
+	if tools.IsDuplicateKey(err) && newSubscriptionTransaction.IdempotencyKey != "" {
+		return transaction.ErrIdempotencyKeyUsed
+	}
+
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// CreateRefund is a method that adds a new pending refund for a completed subscription transaction, debits
+// the refunded amount from the provider's wallet and posts the refund to the ledger, all in a single database transaction
+func (repo *SubscriptionTransactionRepository) CreateRefund(newRefund *entity.SubscriptionTransaction) error {
+
+	return repo.conn.Transaction(func(tx *gorm.DB) error {
+
+		reversedTransaction := new(entity.SubscriptionTransaction)
+		err := tx.Set("gorm:query_option", "FOR UPDATE").Model(reversedTransaction).
+			Where("id = ?", newRefund.ReversedTransactionID).First(reversedTransaction).Error
+		if err != nil {
+			return err
+		}
+
+		if reversedTransaction.Status != entity.TransactionStatusComplete {
+			return errors.New("only completed subscription transactions can be refunded")
+		}
+
+		// Refunds hold a negative received amount, so the refunded total is the negated sum
+		var refunded struct{ Total money.Amount }
+		err = tx.Raw("SELECT COALESCE(SUM(-received_amount), 0) AS total FROM subscription_transactions "+
+			"WHERE reversed_transaction_id = ? && status != ?", reversedTransaction.ID,
+			entity.TransactionStatusRefundFailed).Scan(&refunded).Error
+		if err != nil {
+			return err
+		}
+
+		refundedTotal, err := money.New(refunded.Total, reversedTransaction.CurrencyType).
+			Sub(money.New(newRefund.ReceivedAmount, newRefund.CurrencyType))
+		if err != nil {
+			return err
+		}
+
+		if refundedTotal.Amount > reversedTransaction.ReceivedAmount {
+			return errors.New("refund exceeds the amount received for the subscription transaction")
+		}
+
+		totalNumOfSubscriptionTransactions := tools.CountMembers("subscription_transactions", tx)
+		newRefund.ID = fmt.Sprintf("SBT-%s%d", tools.RandomStringGN(7), totalNumOfSubscriptionTransactions+1)
+
+		for !tools.IsUnique("id", newRefund.ID, "subscription_transactions", tx) {
+			totalNumOfSubscriptionTransactions++
+			newRefund.ID = fmt.Sprintf("SBT-%s%d", tools.RandomStringGN(7), totalNumOfSubscriptionTransactions+1)
+		}
+
+		newRefund.Status = entity.TransactionStatusRefundPending
+		err = tx.Create(newRefund).Error
+		if err != nil {
+			return err
+		}
+
+		providerID, err := adjustRefundedWallet(tx, reversedTransaction.ID, reversedTransaction.PlanID,
+			newRefund.CurrencyType, newRefund.ReceivedAmount)
+		if err != nil {
+			return err
+		}
+
+		return ledgerRepository.NewJournalRepository(tx).Post(ledger.RefundEntry(newRefund, providerID))
+	})
+}
+
+// UpdateRefundStatus is a method that moves a pending refund to the given status,
+// a failed refund gives the refunded amount back to the provider's wallet and reverses the refund in the ledger
+func (repo *SubscriptionTransactionRepository) UpdateRefundStatus(id, status string) error {
+
+	if status != entity.TransactionStatusRefunded && status != entity.TransactionStatusRefundFailed {
+		return errors.New("invalid refund status")
+	}
+
+	return repo.conn.Transaction(func(tx *gorm.DB) error {
+
+		refund := new(entity.SubscriptionTransaction)
+		err := tx.Set("gorm:query_option", "FOR UPDATE").Model(refund).Where("id = ?", id).First(refund).Error
+		if err != nil {
+			return err
+		}
+
+		if refund.Status != entity.TransactionStatusRefundPending {
+			return errors.New("refund is not pending")
+		}
+
+		err = tx.Model(refund).Updates(map[string]interface{}{"status": status, "updated_at": time.Now()}).Error
+		if err != nil {
+			return err
+		}
+
+		if status != entity.TransactionStatusRefundFailed {
+			return nil
+		}
+
+		providerID, err := adjustRefundedWallet(tx, refund.ReversedTransactionID, refund.PlanID, refund.CurrencyType,
+			refund.ReceivedAmount.Neg())
+		if err != nil {
+			return err
+		}
+
+		return ledgerRepository.NewJournalRepository(tx).Post(ledger.RefundFailureEntry(refund, providerID))
+	})
+}
+
+// adjustRefundedWallet is a function that adds the given amount to the running amount the wallet holds in the given currency,
+// where the wallet belongs to the provider the refunded subscription transaction has been paid to,
+// and returns the provider's id
+func adjustRefundedWallet(tx *gorm.DB, reversedTransactionID, planID, currencyType string,
+	amount money.Amount) (string, error) {
+
+	providerID, err := paidProviderID(tx, reversedTransactionID, planID)
+	if err != nil {
+		return "", err
+	}
+
+	result := tx.Exec("UPDATE sp_wallet_balances SET running_amount = running_amount + ?, updated_at = ? "+
+		"WHERE provider_id = ? && currency_type = ?", amount, time.Now(), providerID, currencyType)
+	if result.Error != nil {
+		return "", result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return "", errors.New("no service provider wallet balance found")
+	}
+
+	return providerID, nil
+}
+
+// paidProviderID is a function that returns the id of the service provider a subscription transaction has been paid to,
+// found through the project of the transaction's plan or through the subscription the transaction has activated
+// when the plan has been removed
+func paidProviderID(tx *gorm.DB, transactionID, planID string) (string, error) {
+
+	var providerData struct{ ProviderID string }
+	err := tx.Raw("SELECT P.provider_id FROM subscription_plans S INNER JOIN projects P ON P.id = S.project_id "+
+		"WHERE S.id = ?", planID).Scan(&providerData).Error
+	if gorm.IsRecordNotFoundError(err) {
+		err = tx.Raw("SELECT provider_id FROM subscriptions WHERE transaction_id = ?", transactionID).
+			Scan(&providerData).Error
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return providerData.ProviderID, nil
+}
+
+// postSubscriptionPayment is a function that credits the provider's wallet with the received amount of a completed
+// subscription transaction and posts the payment to the ledger, so the payment is accounted for as soon as it has
+// been completed even if its subscription is never activated
+func postSubscriptionPayment(tx *gorm.DB, transactionID string) error {
+
+	completedTransaction := new(entity.SubscriptionTransaction)
+	err := tx.Model(completedTransaction).Where("id = ?", transactionID).First(completedTransaction).Error
+	if err != nil {
+		return err
+	}
+
+	providerID, err := paidProviderID(tx, completedTransaction.ID, completedTransaction.PlanID)
+	if err != nil {
+		return err
+	}
+
+	err = creditWallet(tx, providerID, completedTransaction.CurrencyType, completedTransaction.ReceivedAmount)
+	if err != nil {
+		return err
+	}
+
+	return ledgerRepository.NewJournalRepository(tx).Post(
+		ledger.SubscriptionPaymentEntry(completedTransaction, providerID))
+}
+
+// creditWallet is a function that adds the given amount to the running amount a service provider's wallet holds
+// in the given currency, the currency balance is created on the first payment made in that currency
+func creditWallet(tx *gorm.DB, providerID, currencyType string, amount money.Amount) error {
+
+	var count int64
+	err := tx.Model(entity.SPWallet{}).Where("provider_id = ?", providerID).Count(&count).Error
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return errors.New("no service provider wallet found")
+	}
+
+	if amount == 0 {
+		return nil
+	}
+
+	return tx.Exec("INSERT INTO sp_wallet_balances (provider_id, currency_type, running_amount, pending_amount, "+
+		"created_at, updated_at) VALUES (?, ?, ?, 0, ?, ?) ON DUPLICATE KEY UPDATE "+
+		"running_amount = running_amount + VALUES(running_amount), updated_at = VALUES(updated_at)",
+		providerID, currencyType, amount, time.Now(), time.Now()).Error
+}
+
 // Find is a method that finds a certain subscription transaction from the database using an identifier,
 // also Find() uses id and out_trade_no as a key for selection
 func (repo *SubscriptionTransactionRepository) Find(identifier string) (*entity.SubscriptionTransaction, error) {
@@ -64,6 +268,222 @@ func (repo *SubscriptionTransactionRepository) FindMultiple(identifier string) [
 	return subscriptionTransactions
 }
 
+// FindStale is a method that finds pending subscription transactions whose timeout has passed before the given moment,
+// the timeout is stored in minutes starting from the creation of the transaction
+func (repo *SubscriptionTransactionRepository) FindStale(moment time.Time) []*entity.SubscriptionTransaction {
+
+	var subscriptionTransactions []*entity.SubscriptionTransaction
+	err := repo.conn.Model(entity.SubscriptionTransaction{}).Where("status = ? && "+
+		"DATE_ADD(created_at, INTERVAL timeout_express MINUTE) <= ?", entity.TransactionStatusPending, moment).
+		Order("created_at ASC").Find(&subscriptionTransactions).Error
+
+	if err != nil {
+		return []*entity.SubscriptionTransaction{}
+	}
+	return subscriptionTransactions
+}
+
+// FindUnactivated is a method that finds completed subscription transactions updated within the given period
+// that haven't activated a subscription, transactions whose user or subscription plan has been deleted are left out
+func (repo *SubscriptionTransactionRepository) FindUnactivated(from, to time.Time) []*entity.SubscriptionTransaction {
+
+	var subscriptionTransactions []*entity.SubscriptionTransaction
+	err := repo.conn.Model(entity.SubscriptionTransaction{}).Where("status = ? && updated_at >= ? && updated_at <= ? && "+
+		"NOT EXISTS (SELECT 1 FROM subscriptions S WHERE S.transaction_id = subscription_transactions.id) && "+
+		"user_id IN (SELECT id FROM users) && plan_id IN (SELECT id FROM subscription_plans)",
+		entity.TransactionStatusComplete, from, to).Order("updated_at ASC").Find(&subscriptionTransactions).Error
+
+	if err != nil {
+		return []*entity.SubscriptionTransaction{}
+	}
+	return subscriptionTransactions
+}
+
+// Search is a method that searchs and returns set of subscription transactions that match the filter limited to the page number,
+// along with the page count and the totals of all the matched transactions
+func (repo *SubscriptionTransactionRepository) Search(filter *transaction.SearchFilter,
+	pageNum int64) ([]*entity.SubscriptionTransaction, int64, []*transaction.SearchTotal) {
+
+	var subscriptionTransactions []*entity.SubscriptionTransaction
+	whereStmt, sqlValues := searchStatement(filter, "received_amount")
+
+	if filter.AppID != "" {
+		whereStmt = append(whereStmt, " app_id = ? ")
+		sqlValues = append(sqlValues, filter.AppID)
+	}
+
+	if filter.UserID != "" {
+		whereStmt = append(whereStmt, " user_id = ? ")
+		sqlValues = append(sqlValues, filter.UserID)
+	}
+
+	if filter.PlanID != "" {
+		whereStmt = append(whereStmt, " plan_id = ? ")
+		sqlValues = append(sqlValues, filter.PlanID)
+	}
+
+	if filter.ProviderID != "" {
+		whereStmt = append(whereStmt, " plan_id IN (SELECT P.id FROM subscription_plans P "+
+			"INNER JOIN projects J ON J.id = P.project_id WHERE J.provider_id = ?) ")
+		sqlValues = append(sqlValues, filter.ProviderID)
+	}
+
+	if filter.InitiatedFrom != "" {
+		whereStmt = append(whereStmt, " initiated_from = ? ")
+		sqlValues = append(sqlValues, filter.InitiatedFrom)
+	}
+
+	where := strings.Join(whereStmt, "&&")
+	totals, count := searchTotals(repo.conn, "subscription_transactions", where, sqlValues,
+		"received_amount", "transaction_fee")
+
+	sqlValues = append(sqlValues, pageNum*20)
+	repo.conn.Raw("SELECT * FROM subscription_transactions WHERE ("+where+") ORDER BY created_at DESC LIMIT ?, 20",
+		sqlValues...).Scan(&subscriptionTransactions)
+
+	var pageCount int64 = int64(math.Ceil(float64(count) / 20.0))
+	return subscriptionTransactions, pageCount, totals
+}
+
+// searchStatement is a function that builds the where statement of the filter criteria shared by all the transaction tables,
+// amountColumn is the column the amount range is checked against
+func searchStatement(filter *transaction.SearchFilter, amountColumn string) ([]string, []interface{}) {
+
+	// Starting with an always true statement so an empty filter matches every transaction
+	whereStmt := []string{" 1 = 1 "}
+	var sqlValues []interface{}
+
+	if !filter.From.IsZero() {
+		whereStmt = append(whereStmt, " created_at >= ? ")
+		sqlValues = append(sqlValues, filter.From)
+	}
+
+	if !filter.To.IsZero() {
+		whereStmt = append(whereStmt, " created_at < ? ")
+		sqlValues = append(sqlValues, filter.To)
+	}
+
+	if filter.Status != "" {
+		whereStmt = append(whereStmt, " status = ? ")
+		sqlValues = append(sqlValues, filter.Status)
+	}
+
+	if filter.CurrencyType != "" {
+		whereStmt = append(whereStmt, " currency_type = ? ")
+		sqlValues = append(sqlValues, filter.CurrencyType)
+	}
+
+	if filter.MinAmount != nil {
+		whereStmt = append(whereStmt, fmt.Sprintf(" %s >= ? ", amountColumn))
+		sqlValues = append(sqlValues, *filter.MinAmount)
+	}
+
+	if filter.MaxAmount != nil {
+		whereStmt = append(whereStmt, fmt.Sprintf(" %s <= ? ", amountColumn))
+		sqlValues = append(sqlValues, *filter.MaxAmount)
+	}
+
+	return whereStmt, sqlValues
+}
+
+// searchTotals is a function that sums the transactions of a table that match the where statement per currency,
+// it also returns the total number of matched transactions. feeColumn can be left empty for tables without a fee
+func searchTotals(conn *gorm.DB, table, where string, sqlValues []interface{},
+	amountColumn, feeColumn string) ([]*transaction.SearchTotal, int64) {
+
+	var totals []*transaction.SearchTotal
+	var count int64
+
+	feeSum := "0"
+	if feeColumn != "" {
+		feeSum = fmt.Sprintf("COALESCE(SUM(%s), 0)", feeColumn)
+	}
+
+	conn.Raw(fmt.Sprintf("SELECT currency_type, COUNT(*) AS count, COALESCE(SUM(%s), 0) AS amount, "+
+		"%s AS transaction_fee FROM %s WHERE (%s) GROUP BY currency_type ORDER BY currency_type ASC",
+		amountColumn, feeSum, table, where), sqlValues...).Scan(&totals)
+
+	for _, total := range totals {
+		count += total.Count
+	}
+
+	if totals == nil {
+		totals = []*transaction.SearchTotal{}
+	}
+
+	return totals, count
+}
+
+// FindByIdempotencyKey is a method that finds the latest subscription transaction of a user created with the given idempotency key,
+// only transactions that haven't timed out or been expired at the given moment are selected since their pay url is no longer valid
+func (repo *SubscriptionTransactionRepository) FindByIdempotencyKey(userID, idempotencyKey string,
+	moment time.Time) (*entity.SubscriptionTransaction, error) {
+
+	subscriptionTransaction := new(entity.SubscriptionTransaction)
+	err := repo.conn.Model(subscriptionTransaction).Where("user_id = ? && idempotency_key = ? && status != ? && "+
+		"DATE_ADD(created_at, INTERVAL timeout_express MINUTE) > ?", userID, idempotencyKey,
+		entity.TransactionStatusExpired, moment).
+		Order("created_at DESC").First(subscriptionTransaction).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return subscriptionTransaction, nil
+}
+
+// Settle is a method that moves a pending subscription transaction to the status of its settled payment together with
+// the payment's trade number. The transaction is only changed while it is still pending, so concurrent deliveries
+// of the same payment can't both settle it, except for an expired transaction that can still be completed
+// since its payment may have been made right before the timeout. A completed payment is credited to the provider's
+// wallet and posted to the ledger in the same database transaction.
+func (repo *SubscriptionTransactionRepository) Settle(id, tradeNo, status string) error {
+
+	settleableStatuses := []string{entity.TransactionStatusPending}
+	if status == entity.TransactionStatusComplete {
+		settleableStatuses = append(settleableStatuses, entity.TransactionStatusExpired)
+	}
+
+	return repo.conn.Transaction(func(tx *gorm.DB) error {
+
+		result := tx.Exec("UPDATE subscription_transactions SET trade_no = ?, status = ?, updated_at = ? "+
+			"WHERE id = ? && status IN (?)", entity.NullableString(tradeNo), status, time.Now(), id, settleableStatuses)
+		if tools.IsDuplicateKey(result.Error) {
+			return transaction.ErrPaymentMismatch
+		}
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return transaction.ErrPaymentProcessed
+		}
+
+		if status != entity.TransactionStatusComplete {
+			return nil
+		}
+
+		return postSubscriptionPayment(tx, id)
+	})
+}
+
+// Expire is a method that expires a pending subscription transaction whose payment hasn't been made before its timeout,
+// the transaction is only changed while it is still pending so a payment settled in the meantime isn't overwritten
+func (repo *SubscriptionTransactionRepository) Expire(id string) error {
+
+	result := repo.conn.Exec("UPDATE subscription_transactions SET status = ?, updated_at = ? WHERE id = ? && status = ?",
+		entity.TransactionStatusExpired, time.Now(), id, entity.TransactionStatusPending)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return transaction.ErrPaymentProcessed
+	}
+
+	return nil
+}
+
 // Update is a method that updates a certain subscription transaction entries in the database
 func (repo *SubscriptionTransactionRepository) Update(subscriptionTransaction *entity.SubscriptionTransaction) error {
 
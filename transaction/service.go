@@ -1,21 +1,85 @@
 package transaction
 
-import "github.com/Benyam-S/onemembership/entity"
+import (
+	"strings"
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/money"
+)
 
 // TelebirrAPIAccount is a struct that defines all the need entries for telebirr api
 type TelebirrAPIAccount struct {
-	AccessPoint    string  `json:"api_access_point"`
-	AppID          string  `json:"app_id"`
-	AppKey         string  `json:"app_key"`
-	NotifyURL      string  `json:"notify_url"`
-	ReturnURL      string  `json:"return_url"`
-	ShortCode      string  `json:"short_code"`
-	TransactionFee float64 `json:"transaction_fee"`
-	PublicKey      []byte  `json:"-"`
+	AccessPoint    string       `json:"api_access_point"`
+	AppID          string       `json:"app_id"`
+	AppKey         string       `json:"app_key" redact:"secret"`
+	NotifyURL      string       `json:"notify_url"`
+	ReturnURL      string       `json:"return_url"`
+	ShortCode      string       `json:"short_code"`
+	TransactionFee money.Amount `json:"transaction_fee"`
+	PublicKey      []byte       `json:"-"`
+}
+
+// SearchFilter is a type that defines the criteria transactions are searched with, empty criteria are ignored.
+// UserID and InitiatedFrom only apply to subscription transactions while AppID and PlanID don't apply to payroll transactions
+type SearchFilter struct {
+	From          time.Time // Inclusive lower bound of the creation time
+	To            time.Time // Exclusive upper bound of the creation time
+	Status        string
+	AppID         string
+	UserID        string
+	PlanID        string
+	ProviderID    string // Subscription transactions are matched through the project of the plan
+	CurrencyType  string
+	MinAmount     *money.Amount
+	MaxAmount     *money.Amount
+	InitiatedFrom string
+}
+
+// String is a method that returns the criteria of the filter that have been set in a readable format
+func (filter *SearchFilter) String() string {
+
+	var criteria []string
+	if !filter.From.IsZero() {
+		criteria = append(criteria, "From : "+filter.From.Format(time.RFC3339))
+	}
+	if !filter.To.IsZero() {
+		criteria = append(criteria, "To : "+filter.To.Format(time.RFC3339))
+	}
+	if filter.MinAmount != nil {
+		criteria = append(criteria, "Min Amount : "+filter.MinAmount.String())
+	}
+	if filter.MaxAmount != nil {
+		criteria = append(criteria, "Max Amount : "+filter.MaxAmount.String())
+	}
+
+	labels := []string{"Status", "App ID", "User ID", "Plan ID", "Provider ID", "Currency Type", "Initiated From"}
+	values := []string{filter.Status, filter.AppID, filter.UserID, filter.PlanID, filter.ProviderID,
+		filter.CurrencyType, filter.InitiatedFrom}
+	for index, value := range values {
+		if value != "" {
+			criteria = append(criteria, labels[index]+" : "+value)
+		}
+	}
+
+	if len(criteria) == 0 {
+		return "Filter : None"
+	}
+	return strings.Join(criteria, ", ")
+}
+
+// SearchTotal is a type that defines the totals of the transactions that matched a search in a single currency
+type SearchTotal struct {
+	CurrencyType   string
+	Count          int64
+	Amount         money.Amount
+	TransactionFee money.Amount
 }
 
 // IService is an interface that defines all the service methods of a project struct
 type IService interface {
+	WithActor(actor string) IService
+
 	AddPaymentGateway(newPaymentGateway *entity.PaymentGateway) error
 	ValidatePaymentGateway(paymentGateway *entity.PaymentGateway) entity.ErrMap
 	FindPaymentGateway(id int64) (*entity.PaymentGateway, error)
@@ -24,10 +88,20 @@ type IService interface {
 	DeletePaymentGateway(id int64) (*entity.PaymentGateway, error)
 
 	AddSubscriptionTransaction(newSubscriptionTransaction *entity.SubscriptionTransaction) error
+	ConstructCreditSubscriptionTransaction(userID, planID, receiverName, subject, currencyType,
+		initiatedFrom string) *entity.SubscriptionTransaction
 	ValidateSubscriptionTransaction(subscriptionTransaction *entity.SubscriptionTransaction) entity.ErrMap
 	FindSubscriptionTransaction(id string) (*entity.SubscriptionTransaction, error)
 	FindMultipleSubscriptionTransactions(identifier string) []*entity.SubscriptionTransaction
+	FindStaleSubscriptionTransactions(moment time.Time) []*entity.SubscriptionTransaction
+	FindUnactivatedSubscriptionTransactions(from, to time.Time) []*entity.SubscriptionTransaction
+	SearchSubscriptionTransactions(filter *SearchFilter, pageNum int64) ([]*entity.SubscriptionTransaction, int64, []*SearchTotal)
 	UpdateSubscriptionTransaction(subscriptionTransaction *entity.SubscriptionTransaction) error
+	ReconcileSubscriptionTransaction(id string, moment time.Time) (*entity.SubscriptionTransaction, error)
+	RefundSubscriptionTransaction(id string, amount money.Amount) (*entity.SubscriptionTransaction, error)
+	CompleteRefund(id string) error
+	FailRefund(id string) error
+	ProcessRefund(id string) error
 	DeleteSubscriptionTransaction(id string) (*entity.SubscriptionTransaction, error)
 	DeleteMultipleSubscriptionTransactions(identifier string) []*entity.SubscriptionTransaction
 
@@ -35,17 +109,33 @@ type IService interface {
 	ValidateSPSubscriptionTransaction(subscriptionTransaction *entity.SPSubscriptionTransaction) entity.ErrMap
 	FindSPSubscriptionTransaction(id string) (*entity.SPSubscriptionTransaction, error)
 	FindMultipleSPSubscriptionTransactions(identifier string) []*entity.SPSubscriptionTransaction
+	FindStaleSPSubscriptionTransactions(moment time.Time) []*entity.SPSubscriptionTransaction
+	SearchSPSubscriptionTransactions(filter *SearchFilter, pageNum int64) ([]*entity.SPSubscriptionTransaction, int64, []*SearchTotal)
 	UpdateSPSubscriptionTransaction(subscriptionTransaction *entity.SPSubscriptionTransaction) error
+	ReconcileSPSubscriptionTransaction(id string, moment time.Time) (*entity.SPSubscriptionTransaction, error)
 	DeleteSPSubscriptionTransaction(id string) (*entity.SPSubscriptionTransaction, error)
 	DeleteMultipleSPSubscriptionTransactions(identifier string) []*entity.SPSubscriptionTransaction
 
 	AddSPPayrollTransaction(newPayrollTransaction *entity.SPPayrollTransaction) error
+	RequestWithdrawal(providerID, currencyType string, amount money.Amount) (*entity.SPPayrollTransaction, error)
+	ApproveSPPayrollTransaction(id string) error
+	RejectSPPayrollTransaction(id string) error
+	CompleteSPPayrollTransaction(id string) error
 	FindSPPayrollTransaction(id string) (*entity.SPPayrollTransaction, error)
 	FindMultipleSPPayrollTransactions(providerID string) []*entity.SPPayrollTransaction
+	SearchSPPayrollTransactions(filter *SearchFilter, pageNum int64) ([]*entity.SPPayrollTransaction, int64, []*SearchTotal)
 	UpdateSPPayrollTransaction(payrollTransaction *entity.SPPayrollTransaction) error
 	DeleteSPPayrollTransaction(id string) (*entity.SPPayrollTransaction, error)
 	DeleteMultipleSPPayrollTransactions(providerID string) []*entity.SPPayrollTransaction
 
+	InitiateSubscriptionTransaction(gatewayID int64, idempotencyKey, userID, planID, receiverName, subject, currencyType,
+		initiatedFrom, couponIdentifier, renewedSubscriptionID string,
+		amount money.Amount) (*entity.SubscriptionTransaction, string, error)
+	HandlePaymentNotification(gatewayID int64, payload []byte) (*entity.SubscriptionTransaction, error)
 	GetTelebirrH5WebURL(userID, planID, receiverName, subject, currencyType, initiatedFrom string,
 		receivedAmount float64) (string, error)
+	HandleTelebirrNotification(payload []byte) (*entity.SubscriptionTransaction, error)
+	InitiateSPSubscriptionTransaction(gatewayID int64, idempotencyKey, providerID, planID, receiverName, subject,
+		currencyType string, amount money.Amount) (*entity.SPSubscriptionTransaction, string, error)
+	HandleSPPaymentNotification(gatewayID int64, payload []byte) (*entity.SPSubscriptionTransaction, error)
 }
@@ -1,26 +1,22 @@
 package service
 
 import (
-	"bytes"
-	"crypto/sha256"
-	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"net/url"
 	"regexp"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/Benyam-S/onemembership/audit"
 	"github.com/Benyam-S/onemembership/common"
+	"github.com/Benyam-S/onemembership/coupon"
+	"github.com/Benyam-S/onemembership/currency"
 	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/fee"
 	"github.com/Benyam-S/onemembership/log"
-	"github.com/Benyam-S/onemembership/tools"
+	"github.com/Benyam-S/onemembership/money"
 	"github.com/Benyam-S/onemembership/transaction"
-	"github.com/google/go-querystring/query"
 	"github.com/google/uuid"
 )
 
@@ -30,7 +26,13 @@ type Service struct {
 	subTransactionRepo            transaction.ISubscriptionTransactionRepository
 	spSubscriptionTransactionRepo transaction.ISPSubscriptionTransactionRepository
 	spPayrollTransactionRepo      transaction.ISPPayrollTransactionRepository
-	TelebirrAPI                   *transaction.TelebirrAPIAccount
+	gateways                      *transaction.GatewayRegistry
+	feeService                    fee.IService
+	currencyService               currency.IService
+	couponService                 coupon.IService
+	auditService                  audit.IService
+	actor                         string // The actor the changes are recorded on behalf of in the audit trail
+	idempotencyLocks              *keyedMutex
 	cmService                     common.IService
 	logger                        *log.Logger
 }
@@ -40,12 +42,34 @@ func NewTransactionService(paymentGatewayRepository transaction.IPaymentGatewayR
 	subscriptionTransactionRepository transaction.ISubscriptionTransactionRepository,
 	spSubscriptionTransactionRepository transaction.ISPSubscriptionTransactionRepository,
 	spPayrollTransactionRepository transaction.ISPPayrollTransactionRepository,
-	telebirrAPIAccount *transaction.TelebirrAPIAccount, commonService common.IService,
+	gatewayRegistry *transaction.GatewayRegistry, feeService fee.IService, currencyService currency.IService,
+	couponService coupon.IService, commonService common.IService, auditService audit.IService,
 	projectLogger *log.Logger) transaction.IService {
 	return &Service{paymentGatewayRepo: paymentGatewayRepository, subTransactionRepo: subscriptionTransactionRepository,
 		spSubscriptionTransactionRepo: spSubscriptionTransactionRepository,
-		spPayrollTransactionRepo:      spPayrollTransactionRepository, TelebirrAPI: telebirrAPIAccount,
-		cmService: commonService, logger: projectLogger}
+		spPayrollTransactionRepo:      spPayrollTransactionRepository, gateways: gatewayRegistry,
+		feeService: feeService, currencyService: currencyService, couponService: couponService,
+		idempotencyLocks: newKeyedMutex(), cmService: commonService, auditService: auditService,
+		actor: audit.SystemActor, logger: projectLogger}
+}
+
+// WithActor is a method that returns a copy of the transaction service that records its changes in the audit trail
+// on behalf of the given actor, such as the admin that has approved a payroll
+func (service *Service) WithActor(actor string) transaction.IService {
+	serviceCopy := *service
+	serviceCopy.actor = actor
+	return &serviceCopy
+}
+
+// recordAudit is a method that appends an action performed on an entity to the audit trail on behalf of the actor,
+// the action has already taken place so a failure is only logged
+func (service *Service) recordAudit(action, entityID string, before, after interface{}) {
+	err := service.auditService.Record(service.actor, action, entityID, before, after)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For recording audit { Actor : %s, Action : %s, "+
+			"Entity ID : %s }, %s", service.actor, action, entityID, err.Error()))
+	}
 }
 
 // AddPaymentGateway is a method that adds a new payment gateway to the system
@@ -133,7 +157,16 @@ func (service *Service) UpdatePaymentGateway(paymentGateway *entity.PaymentGatew
 	service.logger.Log(fmt.Sprintf("Started payment gateway updating process, Payment Gateway => %s",
 		paymentGateway.ToString()), service.logger.Logs.TransactionLogFile)
 
-	err := service.paymentGatewayRepo.Update(paymentGateway)
+	prevPaymentGateway, err := service.paymentGatewayRepo.Find(paymentGateway.ID)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding payment gateway before updating "+
+			"{ Payment Gateway ID : %d }, %s", paymentGateway.ID, err.Error()))
+
+		return errors.New("unable to update payment gateway")
+	}
+
+	err = service.paymentGatewayRepo.Update(paymentGateway)
 	if err != nil {
 		/* ---------------------------- Logging ---------------------------- */
 		service.logger.LogToErrorFile(fmt.Sprintf("Error: For updating Payment Gateway => %s, %s",
@@ -142,6 +175,10 @@ func (service *Service) UpdatePaymentGateway(paymentGateway *entity.PaymentGatew
 		return errors.New("unable to update payment gateway")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionUpdate, fmt.Sprint(paymentGateway.ID),
+		prevPaymentGateway, paymentGateway)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished payment gateway updating process, Payment Gateway => %s",
 		paymentGateway.ToString()), service.logger.Logs.TransactionLogFile)
@@ -164,168 +201,677 @@ func (service *Service) DeletePaymentGateway(id int64) (*entity.PaymentGateway,
 		return nil, errors.New("unable to delete payment gateway")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionDelete, fmt.Sprint(id), paymentGateway, nil)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished payment gateway deleting process, Deleted Payment Gateway => %s",
 		paymentGateway.ToString()), service.logger.Logs.TransactionLogFile)
 	return paymentGateway, nil
 }
 
-// GetTelebirrH5WebURL is a method that generates a H5 web url
-func (service *Service) GetTelebirrH5WebURL(userID, planID, receiverName, subject, currencyType, initiatedFrom string,
-	receivedAmount float64) (string, error) {
+// InitiateSubscriptionTransaction is a method that creates a pending subscription transaction through the payment
+// provider of the given payment gateway and returns it together with the url the user should pay through.
+// When an idempotency key is provided, repeating the call while the first transaction is still payable
+// returns the first transaction and its pay url instead of creating a new order, a key can only be used
+// for a single transaction so repeating it once the first transaction has timed out is rejected.
+// A coupon, identified by its code or by its id for renewals, discounts the amount before it is converted
+// to the currency of the payment provider using the rate of the transaction date,
+// then the transaction fee is calculated by the fee engine.
+// A renewal transaction holds the id of the subscription it renews, other transactions leave it empty.
+func (service *Service) InitiateSubscriptionTransaction(gatewayID int64, idempotencyKey, userID, planID, receiverName,
+	subject, currencyType, initiatedFrom, couponIdentifier, renewedSubscriptionID string,
+	amount money.Amount) (*entity.SubscriptionTransaction, string, error) {
 
-	type TelebirrRequest struct {
-		AppID string `json:"appid"`
-		Sign  string `json:"sign"`
-		USSD  string `json:"ussd"`
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started subscription transaction initiating process "+
+		"{ Payment Gateway ID : %d, Idempotency Key : %s, User ID : %s, Plan ID : %s }", gatewayID, idempotencyKey,
+		userID, planID), service.logger.Logs.TransactionLogFile)
+
+	emptyKey, _ := regexp.MatchString(`^\s*$`, idempotencyKey)
+	if !emptyKey {
+		lockKey := "subscription_transactions:" + userID + ":" + idempotencyKey
+		service.idempotencyLocks.Lock(lockKey)
+		defer service.idempotencyLocks.Unlock(lockKey)
+
+		prevSubscriptionTransaction, err := service.subTransactionRepo.FindByIdempotencyKey(userID, idempotencyKey,
+			time.Now())
+		if err == nil {
+			/* ---------------------------- Logging ---------------------------- */
+			service.logger.Log(fmt.Sprintf("Finished subscription transaction initiating process, repeated request "+
+				"{ Idempotency Key : %s, Subscription Transaction ID : %s }", idempotencyKey,
+				prevSubscriptionTransaction.ID), service.logger.Logs.TransactionLogFile)
+
+			return prevSubscriptionTransaction, prevSubscriptionTransaction.PayURL, nil
+		}
 	}
 
-	type TelebirrRequestParameters struct {
-		AppID          string `json:"appId" url:"appId"`
-		AppKey         string `json:"-" url:"appKey"` // since it isn't included in the ussd value no need to change it to json format
-		Nonce          string `json:"nonce" url:"nonce"`
-		NotifyURL      string `json:"notifyUrl" url:"notifyUrl,omitempty"`
-		OutTradeNo     string `json:"outTradeNo" url:"outTradeNo"`
-		ReceiverName   string `json:"receiveName" url:"receiveName,omitempty"`
-		ReturnURL      string `json:"returnUrl" url:"returnUrl,omitempty"`
-		ShortCode      string `json:"shortCode" url:"shortCode"`
-		Subject        string `json:"subject" url:"subject,omitempty"`
-		TimeoutExpress string `json:"timeoutExpress" url:"timeoutExpress"`
-		Timestamp      string `json:"timestamp" url:"timestamp"`
-		TotalAmount    string `json:"totalAmount" url:"totalAmount"`
+	provider, err := service.gateways.Find(gatewayID)
+	if err != nil {
+		return nil, "", err
 	}
 
-	type TelebirrResponse struct {
-		Code    string            `json:"code"`
-		Message string            `json:"msg"`
-		Data    map[string]string `json:"data"`
+	var couponRedemption *entity.CouponRedemption
+	emptyCoupon, _ := regexp.MatchString(`^\s*$`, couponIdentifier)
+	if !emptyCoupon {
+		isRenewal := initiatedFrom == entity.InitiatedFromRenewal
+		couponRedemption, err = service.couponService.ApplyCoupon(&coupon.Request{Identifier: couponIdentifier,
+			UserID: userID, SubscriptionPlanID: planID, CurrencyType: currencyType, Amount: amount,
+			IsRenewal: isRenewal, Moment: time.Now()})
+
+		// A renewal is charged the full price once the carried over coupon no longer applies
+		if err != nil && !isRenewal {
+			return nil, "", err
+		}
+
+		if couponRedemption != nil {
+			discounted, err := money.New(amount, currencyType).Sub(money.New(couponRedemption.DiscountAmount,
+				couponRedemption.CurrencyType))
+			if err != nil {
+				/* ---------------------------- Logging ---------------------------- */
+				service.logger.LogToErrorFile(fmt.Sprintf("Error: For applying coupon discount "+
+					"{ Coupon ID : %s, Currency : %s, Discount Currency : %s }, %s", couponRedemption.CouponID,
+					currencyType, couponRedemption.CurrencyType, err.Error()))
+
+				return nil, "", errors.New("unable to apply coupon discount")
+			}
+
+			amount = discounted.Amount
+		}
 	}
 
-	var requestTimeout int64 = 60
-	var timestamp int64 = time.Now().Unix()
-	var transactionFee float64 = service.TelebirrAPI.TransactionFee
-	uniqueID := strings.ReplaceAll(uuid.Must(uuid.NewRandom()).String(), "-", "")
-	totalAmount := receivedAmount + transactionFee
+	paidCurrency := provider.Currency()
+	paidAmount, exchangeRate, err := service.currencyService.Convert(amount, currencyType, paidCurrency, time.Now())
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For converting transaction amount "+
+			"{ Amount : %s, From : %s, To : %s }, %s", amount, currencyType, paidCurrency, err.Error()))
 
-	telebirrRequestParameters := &TelebirrRequestParameters{
-		AppID:          service.TelebirrAPI.AppID,
-		AppKey:         service.TelebirrAPI.AppKey,
-		Nonce:          uniqueID,
-		OutTradeNo:     "T_" + uniqueID,
-		NotifyURL:      service.TelebirrAPI.NotifyURL,
-		ReceiverName:   receiverName,
-		ReturnURL:      service.TelebirrAPI.ReturnURL,
-		ShortCode:      service.TelebirrAPI.ShortCode,
-		Subject:        subject,
-		TimeoutExpress: strconv.FormatInt(requestTimeout, 10),
-		Timestamp:      strconv.FormatInt(timestamp, 10),
-		TotalAmount:    strconv.FormatFloat(totalAmount, 'f', 2, 64),
+		return nil, "", err
+	}
+
+	quote, err := service.feeService.CalculateFee(&fee.Request{GatewayID: service.gateways.Resolve(gatewayID),
+		SubscriptionPlanID: planID, CurrencyType: paidCurrency, Amount: paidAmount, DefaultFee: provider.TransactionFee()})
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For calculating transaction fee "+
+			"{ Payment Gateway ID : %d, Plan ID : %s, Amount : %s }, %s", gatewayID, planID, paidAmount, err.Error()))
+
+		return nil, "", err
 	}
 
+	var requestTimeout int64 = 60
+	uniqueID := strings.ReplaceAll(uuid.Must(uuid.NewRandom()).String(), "-", "")
+
 	subscriptionTransaction := &entity.SubscriptionTransaction{
 		UserID:         userID,
 		PlanID:         planID,
-		AppID:          telebirrRequestParameters.AppID,
-		ReceiverName:   telebirrRequestParameters.ReceiverName,
-		Subject:        telebirrRequestParameters.Subject,
-		ReceivedAmount: receivedAmount,
-		TransactionFee: transactionFee,
-		CurrencyType:   currencyType,
+		AppID:          provider.AppID(),
+		ReceiverName:   receiverName,
+		Subject:        subject,
+		ReceivedAmount: quote.ReceivedAmount,
+		TransactionFee: quote.TransactionFee,
+		CurrencyType:   paidCurrency,
 		TimeoutExpress: requestTimeout,
-		Nonce:          telebirrRequestParameters.Nonce,
-		OutTradeNo:     telebirrRequestParameters.OutTradeNo,
+		Nonce:          uniqueID,
+		OutTradeNo:     "T_" + uniqueID,
 		InitiatedFrom:  initiatedFrom,
+		FeeRuleIDs:     strings.Join(quote.RuleIDs, ","),
+		FeeAbsorbedBy:  quote.AbsorbedBy,
+
+		OriginalAmount:   amount,
+		OriginalCurrency: currencyType,
+		ExchangeRate:     exchangeRate,
+
+		RenewedSubscriptionID: renewedSubscriptionID,
+	}
+
+	if !emptyKey {
+		subscriptionTransaction.IdempotencyKey = entity.NullableString(idempotencyKey)
 	}
 
 	// Checking the nonce and out_trade_no uniqueness
 	for errMap := service.ValidateSubscriptionTransaction(subscriptionTransaction); errMap["nonce"] != nil ||
-		errMap["out_trade_no"] != nil; {
+		errMap["out_trade_no"] != nil; errMap = service.ValidateSubscriptionTransaction(subscriptionTransaction) {
 
 		uniqueID := strings.ReplaceAll(uuid.Must(uuid.NewRandom()).String(), "-", "")
 		subscriptionTransaction.Nonce = uniqueID
 		subscriptionTransaction.OutTradeNo = "T_" + uniqueID
 	}
 
-	telebirrParameterS, err := json.Marshal(telebirrRequestParameters)
+	// Reserving the coupon before the payment is requested so the coupon's redemption limits can't be exceeded
+	if couponRedemption != nil {
+		if err := service.couponService.RedeemCoupon(couponRedemption); err != nil {
+			return nil, "", err
+		}
+
+		subscriptionTransaction.CouponID = couponRedemption.CouponID
+		subscriptionTransaction.DiscountAmount = couponRedemption.DiscountAmount
+	}
+
+	paymentRequest := &transaction.PaymentRequest{
+		Nonce:          subscriptionTransaction.Nonce,
+		OutTradeNo:     subscriptionTransaction.OutTradeNo,
+		ReceiverName:   subscriptionTransaction.ReceiverName,
+		Subject:        subscriptionTransaction.Subject,
+		TotalAmount:    quote.TotalAmount,
+		CurrencyType:   paidCurrency,
+		TimeoutExpress: requestTimeout,
+	}
+
+	toPayURL, err := provider.Initiate(paymentRequest)
 	if err != nil {
-		return "", err
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For initiating payment "+
+			"{ Payment Gateway ID : %d, Out Trade No : %s }, %s", gatewayID, paymentRequest.OutTradeNo, err.Error()))
+
+		service.releaseCouponRedemption(couponRedemption)
+		return nil, "", err
+	}
+
+	// Adding the subscription transaction to the database
+	subscriptionTransaction.Status = entity.TransactionStatusPending
+	subscriptionTransaction.PayURL = toPayURL
+	err = service.AddSubscriptionTransaction(subscriptionTransaction)
+	if err != nil {
+		service.releaseCouponRedemption(couponRedemption)
+
+		// Another instance has created the transaction of the same request first, so its transaction is returned
+		if err == transaction.ErrIdempotencyKeyUsed {
+			prevSubscriptionTransaction, err := service.subTransactionRepo.FindByIdempotencyKey(userID,
+				idempotencyKey, time.Now())
+			if err != nil {
+				return nil, "", transaction.ErrIdempotencyKeyUsed
+			}
+
+			return prevSubscriptionTransaction, prevSubscriptionTransaction.PayURL, nil
+		}
+
+		return nil, "", err
+	}
+
+	// A redemption that couldn't be attached fails the checkout, so a discount is never paid without its redemption
+	if couponRedemption != nil {
+		err = service.couponService.AttachCouponRedemption(couponRedemption.ID, subscriptionTransaction.ID)
+		if err != nil {
+			service.releaseCouponRedemption(couponRedemption)
+			service.expireSubscriptionTransaction(subscriptionTransaction)
+			return nil, "", err
+		}
 	}
 
-	publicKey, err := tools.BytesToPublicKey(service.TelebirrAPI.PublicKey, nil)
+	return subscriptionTransaction, toPayURL, nil
+}
+
+// GetTelebirrH5WebURL is a method that generates a H5 web url through the default payment gateway,
+// it is kept for the callers that predate the payment gateway drivers
+func (service *Service) GetTelebirrH5WebURL(userID, planID, receiverName, subject, currencyType, initiatedFrom string,
+	receivedAmount float64) (string, error) {
+
+	_, toPayURL, err := service.InitiateSubscriptionTransaction(transaction.DefaultGatewayID, "", userID, planID,
+		receiverName, subject, currencyType, initiatedFrom, "", "", money.FromFloat(receivedAmount))
 	if err != nil {
 		return "", err
 	}
 
-	var encryptedBytes []byte
-	source := string(telebirrParameterS)
-	for len(source) > 0 {
-		input := tools.Substr(source, 0, 117)
-		source = tools.Substr(source, 117, len(source))
+	return toPayURL, nil
+}
 
-		encryptedChunkBytes, err := tools.EncryptWithPublicKey([]byte(input), publicKey)
-		if err != nil {
-			return "", err
+// releaseCouponRedemption is a method that releases the coupon redemption reserved for a checkout that has failed
+func (service *Service) releaseCouponRedemption(couponRedemption *entity.CouponRedemption) {
+	if couponRedemption != nil {
+		service.couponService.ReleaseCouponRedemption(couponRedemption.ID)
+	}
+}
+
+// HandlePaymentNotification is a method that verifies a payment notification using the payment provider
+// of the given payment gateway, then applies the notification result to the pending subscription transaction it belongs to
+func (service *Service) HandlePaymentNotification(gatewayID int64, payload []byte) (*entity.SubscriptionTransaction, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started payment notification handling process { Payment Gateway ID : %d }",
+		gatewayID), service.logger.Logs.TransactionLogFile)
+
+	provider, err := service.gateways.Find(gatewayID)
+	if err != nil {
+		return nil, err
+	}
+
+	paymentResult, err := provider.VerifyCallback(payload)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For verifying payment notification "+
+			"{ Payment Gateway ID : %d }, %s", gatewayID, err.Error()))
+
+		return nil, err
+	}
+
+	subscriptionTransaction, err := service.applyPaymentResult(provider, paymentResult)
+	if err != nil {
+		return nil, err
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished payment notification handling process, Subscription Transaction => %s",
+		subscriptionTransaction.ToString()), service.logger.Logs.TransactionLogFile)
+
+	return subscriptionTransaction, nil
+}
+
+// InitiateSPSubscriptionTransaction is a method that creates a pending service provider subscription transaction
+// through the payment provider of the given payment gateway and returns it together with the url the provider should pay through.
+// When an idempotency key is provided, repeating the call while the first transaction is still payable
+// returns the first transaction and its pay url instead of creating a new order, a key can only be used
+// for a single transaction so repeating it once the first transaction has timed out is rejected.
+// The amount is converted to the currency of the payment provider using the rate of the transaction date,
+// then the transaction fee is calculated by the fee engine.
+func (service *Service) InitiateSPSubscriptionTransaction(gatewayID int64, idempotencyKey, providerID, planID,
+	receiverName, subject, currencyType string, amount money.Amount) (*entity.SPSubscriptionTransaction, string, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started SP subscription transaction initiating process "+
+		"{ Payment Gateway ID : %d, Idempotency Key : %s, Provider ID : %s, Plan ID : %s }", gatewayID,
+		idempotencyKey, providerID, planID), service.logger.Logs.TransactionLogFile)
+
+	emptyKey, _ := regexp.MatchString(`^\s*$`, idempotencyKey)
+	if !emptyKey {
+		lockKey := "sp_subscription_transactions:" + providerID + ":" + idempotencyKey
+		service.idempotencyLocks.Lock(lockKey)
+		defer service.idempotencyLocks.Unlock(lockKey)
+
+		prevSubscriptionTransaction, err := service.spSubscriptionTransactionRepo.FindByIdempotencyKey(providerID,
+			idempotencyKey, time.Now())
+		if err == nil {
+			/* ---------------------------- Logging ---------------------------- */
+			service.logger.Log(fmt.Sprintf("Finished SP subscription transaction initiating process, repeated request "+
+				"{ Idempotency Key : %s, SP Subscription Transaction ID : %s }", idempotencyKey,
+				prevSubscriptionTransaction.ID), service.logger.Logs.TransactionLogFile)
+
+			return prevSubscriptionTransaction, prevSubscriptionTransaction.PayURL, nil
 		}
+	}
 
-		encryptedBytes = append(encryptedBytes, encryptedChunkBytes...)
+	provider, err := service.gateways.Find(gatewayID)
+	if err != nil {
+		return nil, "", err
 	}
-	encryptedValue := base64.URLEncoding.EncodeToString(encryptedBytes)
 
-	urlValues, err := query.Values(telebirrRequestParameters)
+	paidCurrency := provider.Currency()
+	paidAmount, exchangeRate, err := service.currencyService.Convert(amount, currencyType, paidCurrency, time.Now())
 	if err != nil {
-		return "", errors.New("unable to construct valid request")
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For converting transaction amount "+
+			"{ Amount : %s, From : %s, To : %s }, %s", amount, currencyType, paidCurrency, err.Error()))
+
+		return nil, "", err
 	}
 
-	// Incase
-	parsedURLValue, err := url.PathUnescape(urlValues.Encode())
+	quote, err := service.feeService.CalculateFee(&fee.Request{GatewayID: service.gateways.Resolve(gatewayID),
+		ProviderID: providerID, CurrencyType: paidCurrency, Amount: paidAmount, DefaultFee: provider.TransactionFee()})
 	if err != nil {
-		return "", errors.New("unable to construct valid request")
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For calculating transaction fee "+
+			"{ Payment Gateway ID : %d, Plan ID : %s, Amount : %s }, %s", gatewayID, planID, paidAmount, err.Error()))
+
+		return nil, "", err
 	}
 
-	hasher := sha256.New()
-	hasher.Write([]byte(parsedURLValue))
-	signedValue := base64.URLEncoding.EncodeToString(hasher.Sum(nil))
+	var requestTimeout int64 = 60
+	uniqueID := strings.ReplaceAll(uuid.Must(uuid.NewRandom()).String(), "-", "")
 
-	telebirrRequest := &TelebirrRequest{AppID: service.TelebirrAPI.AppID, USSD: encryptedValue, Sign: signedValue}
+	spSubscriptionTransaction := &entity.SPSubscriptionTransaction{
+		ProviderID:     providerID,
+		PlanID:         planID,
+		AppID:          provider.AppID(),
+		ReceiverName:   receiverName,
+		Subject:        subject,
+		ReceivedAmount: quote.ReceivedAmount,
+		TransactionFee: quote.TransactionFee,
+		CurrencyType:   paidCurrency,
+		TimeoutExpress: requestTimeout,
+		Nonce:          uniqueID,
+		OutTradeNo:     "SP_" + uniqueID,
+		FeeRuleIDs:     strings.Join(quote.RuleIDs, ","),
+		FeeAbsorbedBy:  quote.AbsorbedBy,
 
-	client := new(http.Client)
-	jsonOutput, _ := json.MarshalIndent(telebirrRequest, "", "\t")
-	output := bytes.NewBuffer(jsonOutput)
-	url := service.TelebirrAPI.AccessPoint + "toTradeWebPay"
+		OriginalAmount:   amount,
+		OriginalCurrency: currencyType,
+		ExchangeRate:     exchangeRate,
+	}
 
-	request, err := http.NewRequest("POST", url, output)
+	if !emptyKey {
+		spSubscriptionTransaction.IdempotencyKey = entity.NullableString(idempotencyKey)
+	}
+
+	// Checking the nonce and out_trade_no uniqueness
+	for errMap := service.ValidateSPSubscriptionTransaction(spSubscriptionTransaction); errMap["nonce"] != nil ||
+		errMap["out_trade_no"] != nil; errMap = service.ValidateSPSubscriptionTransaction(spSubscriptionTransaction) {
+
+		uniqueID := strings.ReplaceAll(uuid.Must(uuid.NewRandom()).String(), "-", "")
+		spSubscriptionTransaction.Nonce = uniqueID
+		spSubscriptionTransaction.OutTradeNo = "SP_" + uniqueID
+	}
+
+	paymentRequest := &transaction.PaymentRequest{
+		Nonce:          spSubscriptionTransaction.Nonce,
+		OutTradeNo:     spSubscriptionTransaction.OutTradeNo,
+		ReceiverName:   spSubscriptionTransaction.ReceiverName,
+		Subject:        spSubscriptionTransaction.Subject,
+		TotalAmount:    quote.TotalAmount,
+		CurrencyType:   paidCurrency,
+		TimeoutExpress: requestTimeout,
+	}
+
+	toPayURL, err := provider.Initiate(paymentRequest)
 	if err != nil {
-		return "", err
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For initiating payment "+
+			"{ Payment Gateway ID : %d, Out Trade No : %s }, %s", gatewayID, paymentRequest.OutTradeNo, err.Error()))
+
+		return nil, "", err
 	}
 
-	response, err := client.Do(request)
+	// Adding the service provider subscription transaction to the database
+	spSubscriptionTransaction.Status = entity.TransactionStatusPending
+	spSubscriptionTransaction.PayURL = toPayURL
+	err = service.AddSPSubscriptionTransaction(spSubscriptionTransaction)
 	if err != nil {
-		return "", err
+
+		// Another instance has created the transaction of the same request first, so its transaction is returned
+		if err == transaction.ErrIdempotencyKeyUsed {
+			prevSubscriptionTransaction, err := service.spSubscriptionTransactionRepo.FindByIdempotencyKey(providerID,
+				idempotencyKey, time.Now())
+			if err != nil {
+				return nil, "", transaction.ErrIdempotencyKeyUsed
+			}
+
+			return prevSubscriptionTransaction, prevSubscriptionTransaction.PayURL, nil
+		}
+
+		return nil, "", err
 	}
-	defer response.Body.Close()
 
-	responseBody, err := ioutil.ReadAll(response.Body)
+	return spSubscriptionTransaction, toPayURL, nil
+}
+
+// HandleTelebirrNotification is a method that handles a payment notification of the default payment gateway,
+// it is kept for the callers that predate the payment gateway drivers
+func (service *Service) HandleTelebirrNotification(payload []byte) (*entity.SubscriptionTransaction, error) {
+	return service.HandlePaymentNotification(transaction.DefaultGatewayID, payload)
+}
+
+// HandleSPPaymentNotification is a method that verifies a payment notification using the payment provider
+// of the given payment gateway, then applies the notification result to the pending service provider subscription
+// transaction it belongs to
+func (service *Service) HandleSPPaymentNotification(gatewayID int64,
+	payload []byte) (*entity.SPSubscriptionTransaction, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started SP payment notification handling process { Payment Gateway ID : %d }",
+		gatewayID), service.logger.Logs.TransactionLogFile)
+
+	provider, err := service.gateways.Find(gatewayID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	telebirrResponse := new(TelebirrResponse)
-	err = json.Unmarshal(responseBody, telebirrResponse)
+	paymentResult, err := provider.VerifyCallback(payload)
 	if err != nil {
-		return "", err
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For verifying payment notification "+
+			"{ Payment Gateway ID : %d }, %s", gatewayID, err.Error()))
+
+		return nil, err
 	}
 
-	// Means error has occurred
-	if telebirrResponse.Code != "0" {
-		return "", errors.New("unable to generate web url")
+	spSubscriptionTransaction, err := service.applySPPaymentResult(provider, paymentResult)
+	if err != nil {
+		return nil, err
 	}
 
-	// Adding the subscription transaction to the database
-	subscriptionTransaction.Status = entity.TransactionStatusPending
-	err = service.AddSubscriptionTransaction(subscriptionTransaction)
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished SP payment notification handling process, "+
+		"SP Subscription Transaction => %s", spSubscriptionTransaction.ToString()), service.logger.Logs.TransactionLogFile)
+
+	return spSubscriptionTransaction, nil
+}
+
+// applyPaymentResult is a method that applies a payment result reported by a payment provider
+// to the pending subscription transaction it belongs to
+func (service *Service) applyPaymentResult(provider transaction.PaymentProvider,
+	paymentResult *transaction.PaymentResult) (*entity.SubscriptionTransaction, error) {
+
+	subscriptionTransaction, err := service.subTransactionRepo.Find(paymentResult.OutTradeNo)
+	if err != nil || subscriptionTransaction.OutTradeNo != paymentResult.OutTradeNo {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For payment result no subscription transaction found "+
+			"{ App ID : %s, Out Trade No : %s }", provider.AppID(), paymentResult.OutTradeNo))
+
+		return nil, errors.New("no subscription transaction found")
+	}
+
+	err = service.verifyPaymentResult(provider, paymentResult, subscriptionTransaction.AppID,
+		subscriptionTransaction.Status, subscriptionTransaction.Total(),
+		"subscription_transactions")
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	// Settling only succeeds while the transaction is still pending, so a concurrent delivery that has passed
+	// the checks above can't settle the transaction a second time
+	err = service.subTransactionRepo.Settle(subscriptionTransaction.ID, paymentResult.TradeNo, paymentResult.Status)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For settling subscription transaction "+
+			"{ Subscription Transaction ID : %s, Trade No : %s }, %s", subscriptionTransaction.ID,
+			paymentResult.TradeNo, err.Error()))
+
+		if err == transaction.ErrPaymentProcessed || err == transaction.ErrPaymentMismatch {
+			return nil, err
+		}
+		return nil, errors.New("unable to settle subscription transaction")
+	}
+
+	settledTransaction, err := service.subTransactionRepo.Find(subscriptionTransaction.ID)
+	if err != nil {
+		return nil, errors.New("no subscription transaction found")
 	}
 
-	return telebirrResponse.Data["toPayUrl"], nil
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionUpdate, settledTransaction.ID, subscriptionTransaction, settledTransaction)
+
+	return settledTransaction, nil
+}
+
+// applySPPaymentResult is a method that applies a payment result reported by a payment provider
+// to the pending service provider subscription transaction it belongs to
+func (service *Service) applySPPaymentResult(provider transaction.PaymentProvider,
+	paymentResult *transaction.PaymentResult) (*entity.SPSubscriptionTransaction, error) {
+
+	subscriptionTransaction, err := service.spSubscriptionTransactionRepo.Find(paymentResult.OutTradeNo)
+	if err != nil || subscriptionTransaction.OutTradeNo != paymentResult.OutTradeNo {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For payment result no service provider subscription "+
+			"transaction found { App ID : %s, Out Trade No : %s }", provider.AppID(), paymentResult.OutTradeNo))
+
+		return nil, errors.New("no service provider subscription transaction found")
+	}
+
+	err = service.verifyPaymentResult(provider, paymentResult, subscriptionTransaction.AppID,
+		subscriptionTransaction.Status, subscriptionTransaction.Total(),
+		"sp_subscription_transactions")
+	if err != nil {
+		return nil, err
+	}
+
+	// Settling only succeeds while the transaction is still pending, so a concurrent delivery that has passed
+	// the checks above can't settle the transaction a second time
+	err = service.spSubscriptionTransactionRepo.Settle(subscriptionTransaction.ID, paymentResult.TradeNo,
+		paymentResult.Status)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For settling service provider subscription transaction "+
+			"{ SP Subscription Transaction ID : %s, Trade No : %s }, %s", subscriptionTransaction.ID,
+			paymentResult.TradeNo, err.Error()))
+
+		if err == transaction.ErrPaymentProcessed || err == transaction.ErrPaymentMismatch {
+			return nil, err
+		}
+		return nil, errors.New("unable to settle service provider subscription transaction")
+	}
+
+	settledTransaction, err := service.spSubscriptionTransactionRepo.Find(subscriptionTransaction.ID)
+	if err != nil {
+		return nil, errors.New("no service provider subscription transaction found")
+	}
+
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionUpdate, settledTransaction.ID, subscriptionTransaction, settledTransaction)
+
+	return settledTransaction, nil
+}
+
+// verifyPaymentResult is a method that checks a payment result against the transaction it belongs to.
+// The transaction should have been made through the provider, still be pending and have the paid amount as its total
+// in the provider's currency.
+func (service *Service) verifyPaymentResult(provider transaction.PaymentProvider,
+	paymentResult *transaction.PaymentResult, appID, status string, total money.Money, tableName string) error {
+
+	empty, _ := regexp.MatchString(`^\s*$`, paymentResult.OutTradeNo)
+	if empty {
+		return errors.New("invalid payment result")
+	}
+
+	if paymentResult.Status != entity.TransactionStatusComplete &&
+		paymentResult.Status != entity.TransactionStatusFailed {
+		return errors.New("payment hasn't been settled")
+	}
+
+	if appID != provider.AppID() {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For payment result app id mismatch "+
+			"{ Out Trade No : %s, Expected App ID : %s, App ID : %s }", paymentResult.OutTradeNo, appID,
+			provider.AppID()))
+
+		return transaction.ErrPaymentMismatch
+	}
+
+	// A result that targets an already processed transaction is a replay, only an expired transaction can still be
+	// completed since its payment may have been made right before the timeout
+	if status != entity.TransactionStatusPending &&
+		(status != entity.TransactionStatusExpired || paymentResult.Status != entity.TransactionStatusComplete) {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For payment result replayed result received "+
+			"{ Out Trade No : %s, Trade No : %s }", paymentResult.OutTradeNo, paymentResult.TradeNo))
+
+		return transaction.ErrPaymentProcessed
+	}
+
+	// A trade number can only settle a single transaction, the unique index on the trade number enforces it on settling
+	if !service.cmService.IsUnique("trade_no", paymentResult.TradeNo, tableName) {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For payment result reused trade number received "+
+			"{ Out Trade No : %s, Trade No : %s }", paymentResult.OutTradeNo, paymentResult.TradeNo))
+
+		return transaction.ErrPaymentMismatch
+	}
+
+	paid := money.New(paymentResult.TotalAmount, provider.Currency())
+	if comparison, err := paid.Cmp(total); err != nil || comparison != 0 {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For payment result amount mismatch "+
+			"{ Out Trade No : %s, Expected Amount : %s, Paid Amount : %s }", paymentResult.OutTradeNo,
+			total, paid))
+
+		return transaction.ErrPaymentMismatch
+	}
+
+	return nil
+}
+
+// queryPaymentResult is a method that asks the provider a stale transaction has been made through for its payment state.
+// A nil payment result is returned when the transaction should be expired, meaning the payment is still open
+// after its timeout. A provider that can't be queried returns transaction.ErrUnsupportedOperation, since the payment
+// may still have been made and only its notification can tell.
+func (service *Service) queryPaymentResult(appID, outTradeNo string) (transaction.PaymentProvider,
+	*transaction.PaymentResult, error) {
+
+	provider, err := service.gateways.FindByAppID(appID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	paymentResult, err := provider.QueryStatus(outTradeNo)
+	if err == transaction.ErrUnsupportedOperation {
+		return nil, nil, err
+	}
+
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For querying payment status "+
+			"{ App ID : %s, Out Trade No : %s }, %s", appID, outTradeNo, err.Error()))
+
+		return nil, nil, errors.New("unable to query payment status")
+	}
+
+	if paymentResult.OutTradeNo != outTradeNo {
+		return nil, nil, transaction.ErrPaymentMismatch
+	}
+
+	if paymentResult.Status == entity.TransactionStatusPending {
+		return provider, nil, nil
+	}
+
+	return provider, paymentResult, nil
+}
+
+// keyedMutex is a type that serializes work done under the same key,
+// such as the initiation of transactions that share an idempotency key
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+// keyedLock is a type that holds the lock of a single key together with the number of goroutines using it
+type keyedLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// newKeyedMutex is a function that returns a new keyed mutex
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{locks: make(map[string]*keyedLock)}
+}
+
+// Lock is a method that locks the given key, waiting until it is unlocked if it is already locked
+func (keyed *keyedMutex) Lock(key string) {
+
+	keyed.mu.Lock()
+	lock, ok := keyed.locks[key]
+	if !ok {
+		lock = new(keyedLock)
+		keyed.locks[key] = lock
+	}
+	lock.refs++
+	keyed.mu.Unlock()
+
+	lock.mu.Lock()
+}
+
+// Unlock is a method that unlocks the given key, the key is forgotten once no goroutine uses it
+func (keyed *keyedMutex) Unlock(key string) {
+
+	keyed.mu.Lock()
+	defer keyed.mu.Unlock()
+
+	lock, ok := keyed.locks[key]
+	if !ok {
+		return
+	}
+
+	lock.refs--
+	if lock.refs == 0 {
+		delete(keyed.locks, key)
+	}
+	lock.mu.Unlock()
 }
@@ -0,0 +1,182 @@
+package service_test
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/log"
+	"github.com/Benyam-S/onemembership/money"
+	"github.com/Benyam-S/onemembership/transaction"
+	"github.com/Benyam-S/onemembership/transaction/gateway/telebirr"
+	"github.com/Benyam-S/onemembership/transaction/internal/transactiontest"
+	"github.com/Benyam-S/onemembership/transaction/service"
+)
+
+func TestHandlePaymentNotification(t *testing.T) {
+
+	const appID = "telebirr-test-app"
+
+	server := transactiontest.NewTelebirrServer(t, appID)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	driver := telebirr.NewTelebirrDriver(&transaction.TelebirrAPIAccount{AccessPoint: httpServer.URL + "/",
+		AppID: appID, AppKey: "telebirr-test-key", ShortCode: "500000", PublicKey: server.PublicKey()})
+
+	gatewayRegistry := transaction.NewGatewayRegistry([]*entity.PaymentGateway{{ID: 1, Name: "Telebirr"}},
+		map[string]transaction.PaymentProvider{"Telebirr": driver})
+
+	clock := transactiontest.NewClock(time.Date(2021, time.June, 10, 12, 0, 0, 0, time.UTC))
+	repo := transactiontest.NewSubscriptionTransactionRepository(clock, nil)
+	transactionService := service.NewTransactionService(nil, repo, nil, nil, gatewayRegistry, nil, nil, nil,
+		&transactiontest.CommonService{Repo: repo}, &transactiontest.AuditService{},
+		log.NewLogger(&log.LogContainer{}, log.None))
+
+	// initiate opens a payment at the fake server and stores its pending subscription transaction
+	initiate := func(id string) *entity.SubscriptionTransaction {
+
+		subscriptionTransaction := entity.SubscriptionTransaction{ID: id, AppID: appID, Nonce: "N_" + id,
+			OutTradeNo: "T_" + id, ReceivedAmount: money.Amount(9800), TransactionFee: money.Amount(200),
+			CurrencyType: telebirr.CurrencyType, TimeoutExpress: 60, Status: entity.TransactionStatusPending}
+
+		_, err := driver.Initiate(&transaction.PaymentRequest{Nonce: subscriptionTransaction.Nonce,
+			OutTradeNo: subscriptionTransaction.OutTradeNo, ReceiverName: "Project", Subject: "Plan",
+			TotalAmount:  subscriptionTransaction.Total().Amount,
+			CurrencyType: telebirr.CurrencyType, TimeoutExpress: subscriptionTransaction.TimeoutExpress})
+		if err != nil {
+			t.Fatalf("initiating payment: %v", err)
+		}
+
+		repo.Put(subscriptionTransaction)
+		return &subscriptionTransaction
+	}
+
+	find := func(id string) *entity.SubscriptionTransaction {
+		subscriptionTransaction, err := repo.Find(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return subscriptionTransaction
+	}
+
+	t.Run("valid notification", func(t *testing.T) {
+
+		pendingTransaction := initiate("ST-1")
+		payload := server.Notify(pendingTransaction.OutTradeNo, "TB-1", "100.00", telebirr.TradeStatusCompleted)
+
+		subscriptionTransaction, err := transactionService.HandlePaymentNotification(1, payload)
+		if err != nil {
+			t.Fatalf("handling valid notification: %v", err)
+		}
+
+		if subscriptionTransaction.Status != entity.TransactionStatusComplete || subscriptionTransaction.TradeNo != "TB-1" {
+			t.Fatalf("unexpected subscription transaction %+v", subscriptionTransaction)
+		}
+
+		if find(pendingTransaction.ID).Status != entity.TransactionStatusComplete {
+			t.Fatal("subscription transaction hasn't been completed")
+		}
+
+		t.Run("replayed notification", func(t *testing.T) {
+			if _, err := transactionService.HandlePaymentNotification(1, payload); err == nil {
+				t.Fatal("replayed notification has been accepted")
+			}
+
+			subscriptionTransaction := find(pendingTransaction.ID)
+			if subscriptionTransaction.Status != entity.TransactionStatusComplete || subscriptionTransaction.TradeNo != "TB-1" {
+				t.Fatalf("replayed notification changed the subscription transaction %+v", subscriptionTransaction)
+			}
+		})
+
+		t.Run("reused trade number", func(t *testing.T) {
+			otherTransaction := initiate("ST-4")
+			payload := server.Notify(otherTransaction.OutTradeNo, "TB-1", "100.00", telebirr.TradeStatusCompleted)
+
+			if _, err := transactionService.HandlePaymentNotification(1, payload); err != transaction.ErrPaymentMismatch {
+				t.Fatalf("reused trade number returned %v, expected %v", err, transaction.ErrPaymentMismatch)
+			}
+
+			if find(otherTransaction.ID).Status != entity.TransactionStatusPending {
+				t.Fatal("reused trade number changed the subscription transaction")
+			}
+		})
+	})
+
+	t.Run("telebirr notification", func(t *testing.T) {
+
+		pendingTransaction := initiate("ST-6")
+		payload := server.Notify(pendingTransaction.OutTradeNo, "TB-6", "100.00", telebirr.TradeStatusCompleted)
+
+		subscriptionTransaction, err := transactionService.HandleTelebirrNotification(payload)
+		if err != nil {
+			t.Fatalf("handling telebirr notification: %v", err)
+		}
+
+		if subscriptionTransaction.Status != entity.TransactionStatusComplete || subscriptionTransaction.TradeNo != "TB-6" {
+			t.Fatalf("unexpected subscription transaction %+v", subscriptionTransaction)
+		}
+	})
+
+	t.Run("concurrent deliveries", func(t *testing.T) {
+
+		pendingTransaction := initiate("ST-5")
+		payload := server.Notify(pendingTransaction.OutTradeNo, "TB-5", "100.00", telebirr.TradeStatusCompleted)
+
+		var wg sync.WaitGroup
+		var settled int32
+		for delivery := 0; delivery < 8; delivery++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := transactionService.HandlePaymentNotification(1, payload); err == nil {
+					atomic.AddInt32(&settled, 1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if settled != 1 {
+			t.Fatalf("notification has settled the subscription transaction %d times", settled)
+		}
+	})
+
+	t.Run("mismatching amount", func(t *testing.T) {
+
+		pendingTransaction := initiate("ST-2")
+		payload := server.Notify(pendingTransaction.OutTradeNo, "TB-2", "1.00", telebirr.TradeStatusCompleted)
+
+		if _, err := transactionService.HandlePaymentNotification(1, payload); err != transaction.ErrPaymentMismatch {
+			t.Fatalf("mismatching amount returned %v, expected %v", err, transaction.ErrPaymentMismatch)
+		}
+
+		if find(pendingTransaction.ID).Status != entity.TransactionStatusPending {
+			t.Fatal("mismatching notification changed the subscription transaction")
+		}
+	})
+
+	t.Run("tampered ciphertext", func(t *testing.T) {
+
+		pendingTransaction := initiate("ST-3")
+		payload := server.Notify(pendingTransaction.OutTradeNo, "TB-3", "100.00", telebirr.TradeStatusCompleted)
+
+		encryptedBytes, err := base64.StdEncoding.DecodeString(string(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		encryptedBytes[len(encryptedBytes)/2] ^= 0x01
+		tamperedPayload := []byte(base64.StdEncoding.EncodeToString(encryptedBytes))
+
+		if _, err := transactionService.HandlePaymentNotification(1, tamperedPayload); err == nil {
+			t.Fatal("tampered notification has been accepted")
+		}
+
+		if find(pendingTransaction.ID).Status != entity.TransactionStatusPending {
+			t.Fatal("tampered notification changed the subscription transaction")
+		}
+	})
+}
@@ -4,8 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/money"
+	"github.com/Benyam-S/onemembership/transaction"
 )
 
 // AddSPPayrollTransaction is a method that adds a new service provider payroll transaction to the system
@@ -31,6 +34,98 @@ func (service *Service) AddSPPayrollTransaction(newPayrollTransaction *entity.SP
 	return nil
 }
 
+// RequestWithdrawal is a method that creates a pending payroll transaction for the given amount of a currency,
+// the amount is moved to the pending amount the wallet holds in the currency until the payroll transaction
+// is completed or rejected
+func (service *Service) RequestWithdrawal(providerID, currencyType string,
+	amount money.Amount) (*entity.SPPayrollTransaction, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started withdrawal requesting process { Provider ID : %s, Amount : %s %s }",
+		providerID, amount, currencyType), service.logger.Logs.TransactionLogFile)
+
+	empty, _ := regexp.MatchString(`^\s*$`, providerID)
+	emptyCurrency, _ := regexp.MatchString(`^\s*$`, currencyType)
+	if empty || emptyCurrency || amount <= 0 {
+		return nil, errors.New("invalid withdrawal request")
+	}
+
+	newPayrollTransaction := &entity.SPPayrollTransaction{ProviderID: providerID, PayedAmount: amount,
+		CurrencyType: strings.ToUpper(currencyType)}
+	err := service.spPayrollTransactionRepo.CreateWithdrawal(newPayrollTransaction)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For requesting withdrawal "+
+			"{ Provider ID : %s, Amount : %s %s }, %s", providerID, amount, currencyType, err.Error()))
+
+		return nil, errors.New("unable to request withdrawal")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished withdrawal requesting process, SP Payroll Transaction => %s",
+		newPayrollTransaction.ToString()), service.logger.Logs.TransactionLogFile)
+
+	return newPayrollTransaction, nil
+}
+
+// ApproveSPPayrollTransaction is a method that approves a pending payroll transaction
+func (service *Service) ApproveSPPayrollTransaction(id string) error {
+	return service.updatePayrollStatus(id, entity.PayrollStatusApproved)
+}
+
+// RejectSPPayrollTransaction is a method that rejects a payroll transaction and gives the amount back to the wallet
+func (service *Service) RejectSPPayrollTransaction(id string) error {
+	return service.updatePayrollStatus(id, entity.PayrollStatusRejected)
+}
+
+// CompleteSPPayrollTransaction is a method that marks an approved payroll transaction as paid to the provider
+func (service *Service) CompleteSPPayrollTransaction(id string) error {
+	return service.updatePayrollStatus(id, entity.PayrollStatusComplete)
+}
+
+// updatePayrollStatus is a method that moves a payroll transaction to the given status
+func (service *Service) updatePayrollStatus(id, status string) error {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started payroll transaction status updating process "+
+		"{ SP Payroll Transaction ID : %s, Status : %s }", id, status), service.logger.Logs.TransactionLogFile)
+
+	prevPayrollTransaction, err := service.spPayrollTransactionRepo.Find(id)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding service provider payroll transaction before updating "+
+			"{ SP Payroll Transaction ID : %s }, %s", id, err.Error()))
+
+		return errors.New("unable to update payroll transaction status")
+	}
+
+	err = service.spPayrollTransactionRepo.UpdateStatus(id, status)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For updating payroll transaction status "+
+			"{ SP Payroll Transaction ID : %s, Status : %s }, %s", id, status, err.Error()))
+
+		return errors.New("unable to update payroll transaction status")
+	}
+
+	// Recording the change in the audit trail
+	updatedPayrollTransaction, err := service.spPayrollTransactionRepo.Find(id)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding service provider payroll transaction "+
+			"after updating { SP Payroll Transaction ID : %s }, %s", id, err.Error()))
+	} else {
+		service.recordAudit(entity.AuditActionUpdate, id, prevPayrollTransaction,
+			updatedPayrollTransaction)
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished payroll transaction status updating process "+
+		"{ SP Payroll Transaction ID : %s, Status : %s }", id, status), service.logger.Logs.TransactionLogFile)
+
+	return nil
+}
+
 // FindSPPayrollTransaction is a method that find and return a service provider payroll transaction that matches the id value
 func (service *Service) FindSPPayrollTransaction(id string) (*entity.SPPayrollTransaction, error) {
 
@@ -65,13 +160,34 @@ func (service *Service) FindMultipleSPPayrollTransactions(providerID string) []*
 	return service.spPayrollTransactionRepo.FindMultiple(providerID)
 }
 
+// SearchSPPayrollTransactions is a method that searchs and returns a set of service provider payroll transactions
+// that match the filter, along with the page count and the per currency totals of all the matched transactions
+func (service *Service) SearchSPPayrollTransactions(filter *transaction.SearchFilter,
+	pageNum int64) ([]*entity.SPPayrollTransaction, int64, []*transaction.SearchTotal) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Searching service provider payroll transactions process { %s, Page Number : %d }",
+		filter.String(), pageNum), service.logger.Logs.TransactionLogFile)
+
+	return service.spPayrollTransactionRepo.Search(filter, pageNum)
+}
+
 // UpdateSPPayrollTransaction is a method that updates a service provider payroll transaction in the system
 func (service *Service) UpdateSPPayrollTransaction(payrollTransaction *entity.SPPayrollTransaction) error {
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Started payroll transaction updating process, SP Payroll Transaction => %s",
 		payrollTransaction.ToString()), service.logger.Logs.TransactionLogFile)
 
-	err := service.spPayrollTransactionRepo.Update(payrollTransaction)
+	prevPayrollTransaction, err := service.spPayrollTransactionRepo.Find(payrollTransaction.ID)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding service provider payroll transaction before updating "+
+			"{ SP Payroll Transaction ID : %s }, %s", payrollTransaction.ID, err.Error()))
+
+		return errors.New("unable to update payroll transaction")
+	}
+
+	err = service.spPayrollTransactionRepo.Update(payrollTransaction)
 	if err != nil {
 		/* ---------------------------- Logging ---------------------------- */
 		service.logger.LogToErrorFile(fmt.Sprintf("Error: For updating SP Payroll Transaction => %s, %s",
@@ -80,6 +196,10 @@ func (service *Service) UpdateSPPayrollTransaction(payrollTransaction *entity.SP
 		return errors.New("unable to update payroll transaction")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionUpdate, payrollTransaction.ID,
+		prevPayrollTransaction, payrollTransaction)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished payroll transaction updating process, SP Payroll Transaction => %s",
 		payrollTransaction.ToString()), service.logger.Logs.TransactionLogFile)
@@ -103,6 +223,9 @@ func (service *Service) DeleteSPPayrollTransaction(id string) (*entity.SPPayroll
 		return nil, errors.New("unable to delete payroll transaction")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionDelete, id, payrollTransaction, nil)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished payroll transaction deleting process, "+
 		"Deleted SP Payroll Transaction => %s", payrollTransaction.ToString()), service.logger.Logs.TransactionLogFile)
@@ -115,5 +238,12 @@ func (service *Service) DeleteMultipleSPPayrollTransactions(providerID string) [
 	service.logger.Log(fmt.Sprintf("Multiple payroll transaction deleting { Provider ID : %s }",
 		providerID), service.logger.Logs.TransactionLogFile)
 
-	return service.spPayrollTransactionRepo.DeleteMultiple(providerID)
+	payrollTransactions := service.spPayrollTransactionRepo.DeleteMultiple(providerID)
+
+	// Recording the changes in the audit trail
+	for _, payrollTransaction := range payrollTransactions {
+		service.recordAudit(entity.AuditActionDelete, payrollTransaction.ID, payrollTransaction, nil)
+	}
+
+	return payrollTransactions
 }
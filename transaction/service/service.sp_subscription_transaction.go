@@ -4,8 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"time"
 
 	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/transaction"
 )
 
 // AddSPSubscriptionTransaction is a method that adds a new service provider subscription transaction to the system
@@ -21,6 +23,10 @@ func (service *Service) AddSPSubscriptionTransaction(newSubscriptionTransaction
 		service.logger.LogToErrorFile(fmt.Sprintf("Error: For adding SP Subscription Transaction => %s, %s",
 			newSubscriptionTransaction.ToString(), err.Error()))
 
+		if err == transaction.ErrIdempotencyKeyUsed {
+			return err
+		}
+
 		return errors.New("unable to add new subscription transaction")
 	}
 
@@ -99,13 +105,34 @@ func (service *Service) FindMultipleSPSubscriptionTransactions(identifier string
 	return service.spSubscriptionTransactionRepo.FindMultiple(identifier)
 }
 
+// SearchSPSubscriptionTransactions is a method that searchs and returns a set of service provider subscription transactions
+// that match the filter, along with the page count and the per currency totals of all the matched transactions
+func (service *Service) SearchSPSubscriptionTransactions(filter *transaction.SearchFilter,
+	pageNum int64) ([]*entity.SPSubscriptionTransaction, int64, []*transaction.SearchTotal) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Searching service provider subscription transactions process { %s, Page Number : %d }",
+		filter.String(), pageNum), service.logger.Logs.TransactionLogFile)
+
+	return service.spSubscriptionTransactionRepo.Search(filter, pageNum)
+}
+
 // UpdateSPSubscriptionTransaction is a method that updates a service provider subscription transaction in the system
 func (service *Service) UpdateSPSubscriptionTransaction(subscriptionTransaction *entity.SPSubscriptionTransaction) error {
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Started subscription transaction updating process, SP Subscription Transaction => %s",
 		subscriptionTransaction.ToString()), service.logger.Logs.TransactionLogFile)
 
-	err := service.spSubscriptionTransactionRepo.Update(subscriptionTransaction)
+	prevSubscriptionTransaction, err := service.spSubscriptionTransactionRepo.Find(subscriptionTransaction.ID)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding service provider subscription transaction before updating "+
+			"{ SP Subscription Transaction ID : %s }, %s", subscriptionTransaction.ID, err.Error()))
+
+		return errors.New("unable to update subscription transaction")
+	}
+
+	err = service.spSubscriptionTransactionRepo.Update(subscriptionTransaction)
 	if err != nil {
 		/* ---------------------------- Logging ---------------------------- */
 		service.logger.LogToErrorFile(fmt.Sprintf("Error: For updating SP Subscription Transaction => %s, %s",
@@ -114,6 +141,10 @@ func (service *Service) UpdateSPSubscriptionTransaction(subscriptionTransaction
 		return errors.New("unable to update subscription transaction")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionUpdate, subscriptionTransaction.ID,
+		prevSubscriptionTransaction, subscriptionTransaction)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished subscription transaction updating process, SP Subscription Transaction => %s",
 		subscriptionTransaction.ToString()), service.logger.Logs.TransactionLogFile)
@@ -121,6 +152,94 @@ func (service *Service) UpdateSPSubscriptionTransaction(subscriptionTransaction
 	return nil
 }
 
+// FindStaleSPSubscriptionTransactions is a method that find and return pending service provider subscription transactions
+// whose timeout has passed before the given moment
+func (service *Service) FindStaleSPSubscriptionTransactions(moment time.Time) []*entity.SPSubscriptionTransaction {
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Stale SP subscription transactions finding process { Moment : %s }", moment),
+		service.logger.Logs.TransactionLogFile)
+
+	return service.spSubscriptionTransactionRepo.FindStale(moment)
+}
+
+// ReconcileSPSubscriptionTransaction is a method that settles a stale pending service provider subscription transaction using the state
+// reported by its payment provider. The transaction is completed or failed as the provider reports, and expired when
+// the payment is still open. A transaction whose provider can't be queried is left pending for its notification,
+// transaction.ErrUnsupportedOperation is returned for it.
+func (service *Service) ReconcileSPSubscriptionTransaction(id string,
+	moment time.Time) (*entity.SPSubscriptionTransaction, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started SP subscription transaction reconciling process "+
+		"{ SP Subscription Transaction ID : %s, Moment : %s }", id, moment), service.logger.Logs.TransactionLogFile)
+
+	subscriptionTransaction, err := service.FindSPSubscriptionTransaction(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if subscriptionTransaction.Status != entity.TransactionStatusPending {
+		return nil, errors.New("subscription transaction is not pending")
+	}
+
+	if subscriptionTransaction.CreatedAt.Add(time.Duration(subscriptionTransaction.TimeoutExpress) * time.Minute).
+		After(moment) {
+		return nil, errors.New("subscription transaction hasn't timed out")
+	}
+
+	provider, paymentResult, err := service.queryPaymentResult(subscriptionTransaction.AppID,
+		subscriptionTransaction.OutTradeNo)
+	if err != nil {
+		return nil, err
+	}
+
+	if paymentResult != nil {
+		subscriptionTransaction, err = service.applySPPaymentResult(provider, paymentResult)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		subscriptionTransaction, err = service.expireSPSubscriptionTransaction(subscriptionTransaction)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished SP subscription transaction reconciling process, "+
+		"SP Subscription Transaction => %s", subscriptionTransaction.ToString()), service.logger.Logs.TransactionLogFile)
+
+	return subscriptionTransaction, nil
+}
+
+// expireSPSubscriptionTransaction is a method that expires a pending service provider subscription transaction,
+// the transaction is left as it is when it has been settled in the meantime
+func (service *Service) expireSPSubscriptionTransaction(
+	subscriptionTransaction *entity.SPSubscriptionTransaction) (*entity.SPSubscriptionTransaction, error) {
+
+	err := service.spSubscriptionTransactionRepo.Expire(subscriptionTransaction.ID)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For expiring SP subscription transaction "+
+			"{ SP Subscription Transaction ID : %s }, %s", subscriptionTransaction.ID, err.Error()))
+
+		if err == transaction.ErrPaymentProcessed {
+			return nil, err
+		}
+		return nil, errors.New("unable to expire SP subscription transaction")
+	}
+
+	expiredTransaction, err := service.spSubscriptionTransactionRepo.Find(subscriptionTransaction.ID)
+	if err != nil {
+		return nil, errors.New("no SP subscription transaction found")
+	}
+
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionUpdate, expiredTransaction.ID, subscriptionTransaction, expiredTransaction)
+
+	return expiredTransaction, nil
+}
+
 // DeleteSPSubscriptionTransaction is a method that deletes a service provider subscription transaction from the system using an id
 func (service *Service) DeleteSPSubscriptionTransaction(id string) (*entity.SPSubscriptionTransaction, error) {
 	/* ---------------------------- Logging ---------------------------- */
@@ -137,6 +256,9 @@ func (service *Service) DeleteSPSubscriptionTransaction(id string) (*entity.SPSu
 		return nil, errors.New("unable to delete subscription transaction")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionDelete, id, subscriptionTransaction, nil)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished subscription transaction deleting process, "+
 		"Deleted SP Subscription Transaction => %s", subscriptionTransaction.ToString()), service.logger.Logs.TransactionLogFile)
@@ -149,5 +271,12 @@ func (service *Service) DeleteMultipleSPSubscriptionTransactions(identifier stri
 	service.logger.Log(fmt.Sprintf("Multiple subscription transaction deleting { SP Subscription Transaction Identifier : %s }",
 		identifier), service.logger.Logs.TransactionLogFile)
 
-	return service.spSubscriptionTransactionRepo.DeleteMultiple(identifier)
+	subscriptionTransactions := service.spSubscriptionTransactionRepo.DeleteMultiple(identifier)
+
+	// Recording the changes in the audit trail
+	for _, subscriptionTransaction := range subscriptionTransactions {
+		service.recordAudit(entity.AuditActionDelete, subscriptionTransaction.ID, subscriptionTransaction, nil)
+	}
+
+	return subscriptionTransactions
 }
@@ -4,8 +4,13 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
+	"time"
 
 	"github.com/Benyam-S/onemembership/entity"
+	"github.com/Benyam-S/onemembership/money"
+	"github.com/Benyam-S/onemembership/transaction"
+	"github.com/google/uuid"
 )
 
 // AddSubscriptionTransaction is a method that adds a new subscription transaction to the system
@@ -21,6 +26,10 @@ func (service *Service) AddSubscriptionTransaction(newSubscriptionTransaction *e
 		service.logger.LogToErrorFile(fmt.Sprintf("Error: For adding Subscription Transaction => %s, %s",
 			newSubscriptionTransaction.ToString(), err.Error()))
 
+		if err == transaction.ErrIdempotencyKeyUsed {
+			return err
+		}
+
 		return errors.New("unable to add new subscription transaction")
 	}
 
@@ -31,6 +40,39 @@ func (service *Service) AddSubscriptionTransaction(newSubscriptionTransaction *e
 	return nil
 }
 
+// ConstructCreditSubscriptionTransaction is a method that constructs a completed subscription transaction without
+// an amount, such as the credit of a plan change that didn't require a payment, the caller is responsible for storing it.
+// The transaction holds the credit app id and its own nonce and out trade number, so it can't be mistaken for
+// or settled as a payment gateway transaction.
+func (service *Service) ConstructCreditSubscriptionTransaction(userID, planID, receiverName, subject, currencyType,
+	initiatedFrom string) *entity.SubscriptionTransaction {
+
+	uniqueID := strings.ReplaceAll(uuid.Must(uuid.NewRandom()).String(), "-", "")
+	subscriptionTransaction := &entity.SubscriptionTransaction{
+		UserID:        userID,
+		PlanID:        planID,
+		AppID:         entity.CreditAppID,
+		ReceiverName:  receiverName,
+		Subject:       subject,
+		CurrencyType:  currencyType,
+		Nonce:         uniqueID,
+		OutTradeNo:    "C_" + uniqueID,
+		InitiatedFrom: initiatedFrom,
+		Status:        entity.TransactionStatusComplete,
+	}
+
+	// Checking the nonce and out_trade_no uniqueness
+	for errMap := service.ValidateSubscriptionTransaction(subscriptionTransaction); errMap["nonce"] != nil ||
+		errMap["out_trade_no"] != nil; errMap = service.ValidateSubscriptionTransaction(subscriptionTransaction) {
+
+		uniqueID := strings.ReplaceAll(uuid.Must(uuid.NewRandom()).String(), "-", "")
+		subscriptionTransaction.Nonce = uniqueID
+		subscriptionTransaction.OutTradeNo = "C_" + uniqueID
+	}
+
+	return subscriptionTransaction
+}
+
 // ValidateSubscriptionTransaction is a method that validates a subscription transaction entries.
 // It checks if the subscription transaction has a valid entries or not and return map of errors if any.
 func (service *Service) ValidateSubscriptionTransaction(subscriptionTransaction *entity.SubscriptionTransaction) entity.ErrMap {
@@ -91,13 +133,34 @@ func (service *Service) FindMultipleSubscriptionTransactions(identifier string)
 	return service.subTransactionRepo.FindMultiple(identifier)
 }
 
+// SearchSubscriptionTransactions is a method that searchs and returns a set of subscription transactions that match the filter,
+// along with the page count and the per currency totals of all the matched transactions
+func (service *Service) SearchSubscriptionTransactions(filter *transaction.SearchFilter,
+	pageNum int64) ([]*entity.SubscriptionTransaction, int64, []*transaction.SearchTotal) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Searching subscription transactions process { %s, Page Number : %d }",
+		filter.String(), pageNum), service.logger.Logs.TransactionLogFile)
+
+	return service.subTransactionRepo.Search(filter, pageNum)
+}
+
 // UpdateSubscriptionTransaction is a method that updates a subscription transaction in the system
 func (service *Service) UpdateSubscriptionTransaction(subscriptionTransaction *entity.SubscriptionTransaction) error {
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Started subscription transaction updating process, Subscription Transaction => %s",
 		subscriptionTransaction.ToString()), service.logger.Logs.TransactionLogFile)
 
-	err := service.subTransactionRepo.Update(subscriptionTransaction)
+	prevSubscriptionTransaction, err := service.subTransactionRepo.Find(subscriptionTransaction.ID)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding subscription transaction before updating "+
+			"{ Subscription Transaction ID : %s }, %s", subscriptionTransaction.ID, err.Error()))
+
+		return errors.New("unable to update subscription transaction")
+	}
+
+	err = service.subTransactionRepo.Update(subscriptionTransaction)
 	if err != nil {
 		/* ---------------------------- Logging ---------------------------- */
 		service.logger.LogToErrorFile(fmt.Sprintf("Error: For updating Subscription Transaction => %s, %s",
@@ -106,6 +169,10 @@ func (service *Service) UpdateSubscriptionTransaction(subscriptionTransaction *e
 		return errors.New("unable to update subscription transaction")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionUpdate, subscriptionTransaction.ID,
+		prevSubscriptionTransaction, subscriptionTransaction)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished subscription transaction updating process, Subscription Transaction => %s",
 		subscriptionTransaction.ToString()), service.logger.Logs.TransactionLogFile)
@@ -113,6 +180,240 @@ func (service *Service) UpdateSubscriptionTransaction(subscriptionTransaction *e
 	return nil
 }
 
+// FindStaleSubscriptionTransactions is a method that find and return pending subscription transactions
+// whose timeout has passed before the given moment
+func (service *Service) FindStaleSubscriptionTransactions(moment time.Time) []*entity.SubscriptionTransaction {
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Stale subscription transactions finding process { Moment : %s }", moment),
+		service.logger.Logs.TransactionLogFile)
+
+	return service.subTransactionRepo.FindStale(moment)
+}
+
+// FindUnactivatedSubscriptionTransactions is a method that find and return completed subscription transactions
+// updated within the given period that haven't activated a subscription
+func (service *Service) FindUnactivatedSubscriptionTransactions(from, to time.Time) []*entity.SubscriptionTransaction {
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Unactivated subscription transactions finding process { From : %s, To : %s }",
+		from, to), service.logger.Logs.TransactionLogFile)
+
+	return service.subTransactionRepo.FindUnactivated(from, to)
+}
+
+// ReconcileSubscriptionTransaction is a method that settles a stale pending subscription transaction using the state
+// reported by its payment provider. The transaction is completed or failed as the provider reports, and expired when
+// the payment is still open. A transaction whose provider can't be queried is left pending for its notification,
+// transaction.ErrUnsupportedOperation is returned for it.
+func (service *Service) ReconcileSubscriptionTransaction(id string,
+	moment time.Time) (*entity.SubscriptionTransaction, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started subscription transaction reconciling process "+
+		"{ Subscription Transaction ID : %s, Moment : %s }", id, moment), service.logger.Logs.TransactionLogFile)
+
+	subscriptionTransaction, err := service.FindSubscriptionTransaction(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if subscriptionTransaction.Status != entity.TransactionStatusPending {
+		return nil, errors.New("subscription transaction is not pending")
+	}
+
+	if subscriptionTransaction.CreatedAt.Add(time.Duration(subscriptionTransaction.TimeoutExpress) * time.Minute).
+		After(moment) {
+		return nil, errors.New("subscription transaction hasn't timed out")
+	}
+
+	provider, paymentResult, err := service.queryPaymentResult(subscriptionTransaction.AppID,
+		subscriptionTransaction.OutTradeNo)
+	if err != nil {
+		return nil, err
+	}
+
+	if paymentResult != nil {
+		subscriptionTransaction, err = service.applyPaymentResult(provider, paymentResult)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		subscriptionTransaction, err = service.expireSubscriptionTransaction(subscriptionTransaction)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished subscription transaction reconciling process, "+
+		"Subscription Transaction => %s", subscriptionTransaction.ToString()), service.logger.Logs.TransactionLogFile)
+
+	return subscriptionTransaction, nil
+}
+
+// expireSubscriptionTransaction is a method that expires a pending subscription transaction, the transaction is left
+// as it is when it has been settled in the meantime
+func (service *Service) expireSubscriptionTransaction(
+	subscriptionTransaction *entity.SubscriptionTransaction) (*entity.SubscriptionTransaction, error) {
+
+	err := service.subTransactionRepo.Expire(subscriptionTransaction.ID)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For expiring subscription transaction "+
+			"{ Subscription Transaction ID : %s }, %s", subscriptionTransaction.ID, err.Error()))
+
+		if err == transaction.ErrPaymentProcessed {
+			return nil, err
+		}
+		return nil, errors.New("unable to expire subscription transaction")
+	}
+
+	expiredTransaction, err := service.subTransactionRepo.Find(subscriptionTransaction.ID)
+	if err != nil {
+		return nil, errors.New("no subscription transaction found")
+	}
+
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionUpdate, expiredTransaction.ID, subscriptionTransaction, expiredTransaction)
+
+	return expiredTransaction, nil
+}
+
+// RefundSubscriptionTransaction is a method that creates a pending refund reversing the given amount of a completed
+// subscription transaction, the refunded amount is debited from the provider's wallet until the refund fails
+func (service *Service) RefundSubscriptionTransaction(id string, amount money.Amount) (*entity.SubscriptionTransaction, error) {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started subscription transaction refunding process "+
+		"{ Subscription Transaction ID : %s, Amount : %s }", id, amount), service.logger.Logs.TransactionLogFile)
+
+	if amount <= 0 {
+		return nil, errors.New("refund amount should be greater than zero")
+	}
+
+	subscriptionTransaction, err := service.FindSubscriptionTransaction(id)
+	if err != nil {
+		return nil, err
+	}
+
+	refund := &entity.SubscriptionTransaction{UserID: subscriptionTransaction.UserID,
+		PlanID: subscriptionTransaction.PlanID, AppID: subscriptionTransaction.AppID,
+		ReceiverName: subscriptionTransaction.ReceiverName, Subject: subscriptionTransaction.Subject,
+		ReceivedAmount: amount.Neg(), CurrencyType: subscriptionTransaction.CurrencyType,
+		ReversedTransactionID: subscriptionTransaction.ID}
+
+	err = service.subTransactionRepo.CreateRefund(refund)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For refunding subscription transaction "+
+			"{ Subscription Transaction ID : %s, Amount : %s }, %s", id, amount, err.Error()))
+
+		return nil, errors.New("unable to refund subscription transaction")
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished subscription transaction refunding process, Refund => %s",
+		refund.ToString()), service.logger.Logs.TransactionLogFile)
+
+	return refund, nil
+}
+
+// CompleteRefund is a method that marks a pending refund as paid back
+func (service *Service) CompleteRefund(id string) error {
+	return service.updateRefundStatus(id, entity.TransactionStatusRefunded)
+}
+
+// FailRefund is a method that marks a pending refund as failed and gives the amount back to the provider's wallet
+func (service *Service) FailRefund(id string) error {
+	return service.updateRefundStatus(id, entity.TransactionStatusRefundFailed)
+}
+
+// ProcessRefund is a method that pays a pending refund back through the payment provider the refunded transaction
+// has been made with, the refund is completed when the provider accepts it and failed when the provider declines it.
+// Refunds through providers that don't support refunds stay pending so they can be paid back manually.
+func (service *Service) ProcessRefund(id string) error {
+
+	refund, err := service.FindSubscriptionTransaction(id)
+	if err != nil {
+		return err
+	}
+
+	if refund.Status != entity.TransactionStatusRefundPending || refund.ReversedTransactionID == "" {
+		return errors.New("refund is not pending")
+	}
+
+	reversedTransaction, err := service.FindSubscriptionTransaction(refund.ReversedTransactionID)
+	if err != nil {
+		return err
+	}
+
+	provider, err := service.gateways.FindByAppID(reversedTransaction.AppID)
+	if err != nil {
+		return err
+	}
+
+	err = provider.Refund(reversedTransaction.OutTradeNo, string(reversedTransaction.TradeNo),
+		refund.ReceivedAmount.Neg())
+	if err == transaction.ErrUnsupportedOperation {
+		return err
+	}
+
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For processing refund { Refund ID : %s }, %s",
+			id, err.Error()))
+
+		if err := service.FailRefund(id); err != nil {
+			return err
+		}
+
+		return errors.New("refund has been declined by the payment provider")
+	}
+
+	return service.CompleteRefund(id)
+}
+
+// updateRefundStatus is a method that moves a pending refund to the given status
+func (service *Service) updateRefundStatus(id, status string) error {
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Started refund status updating process { Refund ID : %s, Status : %s }",
+		id, status), service.logger.Logs.TransactionLogFile)
+
+	prevRefund, err := service.subTransactionRepo.Find(id)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding refund before updating "+
+			"{ Refund ID : %s }, %s", id, err.Error()))
+
+		return errors.New("unable to update refund status")
+	}
+
+	err = service.subTransactionRepo.UpdateRefundStatus(id, status)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For updating refund status "+
+			"{ Refund ID : %s, Status : %s }, %s", id, status, err.Error()))
+
+		return errors.New("unable to update refund status")
+	}
+
+	// Recording the change in the audit trail
+	updatedRefund, err := service.subTransactionRepo.Find(id)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding refund after updating "+
+			"{ Refund ID : %s }, %s", id, err.Error()))
+	} else {
+		service.recordAudit(entity.AuditActionUpdate, id, prevRefund, updatedRefund)
+	}
+
+	/* ---------------------------- Logging ---------------------------- */
+	service.logger.Log(fmt.Sprintf("Finished refund status updating process { Refund ID : %s, Status : %s }",
+		id, status), service.logger.Logs.TransactionLogFile)
+
+	return nil
+}
+
 // DeleteSubscriptionTransaction is a method that deletes a subscription transaction from the system using an id
 func (service *Service) DeleteSubscriptionTransaction(id string) (*entity.SubscriptionTransaction, error) {
 	/* ---------------------------- Logging ---------------------------- */
@@ -128,6 +429,9 @@ func (service *Service) DeleteSubscriptionTransaction(id string) (*entity.Subscr
 		return nil, errors.New("unable to delete subscription transaction")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionDelete, id, subscriptionTransaction, nil)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished subscription transaction deleting process, Deleted Subscription Transaction => %s",
 		subscriptionTransaction.ToString()), service.logger.Logs.TransactionLogFile)
@@ -140,5 +444,12 @@ func (service *Service) DeleteMultipleSubscriptionTransactions(identifier string
 	service.logger.Log(fmt.Sprintf("Multiple subscription transaction deleting { Subscription Transaction Identifier : %s }",
 		identifier), service.logger.Logs.TransactionLogFile)
 
-	return service.subTransactionRepo.DeleteMultiple(identifier)
+	subscriptionTransactions := service.subTransactionRepo.DeleteMultiple(identifier)
+
+	// Recording the changes in the audit trail
+	for _, subscriptionTransaction := range subscriptionTransactions {
+		service.recordAudit(entity.AuditActionDelete, subscriptionTransaction.ID, subscriptionTransaction, nil)
+	}
+
+	return subscriptionTransactions
 }
@@ -4,6 +4,8 @@ import "github.com/Benyam-S/onemembership/entity"
 
 // IService is an interface that defines all the service methods of a user struct
 type IService interface {
+	WithActor(actor string) IService
+
 	AddUser(newUser *entity.User) error
 	ValidateUserProfile(user *entity.User) entity.ErrMap
 	FindUser(identifier string) (*entity.User, error)
@@ -84,7 +84,16 @@ func (service *Service) UpdateUserPassword(userPassword *entity.UserPassword) er
 	service.logger.Log(fmt.Sprintf("Started user password updating process, User Password => %s",
 		userPassword.ToString()), service.logger.Logs.UserLogFile)
 
-	err := service.passwordRepo.Update(userPassword)
+	prevUserPassword, err := service.passwordRepo.Find(userPassword.UserID)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding user password before updating "+
+			"{ User ID : %s }, %s", userPassword.UserID, err.Error()))
+
+		return errors.New("unable to update password")
+	}
+
+	err = service.passwordRepo.Update(userPassword)
 	if err != nil {
 		/* ---------------------------- Logging ---------------------------- */
 		service.logger.LogToErrorFile(fmt.Sprintf("Error: For updating User Password => %s, %s",
@@ -93,6 +102,10 @@ func (service *Service) UpdateUserPassword(userPassword *entity.UserPassword) er
 		return errors.New("unable to update password")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionUpdate, userPassword.UserID, prevUserPassword,
+		userPassword)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished user password updating process, User Password => %s",
 		userPassword.ToString()), service.logger.Logs.UserLogFile)
@@ -116,6 +129,9 @@ func (service *Service) DeleteUserPassword(userID string) (*entity.UserPassword,
 		return nil, errors.New("unable to delete password")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionDelete, userID, userPassword, nil)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished user password deleting process, Deleted User Password => %s",
 		userPassword.ToString()), service.logger.Logs.UserLogFile)
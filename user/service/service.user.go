@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/Benyam-S/onemembership/audit"
 	"github.com/Benyam-S/onemembership/common"
 	"github.com/Benyam-S/onemembership/deleted"
 	"github.com/Benyam-S/onemembership/entity"
@@ -24,15 +25,37 @@ type Service struct {
 	feedbackService   feedback.IService
 	deletedService    deleted.IService
 	cmService         common.IService
+	auditService      audit.IService
+	actor             string // The actor the changes are recorded on behalf of in the audit trail
 	logger            *log.Logger
 }
 
 // NewUserService is a function that returns a new user service
 func NewUserService(userRepository user.IUserRepository, passwordRepository user.IUserPasswordRepository,
 	preferenceService preference.IService, feedbackService feedback.IService, deletedService deleted.IService,
-	commonService common.IService, userLogger *log.Logger) user.IService {
+	commonService common.IService, auditService audit.IService, userLogger *log.Logger) user.IService {
 	return &Service{userRepo: userRepository, passwordRepo: passwordRepository, preferenceService: preferenceService,
-		feedbackService: feedbackService, deletedService: deletedService, cmService: commonService, logger: userLogger}
+		feedbackService: feedbackService, deletedService: deletedService, cmService: commonService,
+		auditService: auditService, actor: audit.SystemActor, logger: userLogger}
+}
+
+// WithActor is a method that returns a copy of the user service that records its changes in the audit trail
+// on behalf of the given actor, such as the admin or the user that has made the request
+func (service *Service) WithActor(actor string) user.IService {
+	serviceCopy := *service
+	serviceCopy.actor = actor
+	return &serviceCopy
+}
+
+// recordAudit is a method that appends an action performed on an entity to the audit trail on behalf of the actor,
+// the action has already taken place so a failure is only logged
+func (service *Service) recordAudit(action, entityID string, before, after interface{}) {
+	err := service.auditService.Record(service.actor, action, entityID, before, after)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For recording audit { Actor : %s, Action : %s, "+
+			"Entity ID : %s }, %s", service.actor, action, entityID, err.Error()))
+	}
 }
 
 // AddUser is a method that adds a new user to the system
@@ -266,7 +289,16 @@ func (service *Service) UpdateUser(user *entity.User) error {
 	service.logger.Log(fmt.Sprintf("Started user updating process, User => %s", user.ToString()),
 		service.logger.Logs.UserLogFile)
 
-	err := service.userRepo.Update(user)
+	prevUser, err := service.userRepo.Find(user.ID)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding user before updating "+
+			"{ User ID : %s }, %s", user.ID, err.Error()))
+
+		return errors.New("unable to update user")
+	}
+
+	err = service.userRepo.Update(user)
 	if err != nil {
 		/* ---------------------------- Logging ---------------------------- */
 		service.logger.LogToErrorFile(fmt.Sprintf("Error: For updating User => %s, %s", user.ToString(), err.Error()))
@@ -274,6 +306,9 @@ func (service *Service) UpdateUser(user *entity.User) error {
 		return errors.New("unable to update user")
 	}
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionUpdate, user.ID, prevUser, user)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished user updating process, User => %s", user.ToString()),
 		service.logger.Logs.UserLogFile)
@@ -288,8 +323,17 @@ func (service *Service) UpdateUserSingleValue(userID, columnName string, columnV
 		"{ UserID : %s, ColumnName : %s, ColumnValue : %s }", userID, columnName, fmt.Sprint(columnValue)),
 		service.logger.Logs.UserLogFile)
 
+	prevUser, err := service.userRepo.Find(userID)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding user before updating "+
+			"{ User ID : %s }, %s", userID, err.Error()))
+
+		return errors.New("unable to update user")
+	}
+
 	user := entity.User{ID: userID}
-	err := service.userRepo.UpdateValue(&user, columnName, columnValue)
+	err = service.userRepo.UpdateValue(&user, columnName, columnValue)
 	if err != nil {
 		/* ---------------------------- Logging ---------------------------- */
 		service.logger.LogToErrorFile(fmt.Sprintf("Error: For updating single user value "+
@@ -299,6 +343,16 @@ func (service *Service) UpdateUserSingleValue(userID, columnName string, columnV
 		return errors.New("unable to update user")
 	}
 
+	// Recording the change in the audit trail
+	updatedUser, err := service.userRepo.Find(userID)
+	if err != nil {
+		/* ---------------------------- Logging ---------------------------- */
+		service.logger.LogToErrorFile(fmt.Sprintf("Error: For finding user after updating "+
+			"{ User ID : %s }, %s", userID, err.Error()))
+	} else {
+		service.recordAudit(entity.AuditActionUpdate, userID, prevUser, updatedUser)
+	}
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished single user value updating process, User => %s",
 		user.ToString()), service.logger.Logs.UserLogFile)
@@ -315,10 +369,11 @@ func (service *Service) DeleteUser(userID string) (*entity.User, error) {
 		return nil, err
 	}
 
-	deletedUser, err := service.deletedService.AddUserToTrash(user)
+	deletedService := service.deletedService.WithActor(service.actor)
+	deletedUser, err := deletedService.AddUserToTrash(user)
 	if err == nil {
 		// Trashing the subscription transactions
-		service.deletedService.AddSubscriptionTranstactionsToTrash(userID, deletedUser.UserID)
+		deletedService.AddSubscriptionTranstactionsToTrash(userID, deletedUser.UserID)
 	}
 
 	/* ---------------------------- Logging ---------------------------- */
@@ -339,6 +394,9 @@ func (service *Service) DeleteUser(userID string) (*entity.User, error) {
 	// Setting client id to null for feedbacks
 	service.feedbackService.SetFeedbackClientIDNull(userID)
 
+	// Recording the change in the audit trail
+	service.recordAudit(entity.AuditActionDelete, userID, user, nil)
+
 	/* ---------------------------- Logging ---------------------------- */
 	service.logger.Log(fmt.Sprintf("Finished user deleting process, Deleted User => %s",
 		user.ToString()), service.logger.Logs.UserLogFile)